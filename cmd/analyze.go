@@ -1,27 +1,82 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"path"
 	"path/filepath"
+	"runtime"
+	"slices"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/bmatcuk/doublestar/v4"
 	"github.com/cosmos/dependency-guardian/pkg/analysis"
 	"github.com/cosmos/dependency-guardian/pkg/config"
 	"github.com/cosmos/dependency-guardian/pkg/github"
+	"github.com/cosmos/dependency-guardian/pkg/notify"
+	gh "github.com/google/go-github/v60/github"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 )
 
 var (
-	ownerFlag     string
-	repoFlag      string
-	prNumberFlag  int
-	noCommentFlag bool
+	ownerFlag         string
+	repoFlag          string
+	prNumberFlag      int
+	noCommentFlag     bool
+	changedOnlyFlag   bool
+	criticalLabel     string
+	skipDrafts        bool
+	draftsSummary     bool
+	failOnCritical    bool
+	slackWebhook      string
+	requireGoVersion  bool
+	overflowFile      string
+	failOnPolicy      bool
+	sourceFlag        string
+	moduleFlag        string
+	onlyCritical      bool
+	filterChanged     string
+	failOnNewDep      bool
+	servicesFile      string
+	noEmojiFlag       bool
+	botLoginFlag      string
+	testImpactFlag    bool
+	webhookURL        string
+	webhookSecret     string
+	configFromPR      bool
+	deadlineFlag      time.Duration
+	checkpointFlag    string
+	annotateOwners    bool
+	strictImports     bool
+	useMergeBase      bool
+	maxBlameLines     int
+	commentFooterFlag string
+	commentModeFlag   string
+	skipMetadataOnly  bool
+	commentThreshold  int
+	deleteBelowThresh bool
+	failOnDeprecated  bool
+	bestEffortComment bool
+	commentOnEmpty    bool
+	annotateReleases  bool
+	maxReleaseCalls   int
+	parallelism       int
 )
 
+// newProvider creates the GitHub provider used by the commands. Tests
+// substitute a fake.
+var newProvider = func() (github.Provider, error) {
+	return github.NewClient()
+}
+
 var analyzeCmd = &cobra.Command{
 	Use:   "analyze",
 	Short: "Analyze dependencies in a pull request",
@@ -33,6 +88,14 @@ This command will:
 	RunE: runAnalyze,
 }
 
+// changeAnalyzer analyzes the changes of a pull request in a single module or
+// in every module of a go.work workspace
+type changeAnalyzer interface {
+	AnalyzeChangedPackages(changedFiles []string) (*analysis.AnalysisResult, error)
+	ChangedPackages(changedFiles []string) []string
+	PackageDir(pkgName string) (string, bool)
+}
+
 func init() {
 	rootCmd.AddCommand(analyzeCmd)
 
@@ -41,6 +104,48 @@ func init() {
 	analyzeCmd.Flags().StringVarP(&repoFlag, "repo", "r", "", "GitHub repository name (overrides GITHUB_REPOSITORY if provided)")
 	analyzeCmd.Flags().IntVarP(&prNumberFlag, "pr", "p", 0, "Pull request number (overrides PR_NUMBER if provided)")
 	analyzeCmd.Flags().BoolVarP(&noCommentFlag, "no-comment", "n", false, "Do not post a comment on the PR")
+	analyzeCmd.Flags().BoolVar(&bestEffortComment, "best-effort-comment", false, "Skip the comment with a warning instead of failing when the token lacks the permission to comment, e.g. a read-only token")
+	analyzeCmd.Flags().StringVar(&criticalLabel, "label-on-critical", "", "Label to apply to the PR when a critical package is affected (removed otherwise)")
+	analyzeCmd.Flags().BoolVar(&failOnCritical, "fail-on-critical", false, "Exit with an error when the critical impact reaches the block threshold")
+	analyzeCmd.Flags().BoolVar(&failOnPolicy, "fail-on-policy", false, "Exit with an error when a changed package outside policy.critical_sources affects a critical package")
+	analyzeCmd.Flags().BoolVar(&failOnNewDep, "fail-on-new-dependency", false, "Exit with an error when the PR requires an external module that the base go.mod does not")
+	analyzeCmd.Flags().BoolVar(&failOnDeprecated, "fail-on-deprecated-import", false, "Exit with an error when a changed package imports a package matching policy.deprecated_packages")
+	analyzeCmd.Flags().BoolVar(&requireGoVersion, "require-go-version", false, "Exit with an error when the go directive of go.mod violates the version policy or the changes need a newer Go version")
+	analyzeCmd.Flags().StringVar(&slackWebhook, "slack-webhook", "", "Slack incoming webhook URL to post a condensed report to")
+	analyzeCmd.Flags().StringVar(&webhookURL, "webhook-url", "", "URL to POST the JSON analysis result and pull request metadata to")
+	analyzeCmd.Flags().StringVar(&webhookSecret, "webhook-secret", "", "Secret signing the webhook payload with HMAC-SHA256 in the "+notify.SignatureHeader+" header")
+	analyzeCmd.Flags().StringVar(&overflowFile, "overflow-file", "", "Write the full list of affected packages per changed package to this JSON file")
+	analyzeCmd.Flags().StringVar(&servicesFile, "affected-services-file", "", "Write the affected packages and the services they map to under the services config to this JSON file")
+	analyzeCmd.Flags().StringVar(&formatFlag, "format", formatMarkdown, "Format of the report printed to stdout ("+strings.Join(reportFormats, ", ")+"); the comment is always Markdown")
+	analyzeCmd.Flags().StringVar(&outputFileFlag, "output-file", "", "Also write the report printed to stdout, in the selected format, to this file")
+	analyzeCmd.Flags().BoolVar(&quietFlag, "quiet", false, "Do not print the report to stdout")
+	analyzeCmd.Flags().BoolVar(&noEmojiFlag, "no-emoji", false, "Render text markers such as [CRITICAL] instead of emoji, overriding output.emoji")
+	analyzeCmd.Flags().StringVar(&botLoginFlag, "bot-login", "", "Login of the account posting the report; only its comments are updated (defaults to the authenticated user)")
+	analyzeCmd.Flags().BoolVar(&configFromPR, "config-from-pr", false, "Read the config file from the PR head through the GitHub API instead of from the checkout")
+	analyzeCmd.Flags().DurationVar(&deadlineFlag, "deadline", 0, "Stop resolving packages after this long (e.g. 2m) and report the partial analysis, marked incomplete (0 disables)")
+	analyzeCmd.Flags().StringVar(&checkpointFlag, "checkpoint", "", "File to periodically save resolution progress to, so that a run interrupted e.g. by a CI time limit resumes from it")
+	analyzeCmd.Flags().BoolVar(&annotateOwners, "annotate-owners", false, "List the recent authors of the lines each changed package modifies, from git blame at the base revision")
+	analyzeCmd.Flags().IntVar(&maxBlameLines, "max-blame-lines", defaultMaxBlameLines, "Maximum number of changed lines blamed for --annotate-owners (0 for no limit)")
+	analyzeCmd.Flags().BoolVar(&annotateReleases, "annotate-releases", false, "Note the release each affected package last changed in: the nearest tag containing its last commit at the PR head")
+	analyzeCmd.Flags().IntVar(&maxReleaseCalls, "max-release-git-calls", defaultMaxReleaseCalls, "Maximum number of git calls made to look up releases for --annotate-releases (0 for no limit)")
+	analyzeCmd.Flags().IntVar(&parallelism, "parallelism", runtime.NumCPU(), "Maximum number of modules of a go.work workspace analyzed concurrently (0 for no limit)")
+	analyzeCmd.Flags().BoolVar(&strictImports, "strict-imports", false, "Fail when a package imports a package of the module that has no directory, except those matching analysis.strict_import_exceptions")
+	analyzeCmd.Flags().BoolVar(&skipMetadataOnly, "skip-metadata-only", false, "Leave out changed files whose patch adds and removes no line, such as mode changes")
+	analyzeCmd.Flags().BoolVar(&useMergeBase, "use-merge-base", false, "Compute the changed files with git from the merge-base of the PR head and base, so only the PR's own commits count")
+	analyzeCmd.Flags().StringVar(&commentModeFlag, "comment-mode", commentModeUpdate, "How a re-analysis is posted: "+commentModeUpdate+" edits the report in place, "+commentModeReply+" posts a new report quoting the previous one")
+	analyzeCmd.Flags().IntVar(&commentThreshold, "comment-threshold", 0, "Only post the comment when at least this many packages are affected or a critical package is (0 always posts)")
+	analyzeCmd.Flags().BoolVar(&commentOnEmpty, "comment-on-empty", false, "Post the comment even when the PR changes no package; by default only a previous report listing changed packages is updated")
+	analyzeCmd.Flags().BoolVar(&deleteBelowThresh, "delete-below-threshold", false, "Delete the previous report comments when the impact falls below --comment-threshold")
+	analyzeCmd.Flags().StringVar(&commentFooterFlag, "comment-footer", "", "Text appended to the posted comment, overriding output.footer; may use {{.Repo}}, {{.PR}} and {{.Version}}")
+	analyzeCmd.Flags().BoolVar(&skipDrafts, "skip-drafts", false, "Exit successfully without analyzing when the PR is a draft")
+	analyzeCmd.Flags().BoolVar(&draftsSummary, "drafts-summary-only", false, "Only report the analysis summary when the PR is a draft")
+	analyzeCmd.Flags().StringVar(&sourceFlag, "source", sourceGit, "Where to fetch the repository from: git, or proxy to download the module from GOPROXY without git")
+	analyzeCmd.Flags().StringVar(&moduleFlag, "module", "", "Module path to fetch with --source proxy (defaults to github.com/<owner>/<repo>)")
+	analyzeCmd.Flags().BoolVar(&onlyCritical, "only-critical", false, "Only report changed packages affecting critical packages, and only their critical affected packages")
+	analyzeCmd.Flags().StringVar(&filterChanged, "filter-changed", "", "Only analyze changed files matching this glob, in addition to the configured include and ignore patterns (e.g. \"internal/**\")")
+	analyzeCmd.Flags().BoolVar(&changedOnlyFlag, "changed-only", false, "Only print the packages changed by the PR, skipping dependency analysis")
+	analyzeCmd.Flags().BoolVar(&testImpactFlag, "test-impact", false, "Only print, for each changed package, the packages whose tests use it, such as the suites relying on a test helper")
+	analyzeCmd.MarkFlagsMutuallyExclusive("changed-only", "test-impact")
 }
 
 func runAnalyze(cmd *cobra.Command, args []string) error {
@@ -48,21 +153,53 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	var err error
 
 	// If a config path is provided via flags, load it immediately.
-	if cfgFile != "" {
-		cfg, err = config.LoadConfig("", cfgFile)
+	if len(cfgFiles) > 0 {
+		cfg, err = config.LoadConfig("", cfgFiles, requireConfig)
 		if err != nil {
-			return fmt.Errorf("failed to load configuration from %s: %w", cfgFile, err)
+			return fmt.Errorf("failed to load configuration from %s: %w", strings.Join(cfgFiles, ", "), err)
 		}
 	}
 
+	if err := validateFormat(formatFlag); err != nil {
+		return err
+	}
+	if err := validateFooter(cfg); err != nil {
+		return err
+	}
+	if !slices.Contains(commentModes, commentModeFlag) {
+		return fmt.Errorf("unsupported comment mode %q, expected one of %s", commentModeFlag, strings.Join(commentModes, ", "))
+	}
+	if commentThreshold < 0 {
+		return fmt.Errorf("--comment-threshold must not be negative")
+	}
+
+	if configFromPR && len(cfgFiles) > 0 {
+		return fmt.Errorf("--config and --config-from-pr cannot be used together")
+	}
+
+	if annotateOwners && sourceFlag == sourceProxy {
+		return fmt.Errorf("--annotate-owners needs the git history and cannot be used with --source %s", sourceProxy)
+	}
+
+	if annotateReleases && sourceFlag == sourceProxy {
+		return fmt.Errorf("--annotate-releases needs the git history and cannot be used with --source %s", sourceProxy)
+	}
+
+	if useMergeBase && sourceFlag == sourceProxy {
+		return fmt.Errorf("--use-merge-base needs the git history and cannot be used with --source %s", sourceProxy)
+	}
+
+	if filterChanged != "" && !doublestar.ValidatePattern(filterChanged) {
+		return fmt.Errorf("invalid --filter-changed pattern: %s", filterChanged)
+	}
+
 	// Create GitHub client
 	token := os.Getenv("GITHUB_TOKEN")
 	if token == "" {
 		return fmt.Errorf("GITHUB_TOKEN environment variable is required")
 	}
 
-	client, err := github.NewClient()
-
+	client, err := newProvider()
 	if err != nil {
 		return fmt.Errorf("failed to create github client: %w", err)
 	}
@@ -117,28 +254,37 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to fetch pull request: %w", err)
 	}
 
+	if pr.GetDraft() && skipDrafts {
+		zap.S().Infow("skipping draft pull request due to --skip-drafts flag", "pr", prNum)
+		return nil
+	}
+
 	headRef := pr.GetHead().GetSHA()
 	branchRef := pr.GetHead().GetRef() // e.g. feature/branch
 
-	cloneDir, err := os.MkdirTemp("", "dep-guardian-*")
-	if err != nil {
-		return fmt.Errorf("failed to create temp dir: %w", err)
+	if configFromPR {
+		// Config changes made by the PR take effect without relying on the checkout
+		cfg, err = loadConfigFromPR(client, owner, repoName, headRef)
+		if err != nil {
+			return err
+		}
+		if err := validateFooter(cfg); err != nil {
+			return err
+		}
 	}
 
-	repoURL := fmt.Sprintf("https://x-access-token:%s@github.com/%s/%s.git", token, owner, repoName)
-
-	// Clone with depth 1 to target branch/ref
-	cloneCmd := exec.Command("git", "clone", "--depth", "1", "--branch", branchRef, repoURL, cloneDir)
-	cloneOut, err := cloneCmd.CombinedOutput()
+	source, err := newSource(sourceFlag, token, owner, repoName, moduleFlag)
 	if err != nil {
-		return fmt.Errorf("git clone failed: %v\n%s", err, string(cloneOut))
+		return err
 	}
 
-	// Ensure we are at the exact head SHA (in case branch moved)
-	checkoutCmd := exec.Command("git", "-C", cloneDir, "checkout", headRef)
-	checkoutOut, err := checkoutCmd.CombinedOutput()
+	cloneDir, err := os.MkdirTemp("", "dep-guardian-*")
 	if err != nil {
-		return fmt.Errorf("git checkout failed: %v\n%s", err, string(checkoutOut))
+		return fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	if err := source.Fetch(branchRef, headRef, cloneDir); err != nil {
+		return err
 	}
 
 	workDir := cloneDir
@@ -146,17 +292,33 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	// If config wasn't loaded from a specific path, load it from the cloned repo.
 	if cfg == nil {
 		// The --config flag was not provided, so load from the default path in the repository.
-		// cfgFile will be empty here.
-		cfg, err = config.LoadConfig(workDir, cfgFile)
+		// cfgFiles will be empty here.
+		cfg, err = config.LoadConfig(workDir, cfgFiles, requireConfig)
 		if err != nil {
 			return fmt.Errorf("failed to load configuration: %w", err)
 		}
+		if err := validateFooter(cfg); err != nil {
+			return err
+		}
+	}
+	applyConfigFlags(cfg)
+
+	// Without a configured module, a go.work at the repository root analyzes
+	// every module of the workspace
+	var workspace []*analysis.WorkspaceModule
+	if _, statErr := os.Stat(filepath.Join(workDir, "go.work")); statErr == nil && cfg.Analysis.ModuleDir == "" {
+		if workspace, err = analysis.ReadWorkspace(workDir); err != nil {
+			return err
+		}
+		zap.S().Infow("analyzing go.work workspace", "modules", len(workspace), "parallelism", parallelism)
 	}
 
 	// Get root package path from the cloned repo's go.mod
-	rootPkg, err := getRootPackage(workDir)
-	if err != nil {
-		return fmt.Errorf("failed to get root package from cloned repo: %w", err)
+	var rootPkg string
+	if workspace == nil {
+		if rootPkg, err = getRootPackage(cfg, workDir); err != nil {
+			return fmt.Errorf("failed to get root package from cloned repo: %w", err)
+		}
 	}
 
 	// Fetch changed files from PR
@@ -165,15 +327,139 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to get PR files: %w", err)
 	}
 
-	// Convert to string slice (GitHub returns repo-relative paths)
+	// Convert to string slice (GitHub returns repo-relative paths), keeping
+	// track of moved files so package renames can be detected
 	var changedFiles []string
+	renamedFiles := make(map[string]string)
+	metadataOnlyFiles := make(map[string]bool)
 	for _, file := range files {
+		if !matchesChangedFilter(file.GetFilename()) {
+			continue
+		}
+		if skipMetadataOnly && metadataOnly(file) {
+			zap.S().Infow("skipping file without content changes", "file", file.GetFilename())
+			metadataOnlyFiles[file.GetFilename()] = true
+			continue
+		}
 		changedFiles = append(changedFiles, *file.Filename)
+		if file.GetStatus() == "renamed" && file.GetPreviousFilename() != "" {
+			renamedFiles[file.GetFilename()] = file.GetPreviousFilename()
+		}
+	}
+
+	if useMergeBase {
+		// Leave out changes merged into the base branch since the PR branched off
+		mergeBaseFiles, err := mergeBaseChangedFiles(gitRunner, workDir, pr.GetBase().GetSHA(), headRef)
+		if err != nil {
+			return err
+		}
+		changedFiles = nil
+		for _, file := range mergeBaseFiles {
+			if matchesChangedFilter(file) && !metadataOnlyFiles[file] {
+				changedFiles = append(changedFiles, file)
+			}
+		}
+	}
+
+	var ctx context.Context
+	if deadlineFlag > 0 {
+		// Only the analysis is bounded, the report is still posted
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), deadlineFlag)
+		defer cancel()
+	}
+
+	// Import-diff mode, interface change detection and layering rules compare
+	// the head revision against the base revision, checked out into baseDir
+	checkoutBase := cfg.Analysis.ImportDiff || cfg.Analysis.InterfaceChanges || len(cfg.Layering.Rules) > 0
+	var baseDir string
+
+	// setupAnalyzer applies the settings of the run to the analyzer of the
+	// module in moduleDir, saving its progress to checkpoint
+	setupAnalyzer := func(analyzer *analysis.Analyzer, moduleDir, checkpoint string) {
+		analyzer.SetRenamedFiles(renamedFiles)
+		if ctx != nil {
+			analyzer.SetContext(ctx)
+		}
+		if checkpoint != "" {
+			analyzer.SetCheckpoint(checkpoint)
+		}
+		analyzer.SetStrictImports(strictImports)
+		if baseDir != "" {
+			analyzer.SetBaseRepo(baseDir)
+			return
+		}
+
+		goModPath := path.Join(moduleDir, "go.mod")
+		if !checkoutBase && !changedOnlyFlag && changesFile(files, goModPath) {
+			// Without a base checkout, fetch the base go.mod to detect new dependencies
+			content, err := client.GetFileContent(owner, repoName, goModPath, pr.GetBase().GetSHA())
+			if err != nil {
+				zap.S().Warnw("failed to fetch base go.mod, skipping new dependency detection", "go_mod", goModPath, "error", err)
+			} else if baseMod, err := analysis.ParseModFile(content); err != nil {
+				zap.S().Warnw("failed to parse base go.mod, skipping new dependency detection", "go_mod", goModPath, "error", err)
+			} else {
+				analyzer.SetBaseModFile(baseMod)
+			}
+		}
+	}
+
+	// newAnalyzer creates the analyzer of the module, or of every module of
+	// the workspace
+	newAnalyzer := func() changeAnalyzer {
+		if workspace != nil {
+			w := analysis.NewWorkspace(cfg, workDir, workspace, func(analyzer *analysis.Analyzer, module *analysis.WorkspaceModule) {
+				// Each module saves its progress to its own checkpoint
+				checkpoint := ""
+				if checkpointFlag != "" {
+					checkpoint = checkpointFlag + "." + strings.ReplaceAll(module.Path, "/", "_")
+				}
+				setupAnalyzer(analyzer, module.Dir, checkpoint)
+			})
+			w.SetParallelism(parallelism)
+			return w
+		}
+		analyzer := analysis.NewAnalyzer(cfg, workDir)
+		setupAnalyzer(analyzer, cfg.Analysis.ModuleDir, checkpointFlag)
+		analyzer.SetRootPackage(rootPkg)
+		return analyzer
+	}
+
+	if changedOnlyFlag {
+		// Report the touched packages without resolving the dependency graph
+		// or checking out the base revision
+		for _, pkg := range newAnalyzer().ChangedPackages(changedFiles) {
+			fmt.Println(pkg)
+		}
+		return nil
+	}
+
+	if checkoutBase {
+		if baseDir, err = os.MkdirTemp("", "dep-guardian-base-*"); err != nil {
+			return fmt.Errorf("failed to create temp dir: %w", err)
+		}
+		defer os.RemoveAll(baseDir)
+		if err := source.Fetch(pr.GetBase().GetRef(), pr.GetBase().GetSHA(), baseDir); err != nil {
+			return fmt.Errorf("failed to clone base revision: %w", err)
+		}
 	}
 
 	// Create analyzer
-	analyzer := analysis.NewAnalyzer(cfg, workDir)
-	analyzer.SetRootPackage(rootPkg)
+	analyzer := newAnalyzer()
+
+	if testImpactFlag {
+		moduleAnalyzer, ok := analyzer.(*analysis.Analyzer)
+		if !ok {
+			return errors.New("--test-impact does not support go.work workspaces, select a module with --module-dir")
+		}
+		// Report the reverse dependencies through test imports instead of the impact
+		impacts, err := moduleAnalyzer.TestImpact(changedFiles)
+		if err != nil {
+			return fmt.Errorf("failed to analyze test impact: %w", err)
+		}
+		fmt.Print(formatTestImpacts(impacts))
+		return nil
+	}
 
 	// Analyze changes
 	result, err := analyzer.AnalyzeChangedPackages(changedFiles)
@@ -181,63 +467,423 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("failed to analyze changes: %w", err)
 	}
 
-	// Print results to stdout
-	fmt.Println(result)
-
-	// Post or update PR comment
-	if !noCommentFlag {
-		zap.S().Infow("posting or updating PR comment", "owner", owner, "repo", repoName, "pr", prNum)
-
-		// Find existing comment
-		var existingCommentID int64
-		comments, err := client.ListComments(owner, repoName, prNum)
+	if annotateOwners {
+		packagesOf := func(file string) []string {
+			if !matchesChangedFilter(file) {
+				return nil
+			}
+			return analyzer.ChangedPackages([]string{file})
+		}
+		authors, err := recentAuthors(gitRunner, workDir, pr.GetBase().GetSHA(), files, maxBlameLines, packagesOf)
 		if err != nil {
-			return fmt.Errorf("failed to list PR comments: %w", err)
+			zap.S().Warnw("failed to annotate owners, skipping recent authors", "error", err)
 		}
-		for _, comment := range comments {
-			if strings.Contains(comment.GetBody(), "<!-- dependency-guardian -->") {
-				existingCommentID = comment.GetID()
-				break
-			}
+		for _, impact := range result.Impacts {
+			impact.RecentAuthors = authors[impact.ChangedPackage]
 		}
+	}
 
-		report := result.String()
+	if annotateReleases {
+		pkgDirs := make(map[string]string)
+		for _, name := range result.AffectedPackageNames() {
+			if dir, ok := analyzer.PackageDir(name); ok {
+				pkgDirs[name] = dir
+			}
+		}
+		releases, err := lastReleases(gitRunner, workDir, headRef, pkgDirs, maxReleaseCalls)
+		if err != nil {
+			zap.S().Warnw("failed to look up releases, skipping them", "error", err)
+		}
+		setLastReleases(result, releases)
+	}
 
-		if existingCommentID != 0 {
-			// Update existing comment
-			zap.S().Infow("updating existing comment", "comment_id", existingCommentID)
-			err = client.UpdateComment(owner, repoName, existingCommentID, report)
+	if len(cfgFiles) == 0 && changesFile(files, config.DefaultConfigName) {
+		// The analysis ran under the config of the PR, flag what it changes
+		changes, err := configChanges(client, owner, repoName, pr.GetBase().GetSHA(), headRef)
+		if err != nil {
+			zap.S().Warnw("failed to diff the configuration against the base, skipping config changes", "error", err)
 		} else {
-			// Create new comment
-			zap.S().Infow("creating new comment")
-			err = client.CreateComment(owner, repoName, prNum, report)
+			result.ConfigChanges = changes
 		}
+	}
+
+	// Render the report for the exact revisions that were analyzed
+	resultCtx := analysis.ResultContext{
+		BaseSHA:    pr.GetBase().GetSHA(),
+		HeadSHA:    headRef,
+		AnalyzedAt: time.Now(),
+	}
+	if noEmojiFlag {
+		result.NoEmoji = true
+	}
+
+	var botLogin string
+	if !noCommentFlag {
+		botLogin = botIdentity(client, botLoginFlag)
 
+		// Report how the impact changed since the analysis of the current comment
+		previous, err := previousSnapshot(client, owner, repoName, prNum, botLogin)
 		if err != nil {
-			return fmt.Errorf("failed to post or update PR comment: %w", err)
+			zap.S().Warnw("failed to read the previous analysis, skipping the delta", "error", err)
+		} else if previous != nil {
+			result.Delta = result.DeltaSince(previous)
 		}
+	}
+
+	reportResult := result
+	if onlyCritical {
+		reportResult = result.CriticalOnly()
+	}
+	var report string
+	if pr.GetDraft() && draftsSummary {
+		report = reportResult.SummaryWithContext(resultCtx)
 	} else {
+		report = reportResult.StringWithContext(resultCtx)
+	}
+
+	// Print results to stdout and the output file
+	text := func() string { return reportResult.TextWithContext(resultCtx) }
+	output, err := renderOutput(formatFlag, result, report, text)
+	if err != nil {
+		return err
+	}
+	if err := emitOutput(output); err != nil {
+		return err
+	}
+
+	if overflowFile != "" {
+		if err := writeOverflowFile(overflowFile, result); err != nil {
+			return err
+		}
+	}
+
+	if servicesFile != "" {
+		if err := writeAffectedServicesFile(servicesFile, result, cfg); err != nil {
+			return err
+		}
+	}
+
+	// Post or update PR comment
+	affectedCount := len(result.AffectedPackageNames())
+	belowThreshold := affectedCount < commentThreshold && !result.HasCriticalImpact()
+	skipEmpty := false
+	if !noCommentFlag && !commentOnEmpty && emptyResult(result) {
+		// A previous report listing changed packages would be stale, so it is still updated
+		stale, err := nonEmptyReport(client, owner, repoName, prNum, botLogin)
+		if err != nil {
+			return commentError(err)
+		}
+		skipEmpty = !stale
+	}
+	switch {
+	case noCommentFlag:
 		zap.S().Infow("skipping PR comment due to --no-comment flag")
+	case skipEmpty:
+		zap.S().Infow("skipping PR comment, no changed packages found")
+	case belowThreshold:
+		zap.S().Infow("skipping PR comment, the impact is below --comment-threshold", "affected", affectedCount, "threshold", commentThreshold)
+		if deleteBelowThresh {
+			if err := deleteReport(client, owner, repoName, prNum, botLogin); err != nil {
+				return commentError(err)
+			}
+		}
+	default:
+		zap.S().Infow("posting or updating PR comment", "owner", owner, "repo", repoName, "pr", prNum)
+
+		// The head may have moved while the analysis ran
+		current, err := client.GetPullRequest(owner, repoName, prNum)
+		if err != nil {
+			zap.S().Warnw("failed to refresh the PR head, skipping the staleness check", "error", err)
+		} else if currentSHA := current.GetHead().GetSHA(); currentSHA != headRef {
+			zap.S().Warnw("PR head changed during the analysis", "analyzed", headRef, "current", currentSHA)
+			report = withBanner(report, staleBanner(headRef, currentSHA, !result.NoEmoji))
+		}
+		body, err := commentBody(report, result, cfg, footerData{Repo: owner + "/" + repoName, PR: prNum, Version: version})
+		if err != nil {
+			return err
+		}
+		post := postReport
+		if commentModeFlag == commentModeReply {
+			post = replyReport
+		}
+		if err := post(client, owner, repoName, prNum, body, botLogin); err != nil {
+			return commentError(err)
+		}
+	}
+
+	if slackWebhook != "" {
+		// A Slack outage should not fail the analysis
+		if err := notify.NewSlackNotifier(slackWebhook).Notify(result, pr.GetHTMLURL()); err != nil {
+			zap.S().Warnw("failed to post Slack notification", "error", err)
+		}
+	}
+
+	if webhookURL != "" {
+		// Like Slack, an unreachable dashboard should not fail the analysis
+		info := notify.PullRequestInfo{
+			Owner:   owner,
+			Repo:    repoName,
+			Number:  prNum,
+			URL:     pr.GetHTMLURL(),
+			BaseSHA: pr.GetBase().GetSHA(),
+			HeadSHA: headRef,
+		}
+		if err := notify.NewWebhookNotifier(webhookURL, webhookSecret).Notify(result, info); err != nil {
+			zap.S().Warnw("failed to post webhook notification", "error", err)
+		}
+	}
+
+	if criticalLabel != "" {
+		if err := syncCriticalLabel(client, owner, repoName, pr, criticalLabel, result); err != nil {
+			return err
+		}
+	}
+
+	if failOnCritical && result.Severity == analysis.SeverityBlock {
+		return fmt.Errorf("%d critical packages affected, reaching the block threshold of %d", result.CriticalCount, result.BlockThreshold)
+	}
+
+	if failOnPolicy && len(result.PolicyViolations) > 0 {
+		return fmt.Errorf("%d critical source policy violations found", len(result.PolicyViolations))
+	}
+
+	if failOnNewDep && len(result.NewDependencies) > 0 {
+		return fmt.Errorf("%d new external dependencies added", len(result.NewDependencies))
+	}
+
+	if failOnDeprecated && len(result.DeprecatedImports) > 0 {
+		return fmt.Errorf("%d imports of deprecated packages found", len(result.DeprecatedImports))
+	}
+
+	if requireGoVersion && len(result.GoVersionIssues) > 0 {
+		return fmt.Errorf("%d Go version compatibility issues found", len(result.GoVersionIssues))
+	}
+
+	return nil
+}
+
+// commentError returns the error of posting the report, or nil when
+// --best-effort-comment is set and the token is not allowed to comment
+func commentError(err error) error {
+	if bestEffortComment && errors.Is(err, github.ErrForbidden) {
+		zap.S().Warnw("token lacks write scope; skipping comment", "error", err)
+		return nil
+	}
+	return err
+}
+
+// syncCriticalLabel applies label to the pull request when the result affects a
+// critical package and removes it otherwise. Calls that would not change the
+// labels currently on the pull request are skipped.
+func syncCriticalLabel(provider github.Provider, owner, repo string, pr *gh.PullRequest, label string, result *analysis.AnalysisResult) error {
+	hasLabel := false
+	for _, l := range pr.Labels {
+		if l.GetName() == label {
+			hasLabel = true
+			break
+		}
+	}
+
+	critical := result.HasCriticalImpact()
+	switch {
+	case critical && !hasLabel:
+		zap.S().Infow("adding critical impact label", "label", label)
+		if err := provider.AddLabels(owner, repo, pr.GetNumber(), []string{label}); err != nil {
+			return fmt.Errorf("failed to add critical impact label: %w", err)
+		}
+	case !critical && hasLabel:
+		zap.S().Infow("removing critical impact label", "label", label)
+		if err := provider.RemoveLabel(owner, repo, pr.GetNumber(), label); err != nil {
+			return fmt.Errorf("failed to remove critical impact label: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// changesFile reports whether the pull request files include name
+func changesFile(files []*gh.CommitFile, name string) bool {
+	for _, file := range files {
+		if file.GetFilename() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesChangedFilter reports whether a changed file passes --filter-changed.
+// The configured include and ignore patterns still apply to the files that do.
+func matchesChangedFilter(file string) bool {
+	if filterChanged == "" {
+		return true
 	}
+	matched, _ := doublestar.Match(filterChanged, file)
+	return matched
+}
+
+// metadataOnly reports whether a modified file of a pull request only changed
+// metadata, such as its mode: its patch adds and removes no line. Added,
+// removed and renamed files always count as changes.
+func metadataOnly(file *gh.CommitFile) bool {
+	switch file.GetStatus() {
+	case "modified", "changed", "unchanged":
+	default:
+		return false
+	}
+	if file.GetAdditions() > 0 || file.GetDeletions() > 0 {
+		return false
+	}
+	for _, line := range strings.Split(file.GetPatch(), "\n") {
+		if strings.HasPrefix(line, "+") || strings.HasPrefix(line, "-") {
+			return false
+		}
+	}
+	return true
+}
 
+// emptyResult reports whether the analysis has nothing to report beyond the
+// absence of changed packages
+func emptyResult(result *analysis.AnalysisResult) bool {
+	return len(result.Impacts) == 0 && len(result.NewDependencies) == 0 && len(result.ConfigChanges) == 0
+}
+
+// writeOverflowFile writes the complete affected package names of every changed
+// package as JSON, since the report may list only part of them
+func writeOverflowFile(path string, result *analysis.AnalysisResult) error {
+	affected := make(map[string][]string, len(result.Impacts))
+	for _, impact := range result.Impacts {
+		names := make([]string, 0, len(impact.AffectedPackages))
+		for _, pkg := range impact.AffectedPackages {
+			names = append(names, pkg.Name)
+		}
+		affected[impact.ChangedPackage] = names
+	}
+
+	data, err := json.MarshalIndent(affected, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode affected packages: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write overflow file: %w", err)
+	}
 	return nil
 }
 
-// getRootPackage gets the root package path from go.mod
-func getRootPackage(dir string) (string, error) {
-	modFile := filepath.Join(dir, "go.mod")
-	content, err := os.ReadFile(modFile)
+// affectedServices is the content of the affected services file
+type affectedServices struct {
+	Packages map[string]string `json:"packages"` // Affected package to its service, "" when unmapped
+	Services []string          `json:"services"` // Distinct services of the affected packages
+}
+
+// writeAffectedServicesFile writes the affected high-level packages of the
+// result with their service names, and the sorted distinct services, as JSON
+func writeAffectedServicesFile(path string, result *analysis.AnalysisResult, cfg *config.Config) error {
+	affected := affectedServices{
+		Packages: make(map[string]string),
+		Services: []string{},
+	}
+	seen := make(map[string]bool)
+	for _, name := range result.AffectedPackageNames() {
+		service := cfg.ServiceName(name)
+		affected.Packages[name] = service
+		if service != "" && !seen[service] {
+			seen[service] = true
+			affected.Services = append(affected.Services, service)
+		}
+	}
+	sort.Strings(affected.Services)
+
+	data, err := json.MarshalIndent(affected, "", "  ")
 	if err != nil {
-		return "", fmt.Errorf("failed to read go.mod: %w", err)
+		return fmt.Errorf("failed to encode affected services: %w", err)
 	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write affected services file: %w", err)
+	}
+	return nil
+}
 
-	// Extract module path from first line
-	// Expected format: module github.com/org/repo
-	var modulePath string
-	_, err = fmt.Sscanf(string(content), "module %s", &modulePath)
+// loadConfigFromPR fetches and parses the config file of the repository at ref.
+// A missing file yields the default configuration unless --require-config is
+// set.
+func loadConfigFromPR(provider github.Provider, owner, repo, ref string) (*config.Config, error) {
+	data, err := provider.GetFileContent(owner, repo, config.DefaultConfigName, ref)
+	if errors.Is(err, github.ErrNotFound) && !requireConfig {
+		zap.S().Infow("no config file in the PR head, using default configuration", "ref", ref)
+		return config.DefaultConfig(), nil
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to parse go.mod: %w", err)
+		return nil, fmt.Errorf("failed to fetch configuration from the PR head: %w", err)
 	}
 
-	return modulePath, nil
-} 
\ No newline at end of file
+	cfg, err := config.LoadConfigFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s at %s: %w", config.DefaultConfigName, ref, err)
+	}
+	return cfg, nil
+}
+
+// configChanges diffs the config file of the repository at baseRef against the
+// one at headRef. A missing file stands for the default configuration.
+func configChanges(provider github.Provider, owner, repo, baseRef, headRef string) ([]*config.Change, error) {
+	load := func(ref string) (*config.Config, error) {
+		data, err := provider.GetFileContent(owner, repo, config.DefaultConfigName, ref)
+		if errors.Is(err, github.ErrNotFound) {
+			return config.DefaultConfig(), nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s at %s: %w", config.DefaultConfigName, ref, err)
+		}
+		cfg, err := config.LoadConfigFromReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s at %s: %w", config.DefaultConfigName, ref, err)
+		}
+		return cfg, nil
+	}
+
+	base, err := load(baseRef)
+	if err != nil {
+		return nil, err
+	}
+	head, err := load(headRef)
+	if err != nil {
+		return nil, err
+	}
+	return config.Diff(base, head), nil
+}
+
+// formatTestImpacts renders the packages whose tests use each changed package
+func formatTestImpacts(impacts []*analysis.TestImpact) string {
+	if len(impacts) == 0 {
+		return analysis.NoChangedPackages + "\n"
+	}
+
+	var b strings.Builder
+	for _, impact := range impacts {
+		b.WriteString(fmt.Sprintf("%s: used by the tests of %d packages\n", impact.ChangedPackage, len(impact.TestDependents)))
+		for _, name := range impact.TestDependents {
+			b.WriteString("  " + name + "\n")
+		}
+	}
+	return b.String()
+}
+
+// getRootPackage gets the root package path from the go.mod of the module in
+// the repository at repoPath. When the configured module directory has no
+// go.mod, the nearest one is used and cfg is pointed at its directory.
+func getRootPackage(cfg *config.Config, repoPath string) (string, error) {
+	moduleDir, err := analysis.FindModuleDir(repoPath, cfg.Analysis.ModuleDir)
+	if err != nil {
+		return "", err
+	}
+	if moduleDir != path.Clean("/" + cfg.Analysis.ModuleDir)[1:] {
+		zap.S().Infow("no go.mod in the configured module directory, using the nearest one", "module_dir", cfg.Analysis.ModuleDir, "found", moduleDir)
+		cfg.Analysis.ModuleDir = moduleDir
+	}
+
+	mod, err := analysis.ReadModFile(cfg.ModuleRoot(repoPath))
+	if err != nil {
+		return "", err
+	}
+	return mod.Module, nil
+}
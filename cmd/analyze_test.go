@@ -0,0 +1,866 @@
+package cmd
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/cosmos/dependency-guardian/pkg/github"
+	gh "github.com/google/go-github/v60/github"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider is an in-memory github.Provider for a single pull request
+type fakeProvider struct {
+	pr       *gh.PullRequest
+	pushedPR *gh.PullRequest // Returned instead of pr after the first GetPullRequest call
+	files    []*gh.CommitFile
+	comments []*gh.IssueComment
+	reviews  []*gh.PullRequestReview
+	labels   map[string]bool
+	contents map[string]string // File contents keyed by "ref:path"
+	login    string            // Authenticated login, unknown when empty
+
+	addLabelCalls    int
+	removeLabelCalls int
+
+	createCalls    int
+	failCreateCall int  // 1-based CreateComment call that fails, 0 never fails
+	readOnly       bool // Writes fail as with a token lacking write scope
+	nextCommentID  int64
+	getPRCalls     int
+}
+
+var _ github.Provider = (*fakeProvider)(nil)
+
+func (f *fakeProvider) GetPullRequest(owner, repo string, number int) (*gh.PullRequest, error) {
+	f.getPRCalls++
+	if f.getPRCalls > 1 && f.pushedPR != nil {
+		return f.pushedPR, nil
+	}
+	return f.pr, nil
+}
+
+func (f *fakeProvider) GetPullRequestFiles(owner, repo string, number int) ([]*gh.CommitFile, error) {
+	return f.files, nil
+}
+
+func (f *fakeProvider) GetFileContent(owner, repo, path, ref string) ([]byte, error) {
+	content, ok := f.contents[ref+":"+path]
+	if !ok {
+		return nil, fmt.Errorf("failed to get %s at %s: %w", path, ref, github.ErrNotFound)
+	}
+	return []byte(content), nil
+}
+
+func (f *fakeProvider) ListComments(owner, repo string, number int) ([]*gh.IssueComment, error) {
+	return f.comments, nil
+}
+
+func (f *fakeProvider) ListReviews(owner, repo string, number int) ([]*gh.PullRequestReview, error) {
+	return f.reviews, nil
+}
+
+func (f *fakeProvider) UpdateReview(owner, repo string, number int, reviewID int64, body string) error {
+	for _, review := range f.reviews {
+		if review.GetID() == reviewID {
+			review.Body = gh.String(body)
+		}
+	}
+	return nil
+}
+
+func (f *fakeProvider) UpdateComment(owner, repo string, commentID int64, body string) error {
+	if f.readOnly {
+		return fmt.Errorf("failed to update comment #%d: %w: 403 Resource not accessible by integration", commentID, github.ErrForbidden)
+	}
+	for _, comment := range f.comments {
+		if comment.GetID() == commentID {
+			comment.Body = gh.String(body)
+		}
+	}
+	return nil
+}
+
+func (f *fakeProvider) CreateComment(owner, repo string, number int, body string) error {
+	f.createCalls++
+	if f.readOnly {
+		return fmt.Errorf("failed to create comment on PR #%d: %w: 403 Resource not accessible by integration", number, github.ErrForbidden)
+	}
+	if f.createCalls == f.failCreateCall {
+		return errors.New("secondary rate limit")
+	}
+	f.nextCommentID++
+	f.comments = append(f.comments, &gh.IssueComment{
+		ID:   gh.Int64(1000 + f.nextCommentID),
+		Body: gh.String(body),
+		User: &gh.User{Login: gh.String(f.login)},
+	})
+	return nil
+}
+
+func (f *fakeProvider) DeleteComment(owner, repo string, commentID int64) error {
+	for i, comment := range f.comments {
+		if comment.GetID() == commentID {
+			f.comments = append(f.comments[:i], f.comments[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (f *fakeProvider) AddLabels(owner, repo string, number int, labels []string) error {
+	f.addLabelCalls++
+	if f.labels == nil {
+		f.labels = make(map[string]bool)
+	}
+	for _, label := range labels {
+		f.labels[label] = true
+	}
+	return nil
+}
+
+func (f *fakeProvider) AuthenticatedLogin() (string, error) {
+	if f.login == "" {
+		return "", errors.New("resource not accessible by integration")
+	}
+	return f.login, nil
+}
+
+func (f *fakeProvider) RemoveLabel(owner, repo string, number int, label string) error {
+	f.removeLabelCalls++
+	delete(f.labels, label)
+	return nil
+}
+
+// resultWithCritical returns a result with one affected package of the given criticality
+func resultWithCritical(critical bool) *analysis.AnalysisResult {
+	return &analysis.AnalysisResult{
+		Impacts: []*analysis.PackageImpact{{
+			ChangedPackage:   "example.com/m/d",
+			AffectedPackages: []*analysis.AffectedPackage{{Name: "example.com/m/c", IsCritical: critical}},
+		}},
+	}
+}
+
+func TestSyncCriticalLabel(t *testing.T) {
+	const label = "critical-impact"
+	provider := &fakeProvider{}
+	pr := &gh.PullRequest{Number: gh.Int(1)}
+
+	// Critical impact adds the label
+	require.NoError(t, syncCriticalLabel(provider, "o", "r", pr, label, resultWithCritical(true)))
+	require.True(t, provider.labels[label])
+	require.Equal(t, 1, provider.addLabelCalls)
+
+	// Already labeled: nothing to do
+	pr.Labels = []*gh.Label{{Name: gh.String(label)}}
+	require.NoError(t, syncCriticalLabel(provider, "o", "r", pr, label, resultWithCritical(true)))
+	require.Equal(t, 1, provider.addLabelCalls)
+
+	// No critical impact removes the label
+	require.NoError(t, syncCriticalLabel(provider, "o", "r", pr, label, resultWithCritical(false)))
+	require.False(t, provider.labels[label])
+	require.Equal(t, 1, provider.removeLabelCalls)
+
+	// Already unlabeled: nothing to do
+	pr.Labels = nil
+	require.NoError(t, syncCriticalLabel(provider, "o", "r", pr, label, resultWithCritical(false)))
+	require.Equal(t, 1, provider.removeLabelCalls)
+	require.Equal(t, 1, provider.addLabelCalls)
+}
+
+// setupAnalyze points the analyze command at a fake provider and git runner for
+// pull request 1 of o/r, restoring the defaults when the test ends.
+func setupAnalyze(t *testing.T, provider *fakeProvider, runner GitRunner) {
+	t.Helper()
+	t.Setenv("GITHUB_TOKEN", "token")
+
+	origProvider, origRunner := newProvider, gitRunner
+	newProvider = func() (github.Provider, error) { return provider, nil }
+	gitRunner = runner
+	ownerFlag, repoFlag, prNumberFlag = "o", "r", 1
+
+	t.Cleanup(func() {
+		newProvider, gitRunner = origProvider, origRunner
+		ownerFlag, repoFlag, prNumberFlag = "", "", 0
+	})
+}
+
+// cloneOf returns a git runner whose clones contain the given files
+func cloneOf(files map[string]string) *fakeGitRunner {
+	return &fakeGitRunner{
+		clone: func(url, ref, dir string) error {
+			for name, content := range files {
+				path := filepath.Join(dir, filepath.FromSlash(name))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					return err
+				}
+				if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// testRepo is a small module where package c imports package d
+var testRepo = map[string]string{
+	"go.mod": "module example.com/m",
+	"d/d.go": "package d\n\nfunc D() {}",
+	"c/c.go": "package c\n\nimport \"example.com/m/d\"\n\nfunc C() { d.D() }",
+}
+
+// draftPR returns a draft pull request changing d/d.go
+func draftPR() *fakeProvider {
+	return &fakeProvider{
+		pr: &gh.PullRequest{
+			Number: gh.Int(1),
+			Draft:  gh.Bool(true),
+			Head:   &gh.PullRequestBranch{SHA: gh.String("head"), Ref: gh.String("feature")},
+			Base:   &gh.PullRequestBranch{SHA: gh.String("base")},
+		},
+		files: []*gh.CommitFile{{Filename: gh.String("d/d.go")}},
+	}
+}
+
+func TestRunAnalyze_SkipsDrafts(t *testing.T) {
+	provider := draftPR()
+	runner := cloneOf(testRepo)
+	setupAnalyze(t, provider, runner)
+
+	skipDrafts = true
+	t.Cleanup(func() { skipDrafts = false })
+
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Zero(t, runner.clones, "draft PRs should not be cloned")
+	require.Empty(t, provider.comments, "draft PRs should not be commented on")
+}
+
+func TestRunAnalyze_DraftsSummaryOnly(t *testing.T) {
+	provider := draftPR()
+	setupAnalyze(t, provider, cloneOf(testRepo))
+
+	draftsSummary = true
+	t.Cleanup(func() { draftsSummary = false })
+
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 1)
+
+	body := provider.comments[0].GetBody()
+	require.True(t, strings.HasPrefix(body, "<!-- dependency-guardian -->"))
+	require.Contains(t, body, "- **Affected packages**: 1")
+	require.NotContains(t, body, "Changed Packages and Their Impacts")
+}
+
+func TestRunAnalyze_DraftWithoutFlags(t *testing.T) {
+	provider := draftPR()
+	setupAnalyze(t, provider, cloneOf(testRepo))
+
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 1)
+	require.Contains(t, provider.comments[0].GetBody(), "#### Changed Package: `example.com/m/d`")
+}
+
+func TestRunAnalyze_FailOnCritical(t *testing.T) {
+	files := map[string]string{
+		".dependency-guardian.yml": "critical:\n  packages: [\"**/c\"]\n  block_threshold: 1\n",
+	}
+	for name, content := range testRepo {
+		files[name] = content
+	}
+	provider := draftPR()
+	provider.pr.Draft = gh.Bool(false)
+	setupAnalyze(t, provider, cloneOf(files))
+
+	failOnCritical = true
+	t.Cleanup(func() { failOnCritical = false })
+
+	err := runAnalyze(analyzeCmd, nil)
+	require.EqualError(t, err, "1 critical packages affected, reaching the block threshold of 1")
+	require.Len(t, provider.comments, 1, "the report is still posted before failing")
+}
+
+func TestRunAnalyze_BaseCheckout(t *testing.T) {
+	files := maps.Clone(testRepo)
+	files[config.DefaultConfigName] = "analysis:\n  import_diff: true\n"
+	runner := cloneOf(files)
+	var dirs []string
+	clone := runner.clone
+	runner.clone = func(url, ref, dir string) error {
+		dirs = append(dirs, dir)
+		return clone(url, ref, dir)
+	}
+	provider := draftPR()
+	provider.pr.Draft = gh.Bool(false)
+	setupAnalyze(t, provider, runner)
+
+	// Listing the changed packages does not need the base revision
+	changedOnlyFlag = true
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	changedOnlyFlag = false
+	require.Equal(t, 1, runner.clones)
+
+	// The base checkout is removed once analyzed
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Equal(t, 3, runner.clones)
+	require.NoDirExists(t, dirs[2])
+}
+
+func TestWriteOverflowFile(t *testing.T) {
+	result := &analysis.AnalysisResult{
+		Impacts: []*analysis.PackageImpact{
+			{
+				ChangedPackage: "example.com/m/d",
+				AffectedPackages: []*analysis.AffectedPackage{
+					{Name: "example.com/m/a"},
+					{Name: "example.com/m/c", IsCritical: true},
+				},
+			},
+			{ChangedPackage: "example.com/m/e"},
+		},
+	}
+
+	path := filepath.Join(t.TempDir(), "overflow.json")
+	require.NoError(t, writeOverflowFile(path, result))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var affected map[string][]string
+	require.NoError(t, json.Unmarshal(data, &affected))
+	require.Equal(t, map[string][]string{
+		"example.com/m/d": {"example.com/m/a", "example.com/m/c"},
+		"example.com/m/e": {},
+	}, affected)
+}
+
+func TestRunAnalyze_FailOnPolicy(t *testing.T) {
+	files := map[string]string{
+		".dependency-guardian.yml": "critical:\n  packages: [\"**/c\"]\n  warn_threshold: 0\n  block_threshold: 0\npolicy:\n  critical_sources: [\"**/core/**\"]\n",
+	}
+	for name, content := range testRepo {
+		files[name] = content
+	}
+	provider := draftPR()
+	provider.pr.Draft = gh.Bool(false)
+	setupAnalyze(t, provider, cloneOf(files))
+
+	failOnPolicy = true
+	t.Cleanup(func() { failOnPolicy = false })
+
+	err := runAnalyze(analyzeCmd, nil)
+	require.EqualError(t, err, "1 critical source policy violations found")
+	require.Len(t, provider.comments, 1)
+	require.Contains(t, provider.comments[0].GetBody(), "- `example.com/m/d` affects critical package `example.com/m/c`")
+}
+
+func TestRunAnalyze_ProxySource(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range testRepo {
+		f, err := w.Create("example.com/m@v0.0.0-20240101000000-head/" + name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/example.com/m/@v/head.info":
+			_, _ = w.Write([]byte(`{"Version":"v0.0.0-20240101000000-head"}`))
+		case "/example.com/m/@v/v0.0.0-20240101000000-head.zip":
+			_, _ = w.Write(buf.Bytes())
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer proxy.Close()
+	t.Setenv("GOPROXY", proxy.URL)
+
+	provider := draftPR()
+	provider.pr.Draft = gh.Bool(false)
+	runner := cloneOf(nil)
+	setupAnalyze(t, provider, runner)
+
+	sourceFlag, moduleFlag = sourceProxy, "example.com/m"
+	t.Cleanup(func() { sourceFlag, moduleFlag = sourceGit, "" })
+
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Zero(t, runner.clones, "git is not used")
+	require.Len(t, provider.comments, 1)
+	require.Contains(t, provider.comments[0].GetBody(), "`example.com/m/c` — directly affected")
+}
+
+func TestRunAnalyze_FilterChanged(t *testing.T) {
+	files := map[string]string{
+		".dependency-guardian.yml": "patterns:\n  ignore_file_patterns: [\"internal/gen/**\"]\n",
+		"internal/e/e.go":          "package e\n\nfunc E() {}",
+		"internal/gen/g.go":        "package gen\n\nfunc G() {}",
+	}
+	for name, content := range testRepo {
+		files[name] = content
+	}
+	provider := draftPR()
+	provider.pr.Draft = gh.Bool(false)
+	provider.files = []*gh.CommitFile{
+		{Filename: gh.String("d/d.go")},
+		{Filename: gh.String("internal/e/e.go")},
+		{Filename: gh.String("internal/gen/g.go")},
+	}
+	setupAnalyze(t, provider, cloneOf(files))
+
+	filterChanged = "internal/**"
+	t.Cleanup(func() { filterChanged = "" })
+
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 1)
+	body := provider.comments[0].GetBody()
+	require.Contains(t, body, "Changed Package: `example.com/m/internal/e`")
+	require.NotContains(t, body, "Changed Package: `example.com/m/d`", "outside the filter")
+	require.NotContains(t, body, "Changed Package: `example.com/m/internal/gen`", "ignored by the config")
+
+	filterChanged = "internal/[unclosed"
+	require.EqualError(t, runAnalyze(analyzeCmd, nil), "invalid --filter-changed pattern: internal/[unclosed")
+}
+
+func TestRunAnalyze_FailOnNewDependency(t *testing.T) {
+	files := make(map[string]string)
+	for name, content := range testRepo {
+		files[name] = content
+	}
+	files["go.mod"] = "module example.com/m\n\nrequire github.com/new/dep v1.0.0\n"
+
+	provider := draftPR()
+	provider.pr.Draft = gh.Bool(false)
+	provider.files = append(provider.files, &gh.CommitFile{Filename: gh.String("go.mod")})
+	provider.contents = map[string]string{"base:go.mod": "module example.com/m\n"}
+	setupAnalyze(t, provider, cloneOf(files))
+
+	failOnNewDep = true
+	t.Cleanup(func() { failOnNewDep = false })
+
+	err := runAnalyze(analyzeCmd, nil)
+	require.EqualError(t, err, "1 new external dependencies added")
+	require.Contains(t, provider.comments[0].GetBody(), "- `github.com/new/dep` v1.0.0\n")
+}
+
+func TestWriteAffectedServicesFile(t *testing.T) {
+	result := &analysis.AnalysisResult{
+		Impacts: []*analysis.PackageImpact{
+			{
+				ChangedPackage: "example.com/m/lib",
+				AffectedPackages: []*analysis.AffectedPackage{
+					{Name: "example.com/m/services/billing/api"},
+					{Name: "example.com/m/services/billing/worker"},
+					{Name: "example.com/m/tools/gen"},
+				},
+			},
+			{
+				ChangedPackage: "example.com/m/util",
+				AffectedPackages: []*analysis.AffectedPackage{
+					{Name: "example.com/m/services/billing/api"},
+					{Name: "example.com/m/services/search"},
+				},
+			},
+		},
+	}
+	cfg := config.DefaultConfig()
+	cfg.Services = map[string]string{
+		"**/services/billing/**": "billing",
+		"**/services/search":     "search",
+	}
+
+	path := filepath.Join(t.TempDir(), "services.json")
+	require.NoError(t, writeAffectedServicesFile(path, result, cfg))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var affected affectedServices
+	require.NoError(t, json.Unmarshal(data, &affected))
+	require.Equal(t, affectedServices{
+		Packages: map[string]string{
+			"example.com/m/services/billing/api":    "billing",
+			"example.com/m/services/billing/worker": "billing",
+			"example.com/m/services/search":         "search",
+			"example.com/m/tools/gen":               "",
+		},
+		Services: []string{"billing", "search"},
+	}, affected)
+}
+
+func TestRunAnalyze_DeltaSinceLastAnalysis(t *testing.T) {
+	provider := draftPR()
+	setupAnalyze(t, provider, cloneOf(testRepo))
+
+	// The first report has nothing to compare against
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 1)
+	require.NotContains(t, provider.comments[0].GetBody(), "Changes Since Last Analysis")
+	require.Contains(t, provider.comments[0].GetBody(), "<!-- dependency-guardian:state ")
+
+	// A new push moves the dependency on d from c to e
+	gitRunner = cloneOf(map[string]string{
+		"go.mod": "module example.com/m",
+		"d/d.go": "package d\n\nfunc D() {}",
+		"c/c.go": "package c",
+		"e/e.go": "package e\n\nimport \"example.com/m/d\"\n\nfunc E() { d.D() }",
+	})
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 1, "the comment is updated in place")
+	body := provider.comments[0].GetBody()
+	require.Contains(t, body, "### 🔄 Changes Since Last Analysis\n\n- ➕ Newly affected: `example.com/m/e`\n- ➖ No longer affected: `example.com/m/c`\n")
+
+	// An unchanged impact is reported as such
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Contains(t, provider.comments[0].GetBody(), "The affected packages are unchanged.")
+}
+
+func TestRunAnalyze_CommentModeReply(t *testing.T) {
+	provider := draftPR()
+	setupAnalyze(t, provider, cloneOf(testRepo))
+
+	commentModeFlag = commentModeReply
+	t.Cleanup(func() { commentModeFlag = commentModeUpdate })
+
+	// The first run has no report to reply to
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 1)
+	first := provider.comments[0]
+	require.NotContains(t, first.GetBody(), "Follows up on")
+	first.HTMLURL = gh.String("https://github.com/o/r/pull/1#issuecomment-1001")
+
+	// A re-run leaves the first report and quotes its summary
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 2)
+	require.Equal(t, first, provider.comments[0])
+	reply := provider.comments[1].GetBody()
+	require.True(t, strings.HasPrefix(reply, "<!-- dependency-guardian -->\n> Follows up on the [previous analysis](https://github.com/o/r/pull/1#issuecomment-1001):\n>\n> - **Changed packages**: 1\n> - **Affected packages**: 1\n"))
+	require.Contains(t, reply, "The affected packages are unchanged.", "the delta is computed against the latest report")
+
+	// The next reply follows up on the latest report
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 3)
+	require.Contains(t, provider.comments[2].GetBody(), "> Follows up on the previous analysis:\n")
+}
+
+func TestRunAnalyze_SkipMetadataOnly(t *testing.T) {
+	// d/d.go only changed mode, c/c.go changed content
+	provider := draftPR()
+	provider.files = []*gh.CommitFile{
+		{Filename: gh.String("d/d.go"), Status: gh.String("modified"), Additions: gh.Int(0), Deletions: gh.Int(0), Changes: gh.Int(0)},
+		{Filename: gh.String("c/c.go"), Status: gh.String("modified"), Additions: gh.Int(1), Deletions: gh.Int(1), Changes: gh.Int(2), Patch: gh.String("@@ -1 +1 @@\n-package c\n+package c // c")},
+	}
+	setupAnalyze(t, provider, cloneOf(testRepo))
+
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Contains(t, provider.comments[0].GetBody(), "#### Changed Package: `example.com/m/d`", "files are analyzed by default")
+
+	skipMetadataOnly = true
+	t.Cleanup(func() { skipMetadataOnly = false })
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	body := provider.comments[0].GetBody()
+	require.NotContains(t, body, "`example.com/m/d`")
+	require.Contains(t, body, "#### Changed Package: `example.com/m/c`")
+	require.Contains(t, body, "- **Changed packages**: 1\n")
+}
+
+func TestRunAnalyze_StaleHead(t *testing.T) {
+	provider := draftPR()
+	setupAnalyze(t, provider, cloneOf(testRepo))
+
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.NotContains(t, provider.comments[0].GetBody(), "Analysis may be stale")
+
+	// A commit is pushed while the analysis runs
+	provider.getPRCalls = 0
+	provider.pushedPR = &gh.PullRequest{
+		Number: gh.Int(1),
+		Head:   &gh.PullRequestBranch{SHA: gh.String("newer")},
+	}
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	body := provider.comments[0].GetBody()
+	require.True(t, strings.HasPrefix(body, "<!-- dependency-guardian -->\n> ⚠️ **Analysis may be stale** (analyzed `head`, current head `newer`)."))
+	require.Contains(t, body, "Analyzed base `base` against head `head`")
+}
+
+func TestRunAnalyze_BotLogin(t *testing.T) {
+	provider := draftPR()
+	provider.login = "guardian-bot"
+	// A reviewer quoted the report, marker included
+	provider.comments = []*gh.IssueComment{{
+		ID:   gh.Int64(1),
+		Body: gh.String(reportMarker + "\nquoted by a human"),
+		User: &gh.User{Login: gh.String("alice")},
+	}}
+	setupAnalyze(t, provider, cloneOf(testRepo))
+
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 2)
+	require.Equal(t, reportMarker+"\nquoted by a human", provider.comments[0].GetBody())
+	require.Equal(t, "guardian-bot", provider.comments[1].GetUser().GetLogin())
+
+	// The next run updates the bot's comment and still leaves the human's alone
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 2)
+	require.Equal(t, 1, provider.createCalls)
+	require.Equal(t, reportMarker+"\nquoted by a human", provider.comments[0].GetBody())
+
+	// --bot-login takes precedence over the authenticated user
+	botLoginFlag = "alice"
+	t.Cleanup(func() { botLoginFlag = "" })
+	require.Equal(t, "alice", botIdentity(provider, botLoginFlag))
+}
+
+func TestRunAnalyze_ConfigFromPR(t *testing.T) {
+	provider := draftPR()
+	setupAnalyze(t, provider, cloneOf(testRepo))
+	configFromPR = true
+	t.Cleanup(func() { configFromPR = false })
+
+	// The checkout has no config, the PR head marks c as critical
+	provider.contents = map[string]string{
+		"head:" + config.DefaultConfigName: "critical:\n  packages:\n    - \"**/c\"\n",
+	}
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Contains(t, provider.comments[0].GetBody(), "Critical Packages Affected")
+
+	// Without a config file in the PR head, the defaults apply
+	provider.contents = nil
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.NotContains(t, provider.comments[0].GetBody(), "Critical Packages Affected")
+
+	requireConfig = true
+	t.Cleanup(func() { requireConfig = false })
+	require.ErrorContains(t, runAnalyze(analyzeCmd, nil), "failed to fetch configuration from the PR head")
+}
+
+func TestRunAnalyze_NestedModule(t *testing.T) {
+	provider := draftPR()
+	provider.files = []*gh.CommitFile{{Filename: gh.String("src/d/d.go")}}
+
+	// The repository has no top-level go.mod, the module lives under src
+	repo := make(map[string]string)
+	for name, content := range testRepo {
+		repo["src/"+name] = content
+	}
+	setupAnalyze(t, provider, cloneOf(repo))
+
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	body := provider.comments[0].GetBody()
+	require.Contains(t, body, "example.com/m/d")
+	require.Contains(t, body, "example.com/m/c")
+}
+
+func TestRunAnalyze_CommentThreshold(t *testing.T) {
+	provider := draftPR()
+	setupAnalyze(t, provider, cloneOf(testRepo))
+
+	// Changing d affects only c
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 1)
+
+	commentThreshold = 2
+	t.Cleanup(func() { commentThreshold = 0 })
+
+	// Below the threshold, the previous report is left alone by default
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Equal(t, 1, provider.createCalls)
+	require.Len(t, provider.comments, 1)
+
+	// and deleted on request
+	deleteBelowThresh = true
+	t.Cleanup(func() { deleteBelowThresh = false })
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Equal(t, 1, provider.createCalls)
+	require.Empty(t, provider.comments)
+
+	// At the threshold, the report is posted again
+	commentThreshold = 1
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 1)
+}
+
+func TestRunAnalyze_CommentOnEmpty(t *testing.T) {
+	provider := draftPR()
+	provider.files = []*gh.CommitFile{{Filename: gh.String("README.md")}}
+	setupAnalyze(t, provider, cloneOf(testRepo))
+
+	// Without changed packages, nothing is posted by default
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Zero(t, provider.createCalls)
+	require.Empty(t, provider.comments)
+
+	commentOnEmpty = true
+	t.Cleanup(func() { commentOnEmpty = false })
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 1)
+	require.Contains(t, provider.comments[0].GetBody(), "No changed packages found.")
+}
+
+func TestRunAnalyze_EmptyUpdatesPreviousReport(t *testing.T) {
+	provider := draftPR()
+	setupAnalyze(t, provider, cloneOf(testRepo))
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 1)
+
+	// The report listing d would otherwise be stale
+	provider.files = []*gh.CommitFile{{Filename: gh.String("README.md")}}
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Equal(t, 1, provider.createCalls)
+	require.Len(t, provider.comments, 1)
+	require.Contains(t, provider.comments[0].GetBody(), "No changed packages found.")
+}
+
+func TestRunAnalyze_BestEffortComment(t *testing.T) {
+	provider := draftPR()
+	provider.readOnly = true
+	setupAnalyze(t, provider, cloneOf(testRepo))
+	outputFileFlag = filepath.Join(t.TempDir(), "report.md")
+	t.Cleanup(func() { outputFileFlag = "" })
+
+	require.ErrorIs(t, runAnalyze(analyzeCmd, nil), github.ErrForbidden)
+
+	// The report is still printed, the comment skipped
+	bestEffortComment = true
+	t.Cleanup(func() { bestEffortComment = false })
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Empty(t, provider.comments)
+	content, err := os.ReadFile(outputFileFlag)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "example.com/m/c")
+
+	// Other failures still fail the analysis
+	provider.readOnly = false
+	provider.failCreateCall = provider.createCalls + 1
+	require.ErrorContains(t, runAnalyze(analyzeCmd, nil), "secondary rate limit")
+}
+
+func TestRunAnalyze_ConfigChanges(t *testing.T) {
+	provider := draftPR()
+	provider.files = append(provider.files, &gh.CommitFile{Filename: gh.String(config.DefaultConfigName)})
+	setupAnalyze(t, provider, cloneOf(testRepo))
+
+	// The PR adds a config file marking c as critical
+	provider.contents = map[string]string{
+		"head:" + config.DefaultConfigName: "critical:\n  packages:\n    - \"**/c\"\n",
+	}
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	body := provider.comments[0].GetBody()
+	require.Contains(t, body, "### ⚙️ Config changes in this PR")
+	require.Contains(t, body, "- `critical.packages`\n  - ➕ added `**/c`\n")
+}
+
+func TestRunAnalyze_Deadline(t *testing.T) {
+	provider := draftPR()
+	setupAnalyze(t, provider, cloneOf(testRepo))
+	deadlineFlag = time.Nanosecond
+	t.Cleanup(func() { deadlineFlag = 0 })
+
+	// The partial report is still posted
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Contains(t, provider.comments[0].GetBody(), "**Analysis incomplete** (deadline reached after resolving 0 of 2 packages)")
+}
+
+func TestRunAnalyze_CommentFooter(t *testing.T) {
+	provider := draftPR()
+	repo := map[string]string{".dependency-guardian.yml": "output:\n  footer: \"Config footer for {{.Repo}}\"\n"}
+	for name, content := range testRepo {
+		repo[name] = content
+	}
+	runner := cloneOf(repo)
+	setupAnalyze(t, provider, runner)
+
+	// The configured footer closes the comment, after the hidden state marker
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.True(t, strings.HasSuffix(provider.comments[0].GetBody(), "-->\n\n---\nConfig footer for o/r\n"))
+
+	// The flag overrides the configuration
+	commentFooterFlag = "Generated by dependency-guardian {{.Version}} for {{.Repo}}#{{.PR}}; questions → #platform"
+	t.Cleanup(func() { commentFooterFlag = "" })
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.True(t, strings.HasSuffix(provider.comments[0].GetBody(), "\n---\nGenerated by dependency-guardian dev for o/r#1; questions → #platform\n"))
+	require.Len(t, provider.comments, 1)
+
+	// A broken footer fails before the repository is fetched
+	clones := runner.clones
+	commentFooterFlag = "{{.Unknown}}"
+	require.ErrorContains(t, runAnalyze(analyzeCmd, nil), "failed to render comment footer")
+	require.Equal(t, clones, runner.clones)
+
+	// A broken configured footer fails before the analysis
+	commentFooterFlag = ""
+	repo[".dependency-guardian.yml"] = "output:\n  footer: \"{{.Repo\"\n"
+	require.ErrorContains(t, runAnalyze(analyzeCmd, nil), "failed to parse comment footer")
+	require.Len(t, provider.comments, 1)
+}
+
+func TestRunAnalyze_OutputFile(t *testing.T) {
+	provider := draftPR()
+	setupAnalyze(t, provider, cloneOf(testRepo))
+	outputFileFlag = filepath.Join(t.TempDir(), "reports", "impact")
+	quietFlag = true
+	t.Cleanup(func() { outputFileFlag, quietFlag, formatFlag, noCommentFlag = "", false, formatMarkdown, false })
+
+	// The Markdown report is the one posted, ahead of the state marker
+	formatFlag = formatMarkdown
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	content, err := os.ReadFile(outputFileFlag)
+	require.NoError(t, err)
+	require.Contains(t, string(content), "example.com/m/c")
+	require.True(t, strings.HasPrefix(provider.comments[0].GetBody(), strings.TrimSuffix(string(content), "\n")+"<!--"))
+
+	// The JSON report is the analysis result
+	formatFlag, noCommentFlag = formatJSON, true
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	content, err = os.ReadFile(outputFileFlag)
+	require.NoError(t, err)
+
+	repoPath := t.TempDir()
+	require.NoError(t, cloneOf(testRepo).clone("", "", repoPath))
+	analyzer := analysis.NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetRootPackage("example.com/m")
+	expected, err := analyzer.AnalyzeChangedPackages([]string{"d/d.go"})
+	require.NoError(t, err)
+	expectedJSON, err := json.MarshalIndent(expected, "", "  ")
+	require.NoError(t, err)
+	require.Equal(t, string(expectedJSON)+"\n", string(content))
+
+	// Write failures are reported
+	outputFileFlag = filepath.Join(outputFileFlag, "report.json")
+	require.ErrorContains(t, runAnalyze(analyzeCmd, nil), "failed to create directory for output file")
+}
+
+func TestRunAnalyze_Workspace(t *testing.T) {
+	provider := draftPR()
+	provider.files = []*gh.CommitFile{{Filename: gh.String("lib/util/util.go")}}
+	setupAnalyze(t, provider, cloneOf(map[string]string{
+		"go.work":          "go 1.24\n\nuse (\n\t./app\n\t./lib\n)\n",
+		"app/go.mod":       "module example.com/app",
+		"app/srv/srv.go":   "package srv\n\nimport \"example.com/lib/util\"\n\nvar _ = util.U",
+		"lib/go.mod":       "module example.com/lib",
+		"lib/util/util.go": "package util\n\nvar U = 1",
+	}))
+	parallelism = 1
+	t.Cleanup(func() { parallelism = runtime.NumCPU() })
+
+	// The change in lib reaches the package of app importing it
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+	require.Len(t, provider.comments, 1)
+	require.Contains(t, provider.comments[0].GetBody(), "`example.com/lib/util`")
+	require.Contains(t, provider.comments[0].GetBody(), "`example.com/app/srv`")
+}
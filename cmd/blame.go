@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	gh "github.com/google/go-github/v60/github"
+	"go.uber.org/zap"
+)
+
+// defaultMaxBlameLines bounds the lines blamed for --annotate-owners
+const defaultMaxBlameLines = 1000
+
+// hunkHeader matches the header of a unified diff hunk, capturing the start
+// and length of its old side
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+\d+(?:,\d+)? @@`)
+
+// lineRange is an inclusive range of line numbers
+type lineRange struct {
+	Start, End int
+}
+
+// blameLine is the author of a line as attributed by git blame
+type blameLine struct {
+	Author string
+	Time   time.Time
+}
+
+// changedLines returns the lines of the base version of a file that a PR patch
+// modifies or removes. A hunk that only adds lines contributes its context
+// lines instead, since the code around an insertion is what it changes.
+func changedLines(patch string) []lineRange {
+	var lines []int
+	var hunkStart, hunkLen, old int
+	removed, inHunk := false, false
+
+	endHunk := func() {
+		if inHunk && !removed {
+			for line := hunkStart; line < hunkStart+hunkLen; line++ {
+				lines = append(lines, line)
+			}
+		}
+	}
+
+	for _, line := range strings.Split(patch, "\n") {
+		if m := hunkHeader.FindStringSubmatch(line); m != nil {
+			endHunk()
+			hunkStart, _ = strconv.Atoi(m[1])
+			hunkLen = 1
+			if m[2] != "" {
+				hunkLen, _ = strconv.Atoi(m[2])
+			}
+			old, removed, inHunk = hunkStart, false, true
+			continue
+		}
+		if !inHunk || line == "" {
+			continue
+		}
+		switch line[0] {
+		case ' ':
+			old++
+		case '-':
+			lines = append(lines, old)
+			old++
+			removed = true
+		}
+	}
+	endHunk()
+
+	// Collapse the lines into ranges
+	sort.Ints(lines)
+	var ranges []lineRange
+	for _, line := range lines {
+		if n := len(ranges); n > 0 && line <= ranges[n-1].End+1 {
+			ranges[n-1].End = max(ranges[n-1].End, line)
+			continue
+		}
+		ranges = append(ranges, lineRange{Start: line, End: line})
+	}
+	return ranges
+}
+
+// capRanges truncates ranges to at most limit lines in total, returning the
+// remaining budget
+func capRanges(ranges []lineRange, limit int) ([]lineRange, int) {
+	var capped []lineRange
+	for _, r := range ranges {
+		if limit <= 0 {
+			break
+		}
+		if n := r.End - r.Start + 1; n > limit {
+			r.End = r.Start + limit - 1
+		}
+		limit -= r.End - r.Start + 1
+		capped = append(capped, r)
+	}
+	return capped, limit
+}
+
+// parseBlamePorcelain returns the author of each line of git blame --porcelain
+// output. Commit details are only printed for the first line of each commit.
+func parseBlamePorcelain(out string) []blameLine {
+	authors := make(map[string]*blameLine)
+	var lines []blameLine
+	var current *blameLine
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, "\t") {
+			if current != nil {
+				lines = append(lines, *current)
+			}
+			continue
+		}
+		key, value, _ := strings.Cut(line, " ")
+		switch {
+		case isCommitHash(key):
+			if authors[key] == nil {
+				authors[key] = &blameLine{}
+			}
+			current = authors[key]
+		case current == nil:
+		case key == "author":
+			current.Author = value
+		case key == "author-time":
+			if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+				current.Time = time.Unix(secs, 0).UTC()
+			}
+		}
+	}
+	return lines
+}
+
+// isCommitHash reports whether s is a full SHA-1 or SHA-256 commit hash
+func isCommitHash(s string) bool {
+	if len(s) != 40 && len(s) != 64 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// recentAuthors blames, at baseSHA in the repository at dir, the lines each
+// changed file's patch modifies, and returns the distinct authors per package,
+// most recent first. At most maxLines lines are blamed in total (0 means no
+// limit). packagesOf maps a changed file to the packages containing it.
+func recentAuthors(runner GitRunner, dir, baseSHA string, files []*gh.CommitFile, maxLines int, packagesOf func(file string) []string) (map[string][]string, error) {
+	if err := runner.FetchCommit(dir, baseSHA); err != nil {
+		return nil, fmt.Errorf("failed to fetch base revision for blame: %w", err)
+	}
+
+	latest := make(map[string]map[string]time.Time)
+	budget := maxLines
+	for _, file := range files {
+		// Added files have no previous authors
+		if file.GetStatus() == "added" || file.GetPatch() == "" {
+			continue
+		}
+		pkgs := packagesOf(file.GetFilename())
+		if len(pkgs) == 0 {
+			continue
+		}
+
+		ranges := changedLines(file.GetPatch())
+		if maxLines > 0 {
+			if budget <= 0 {
+				zap.S().Warnw("blame line limit reached, remaining changes are not attributed", "max_blame_lines", maxLines)
+				break
+			}
+			ranges, budget = capRanges(ranges, budget)
+		}
+		if len(ranges) == 0 {
+			continue
+		}
+
+		path := file.GetFilename()
+		if file.GetPreviousFilename() != "" {
+			path = file.GetPreviousFilename()
+		}
+		lines, err := runner.Blame(dir, baseSHA, path, ranges)
+		if err != nil {
+			zap.S().Warnw("failed to blame changed lines, skipping file", "file", path, "error", err)
+			continue
+		}
+		for _, pkg := range pkgs {
+			if latest[pkg] == nil {
+				latest[pkg] = make(map[string]time.Time)
+			}
+			for _, line := range lines {
+				if line.Author == "" {
+					continue
+				}
+				if seen, ok := latest[pkg][line.Author]; !ok || line.Time.After(seen) {
+					latest[pkg][line.Author] = line.Time
+				}
+			}
+		}
+	}
+
+	authors := make(map[string][]string)
+	for pkg, times := range latest {
+		for author := range times {
+			authors[pkg] = append(authors[pkg], author)
+		}
+		sort.Slice(authors[pkg], func(i, j int) bool {
+			x, y := authors[pkg][i], authors[pkg][j]
+			if !times[x].Equal(times[y]) {
+				return times[x].After(times[y])
+			}
+			return x < y
+		})
+	}
+	return authors, nil
+}
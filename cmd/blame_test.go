@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	gh "github.com/google/go-github/v60/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChangedLines(t *testing.T) {
+	patch := `@@ -2,4 +2,4 @@ func A() {
+ 	a := 1
+-	b := 2
+-	c := 3
++	b, c := 2, 3
+ 	d := 4
+@@ -20,2 +20,3 @@ func B() {
+ 	x := 1
++	y := 2
+ 	z := 3
+@@ -40 +41 @@ func C() {
+-	return
++	return nil
+\ No newline at end of file`
+
+	// Modified lines, then the context of the insertion, then a single line
+	require.Equal(t, []lineRange{{3, 4}, {20, 21}, {40, 40}}, changedLines(patch))
+}
+
+func TestCapRanges(t *testing.T) {
+	capped, left := capRanges([]lineRange{{1, 3}, {10, 14}, {20, 20}}, 5)
+	require.Equal(t, []lineRange{{1, 3}, {10, 11}}, capped)
+	require.Zero(t, left)
+}
+
+// commitAs commits the files to the repository as author at the given time
+func (r *testGitRepo) commitAs(author string, when time.Time, files map[string]string) {
+	r.t.Helper()
+	for name, content := range files {
+		require.NoError(r.t, os.WriteFile(filepath.Join(r.dir, filepath.FromSlash(name)), []byte(content), 0644))
+	}
+	r.git("add", "-A")
+	date := when.Format(time.RFC3339)
+	r.gitEnv([]string{
+		"GIT_AUTHOR_NAME=" + author,
+		"GIT_AUTHOR_EMAIL=" + strings.ToLower(author) + "@example.com",
+		"GIT_AUTHOR_DATE=" + date,
+		"GIT_COMMITTER_DATE=" + date,
+	}, "commit", "-q", "-m", "change by "+author)
+}
+
+func TestRecentAuthors(t *testing.T) {
+	repo := newTestGitRepo(t)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	repo.commitAs("Alice", start, map[string]string{
+		"go.mod": "module example.com/repo\n",
+		"a.go":   "package repo\n\nconst (\n\tA = 1\n\tB = 2\n\tC = 3\n)\n",
+	})
+	repo.commitAs("Bob", start.Add(24*time.Hour), map[string]string{
+		"a.go": "package repo\n\nconst (\n\tA = 1\n\tB = 20\n\tC = 3\n)\n",
+	})
+	repo.commitAs("Carol", start.Add(48*time.Hour), map[string]string{
+		"go.mod": "module example.com/repo\n\ngo 1.22\n",
+	})
+	baseSHA := repo.git("rev-parse", "HEAD")
+	repo.commitAs("Dave", start.Add(72*time.Hour), map[string]string{
+		"a.go": "package repo\n\nconst (\n\tA = 10\n\tB = 200\n\tC = 3\n)\n",
+	})
+
+	// The analysis runs in a shallow clone of the head, as analyze does
+	clone := filepath.Join(t.TempDir(), "clone")
+	repo.git("clone", "-q", "--depth", "1", "file://"+repo.dir, clone)
+
+	files := []*gh.CommitFile{{
+		Filename: gh.String("a.go"),
+		Status:   gh.String("modified"),
+		Patch: gh.String(`@@ -3,5 +3,5 @@ package repo
+ const (
+-	A = 1
+-	B = 20
++	A = 10
++	B = 200
+ 	C = 3
+ )`),
+	}}
+	packagesOf := func(file string) []string { return []string{"example.com/repo"} }
+
+	authors, err := recentAuthors(execGitRunner{}, clone, baseSHA, files, 0, packagesOf)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"example.com/repo": {"Bob", "Alice"}}, authors)
+
+	// With a one line budget only the first changed line is blamed
+	authors, err = recentAuthors(execGitRunner{}, clone, baseSHA, files, 1, packagesOf)
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"example.com/repo": {"Alice"}}, authors)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	chokepointsPathFlag string
+	chokepointsTopFlag  int
+)
+
+var chokepointsCmd = &cobra.Command{
+	Use:   "chokepoints",
+	Short: "List the most load-bearing packages of a local repository",
+	Long: `List the internal packages that the most dependency paths funnel through.
+Each package is ranked by the number of (dependent, dependency) pairs connected
+through it: every package depending on it, directly or transitively, times every
+package it depends on. Changes to these packages, or to anything below them,
+spread the furthest.`,
+	RunE: runChokepoints,
+}
+
+func init() {
+	rootCmd.AddCommand(chokepointsCmd)
+
+	chokepointsCmd.Flags().StringVar(&chokepointsPathFlag, "path", ".", "Path to the repository")
+	chokepointsCmd.Flags().IntVar(&chokepointsTopFlag, "top", 10, "Number of packages to list (0 lists all)")
+}
+
+func runChokepoints(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(chokepointsPathFlag, cfgFiles, requireConfig)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	applyConfigFlags(cfg)
+
+	rootPkg, err := getRootPackage(cfg, chokepointsPathFlag)
+	if err != nil {
+		return fmt.Errorf("failed to get root package: %w", err)
+	}
+
+	analyzer := analysis.NewAnalyzer(cfg, chokepointsPathFlag)
+	analyzer.SetRootPackage(rootPkg)
+
+	chokepoints, err := analyzer.Chokepoints(chokepointsTopFlag)
+	if err != nil {
+		return fmt.Errorf("failed to compute chokepoints: %w", err)
+	}
+
+	if len(chokepoints) == 0 {
+		fmt.Println("No chokepoint packages found.")
+		return nil
+	}
+	for i, c := range chokepoints {
+		fmt.Printf("%d. %s: %d pairs (%d dependents × %d dependencies)\n", i+1, c.Name, c.Pairs, c.Dependents, c.Depends)
+	}
+	return nil
+}
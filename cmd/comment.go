@@ -0,0 +1,486 @@
+package cmd
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/cosmos/dependency-guardian/pkg/github"
+	gh "github.com/google/go-github/v60/github"
+	"go.uber.org/zap"
+)
+
+// reportMarker identifies the comments posted by the tool
+const reportMarker = "<!-- dependency-guardian -->"
+
+// Comment modes of --comment-mode
+const (
+	commentModeUpdate = "update" // Edit the report comments in place
+	commentModeReply  = "reply"  // Post each report anew, referencing the previous one
+)
+
+// commentModes are the accepted values of --comment-mode
+var commentModes = []string{commentModeUpdate, commentModeReply}
+
+// maxCommentSize is the number of bytes of report posted per comment, below
+// the GitHub limit of 65536 characters. Longer reports are split across
+// several comments.
+var maxCommentSize = 65000
+
+// partMarkerPattern matches the marker numbering each comment of a report
+var partMarkerPattern = regexp.MustCompile(`<!-- dependency-guardian:part-(\d+) -->`)
+
+// stateMarkerPattern matches the marker holding the encoded snapshot of the
+// analysis a report was rendered from
+var stateMarkerPattern = regexp.MustCompile(`<!-- dependency-guardian:state ([A-Za-z0-9+/=]+) -->`)
+
+// stateMarker returns the hidden marker embedding snapshot in a report
+func stateMarker(snapshot *analysis.Snapshot) (string, error) {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode analysis snapshot: %w", err)
+	}
+	return fmt.Sprintf("<!-- dependency-guardian:state %s -->\n", base64.StdEncoding.EncodeToString(data)), nil
+}
+
+// previousSnapshot returns the snapshot embedded in the report comments on the
+// pull request, or in the report review when no comment holds one, or nil
+// when there is none
+func previousSnapshot(provider github.Provider, owner, repo string, number int, botLogin string) (*analysis.Snapshot, error) {
+	comments, err := reportComments(provider, owner, repo, number, botLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	// In reply mode every analysis leaves a report; the latest one counts
+	for i := len(comments) - 1; i >= 0; i-- {
+		snapshot, err := embeddedSnapshot(comments[i].GetBody())
+		if snapshot != nil || err != nil {
+			return snapshot, err
+		}
+	}
+
+	review, err := reportReview(provider, owner, repo, number, botLogin)
+	if err != nil || review == nil {
+		return nil, err
+	}
+	return embeddedSnapshot(review.GetBody())
+}
+
+// embeddedSnapshot decodes the snapshot held by the state marker of a report,
+// or returns nil when body has no state marker
+func embeddedSnapshot(body string) (*analysis.Snapshot, error) {
+	m := stateMarkerPattern.FindStringSubmatch(body)
+	if m == nil {
+		return nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(m[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode analysis snapshot: %w", err)
+	}
+	var snapshot analysis.Snapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode analysis snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// footerData holds the variables available to the comment footer template
+type footerData struct {
+	Repo    string // owner/repo
+	PR      int
+	Version string
+}
+
+// commentFooter expands the footer template, returning the text appended to
+// the posted report, or "" when there is no footer
+func commentFooter(tmpl string, data footerData) (string, error) {
+	if tmpl == "" {
+		return "", nil
+	}
+	t, err := template.New("footer").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse comment footer: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render comment footer: %w", err)
+	}
+	return "\n---\n" + strings.TrimRight(b.String(), "\n") + "\n", nil
+}
+
+// footerTemplate returns the comment footer template: --comment-footer, or
+// else output.footer of cfg when it is loaded
+func footerTemplate(cfg *config.Config) string {
+	if commentFooterFlag != "" || cfg == nil {
+		return commentFooterFlag
+	}
+	return cfg.Output.Footer
+}
+
+// validateFooter checks that the footer template parses and only uses the
+// fields of footerData, so that a broken footer fails before the analysis
+func validateFooter(cfg *config.Config) error {
+	_, err := commentFooter(footerTemplate(cfg), footerData{})
+	return err
+}
+
+// commentBody returns the comment posting report: the report, the state
+// marker of result and the footer, which --comment-footer overrides. A state
+// marker longer than half a comment is left out, so that splitting the report
+// never cuts it.
+func commentBody(report string, result *analysis.AnalysisResult, cfg *config.Config, data footerData) (string, error) {
+	state, err := stateMarker(result.Snapshot())
+	if err != nil {
+		return "", err
+	}
+	if maxState := maxCommentSize / 2; len(state) > maxState {
+		zap.S().Warnw("analysis snapshot too large to embed, the next report will show no changes since this one", "size", len(state), "max", maxState)
+		state = ""
+	}
+	footer, err := commentFooter(footerTemplate(cfg), data)
+	if err != nil {
+		return "", err
+	}
+	return report + state + footer, nil
+}
+
+// staleBanner warns that the pull request head moved after the analysis
+func staleBanner(analyzedSHA, currentSHA string, emoji bool) string {
+	badge := "[WARNING]"
+	if emoji {
+		badge = "⚠️"
+	}
+	return fmt.Sprintf("> %s **Analysis may be stale** (analyzed `%s`, current head `%s`). A newer run will update this comment.\n\n", badge, analyzedSHA, currentSHA)
+}
+
+// withBanner inserts banner at the top of report, after its marker
+func withBanner(report, banner string) string {
+	if rest, ok := strings.CutPrefix(report, reportMarker+"\n"); ok {
+		return reportMarker + "\n" + banner + rest
+	}
+	return banner + report
+}
+
+// partMarker returns the marker of the nth comment of a report
+func partMarker(n int) string {
+	return fmt.Sprintf("<!-- dependency-guardian:part-%d -->", n)
+}
+
+// splitReport splits report into comment bodies of at most limit bytes. A
+// report that fits in one comment is posted as is; otherwise each body is
+// headed by its part marker. Reports are split on line boundaries; only lines
+// longer than a whole comment are cut. A part always holds at least one rune,
+// even when limit leaves no room next to the marker.
+func splitReport(report string, limit int) []string {
+	if len(report) <= limit {
+		return []string{report}
+	}
+
+	var parts []string
+	var b strings.Builder
+	headerLen := 0
+
+	start := func() {
+		b.Reset()
+		b.WriteString(partMarker(len(parts)+1) + "\n")
+		headerLen = b.Len()
+	}
+	flush := func() {
+		if b.Len() > headerLen {
+			parts = append(parts, b.String())
+		}
+		start()
+	}
+
+	start()
+	for _, line := range strings.SplitAfter(report, "\n") {
+		for len(line) > 0 {
+			room := limit - b.Len()
+			if len(line) <= room {
+				b.WriteString(line)
+				break
+			}
+			if b.Len() > headerLen {
+				// Move the line to a fresh comment rather than cutting it
+				flush()
+				continue
+			}
+
+			// The line alone exceeds a comment; cut it on a rune boundary
+			for room > 0 && !utf8.RuneStart(line[room]) {
+				room--
+			}
+			if room <= 0 {
+				_, room = utf8.DecodeRuneInString(line)
+			}
+			b.WriteString(line[:room])
+			line = line[room:]
+			flush()
+		}
+	}
+	flush()
+	return parts
+}
+
+// reportPart returns the part number of a comment posted by the tool, treating
+// a comment with only the report marker as the first part. It returns 0 for
+// other comments.
+func reportPart(body string) int {
+	if m := partMarkerPattern.FindStringSubmatch(body); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return n
+	}
+	if strings.Contains(body, reportMarker) {
+		return 1
+	}
+	return 0
+}
+
+// botIdentity returns the login whose comments the tool may edit: flagLogin if
+// set, the authenticated user otherwise. It returns "" when the user cannot be
+// determined, as with the GitHub Actions token, in which case every report
+// comment is treated as the tool's own.
+func botIdentity(provider github.Provider, flagLogin string) string {
+	if flagLogin != "" {
+		return flagLogin
+	}
+	login, err := provider.AuthenticatedLogin()
+	if err != nil {
+		zap.S().Warnw("failed to determine the bot login, comments will not be checked by author", "error", err)
+		return ""
+	}
+	return login
+}
+
+// reportComments lists the report comments on the pull request, leaving out
+// those not authored by botLogin unless it is empty. A marker copied into a
+// comment by someone else is not mistaken for the report.
+func reportComments(provider github.Provider, owner, repo string, number int, botLogin string) ([]*gh.IssueComment, error) {
+	comments, err := provider.ListComments(owner, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PR comments: %w", err)
+	}
+
+	var reports []*gh.IssueComment
+	for _, comment := range comments {
+		if reportPart(comment.GetBody()) == 0 {
+			continue
+		}
+		if botLogin != "" && !strings.EqualFold(comment.GetUser().GetLogin(), botLogin) {
+			zap.S().Debugw("skipping report comment by another author", "comment_id", comment.GetID(), "author", comment.GetUser().GetLogin())
+			continue
+		}
+		reports = append(reports, comment)
+	}
+	return reports, nil
+}
+
+// reportReview returns the latest review of the pull request holding a
+// report, leaving out those not authored by botLogin unless it is empty. It
+// returns nil when there is none.
+func reportReview(provider github.Provider, owner, repo string, number int, botLogin string) (*gh.PullRequestReview, error) {
+	reviews, err := provider.ListReviews(owner, repo, number)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PR reviews: %w", err)
+	}
+
+	var latest *gh.PullRequestReview
+	for _, review := range reviews {
+		if reportPart(review.GetBody()) == 0 {
+			continue
+		}
+		if botLogin != "" && !strings.EqualFold(review.GetUser().GetLogin(), botLogin) {
+			zap.S().Debugw("skipping report review by another author", "review_id", review.GetID(), "author", review.GetUser().GetLogin())
+			continue
+		}
+		if latest == nil || review.GetID() > latest.GetID() {
+			latest = review
+		}
+	}
+	return latest, nil
+}
+
+// postReport reconciles the report comments on the pull request with report:
+// existing parts are updated, missing parts created and surplus parts, such as
+// those of a longer previous report or duplicates left by an interrupted run,
+// deleted. A run that fails midway is repaired by the next one. Without report
+// comments, a report posted as a review is updated instead, provided the new
+// report fits in its body.
+func postReport(provider github.Provider, owner, repo string, number int, report, botLogin string) error {
+	comments, err := reportComments(provider, owner, repo, number, botLogin)
+	if err != nil {
+		return err
+	}
+
+	if len(comments) == 0 && len(report) <= maxCommentSize {
+		review, err := reportReview(provider, owner, repo, number, botLogin)
+		if err != nil {
+			return err
+		}
+		if review != nil {
+			if review.GetBody() != report {
+				zap.S().Infow("updating report review", "review_id", review.GetID())
+				if err := provider.UpdateReview(owner, repo, number, review.GetID(), report); err != nil {
+					return fmt.Errorf("failed to update the PR review: %w", err)
+				}
+			}
+			return nil
+		}
+	}
+
+	existing := make(map[int]*gh.IssueComment)
+	var surplus []*gh.IssueComment
+	for _, comment := range comments {
+		part := reportPart(comment.GetBody())
+		if _, ok := existing[part]; ok {
+			surplus = append(surplus, comment)
+			continue
+		}
+		existing[part] = comment
+	}
+
+	parts := splitReport(report, maxCommentSize)
+	for i, body := range parts {
+		part := i + 1
+		comment, ok := existing[part]
+		delete(existing, part)
+		switch {
+		case !ok:
+			zap.S().Infow("creating report comment", "part", part, "parts", len(parts))
+			if err := provider.CreateComment(owner, repo, number, body); err != nil {
+				return fmt.Errorf("failed to create part %d of the PR comment: %w", part, err)
+			}
+		case comment.GetBody() != body:
+			zap.S().Infow("updating report comment", "part", part, "parts", len(parts), "comment_id", comment.GetID())
+			if err := provider.UpdateComment(owner, repo, comment.GetID(), body); err != nil {
+				return fmt.Errorf("failed to update part %d of the PR comment: %w", part, err)
+			}
+		}
+	}
+
+	for _, comment := range existing {
+		surplus = append(surplus, comment)
+	}
+	sort.Slice(surplus, func(i, j int) bool { return surplus[i].GetID() < surplus[j].GetID() })
+	for _, comment := range surplus {
+		zap.S().Infow("deleting stale report comment", "comment_id", comment.GetID())
+		if err := provider.DeleteComment(owner, repo, comment.GetID()); err != nil {
+			return fmt.Errorf("failed to delete stale PR comment: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// deleteReport deletes the report comments on the pull request, such as when
+// the impact no longer warrants one
+func deleteReport(provider github.Provider, owner, repo string, number int, botLogin string) error {
+	comments, err := reportComments(provider, owner, repo, number, botLogin)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		zap.S().Infow("deleting report comment", "comment_id", comment.GetID())
+		if err := provider.DeleteComment(owner, repo, comment.GetID()); err != nil {
+			return fmt.Errorf("failed to delete PR comment: %w", err)
+		}
+	}
+	return nil
+}
+
+// nonEmptyReport reports whether the latest report on the pull request lists
+// changed packages, as opposed to stating there are none
+func nonEmptyReport(provider github.Provider, owner, repo string, number int, botLogin string) (bool, error) {
+	comments, err := reportComments(provider, owner, repo, number, botLogin)
+	if err != nil {
+		return false, err
+	}
+	for i := len(comments) - 1; i >= 0; i-- {
+		if reportPart(comments[i].GetBody()) == 1 {
+			return !strings.Contains(comments[i].GetBody(), analysis.NoChangedPackages), nil
+		}
+	}
+
+	review, err := reportReview(provider, owner, repo, number, botLogin)
+	if err != nil || review == nil {
+		return false, err
+	}
+	return !strings.Contains(review.GetBody(), analysis.NoChangedPackages), nil
+}
+
+// replyReport posts report as new comments following up on the latest report
+// on the pull request, whose summary it quotes, leaving the previous reports
+// in place. Without a previous report, the report is posted as postReport
+// would.
+func replyReport(provider github.Provider, owner, repo string, number int, report, botLogin string) error {
+	comments, err := reportComments(provider, owner, repo, number, botLogin)
+	if err != nil {
+		return err
+	}
+	var previous *gh.IssueComment
+	for _, comment := range comments {
+		if reportPart(comment.GetBody()) == 1 && (previous == nil || comment.GetID() > previous.GetID()) {
+			previous = comment
+		}
+	}
+	if previous == nil {
+		return postReport(provider, owner, repo, number, report, botLogin)
+	}
+
+	report = withBanner(report, replyBanner(previous))
+	parts := splitReport(report, maxCommentSize)
+	for i, body := range parts {
+		zap.S().Infow("creating report comment in reply to the previous report", "part", i+1, "parts", len(parts), "previous_comment_id", previous.GetID())
+		if err := provider.CreateComment(owner, repo, number, body); err != nil {
+			return fmt.Errorf("failed to create part %d of the PR comment: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// replyBanner links a report to the previous one and quotes its summary counts
+func replyBanner(previous *gh.IssueComment) string {
+	var b strings.Builder
+	if url := previous.GetHTMLURL(); url != "" {
+		b.WriteString(fmt.Sprintf("> Follows up on the [previous analysis](%s)", url))
+	} else {
+		b.WriteString("> Follows up on the previous analysis")
+	}
+	summary := reportSummary(previous.GetBody())
+	if len(summary) == 0 {
+		b.WriteString(".\n\n")
+		return b.String()
+	}
+	b.WriteString(":\n>\n")
+	for _, line := range summary {
+		b.WriteString("> " + line + "\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// reportSummary returns the count lines of the summary of a report comment,
+// or nil when it has none, such as a report split across several comments
+func reportSummary(body string) []string {
+	_, rest, ok := strings.Cut(body, "### Analysis Summary:\n\n")
+	if !ok {
+		return nil
+	}
+	var lines []string
+	for _, line := range strings.Split(rest, "\n") {
+		if !strings.HasPrefix(line, "- **") {
+			break
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
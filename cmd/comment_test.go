@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	gh "github.com/google/go-github/v60/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitReport(t *testing.T) {
+	require.Equal(t, []string{"short\n"}, splitReport("short\n", 100))
+
+	report := strings.Repeat("0123456789abcdefghi\n", 10) // 200 bytes
+	parts := splitReport(report, 100)
+	require.Len(t, parts, 4, "three lines fit next to the part marker")
+	var joined strings.Builder
+	for i, part := range parts {
+		require.LessOrEqual(t, len(part), 100)
+		marker := partMarker(i+1) + "\n"
+		require.True(t, strings.HasPrefix(part, marker))
+		joined.WriteString(strings.TrimPrefix(part, marker))
+	}
+	require.Equal(t, report, joined.String(), "no content is lost or reordered")
+
+	// A line longer than a comment is cut, without splitting multi-byte runes
+	long := strings.Repeat("🚨", 50)
+	parts = splitReport(long, 64)
+	joined.Reset()
+	for i, part := range parts {
+		require.LessOrEqual(t, len(part), 64)
+		require.True(t, strings.ToValidUTF8(part, "?") == part)
+		joined.WriteString(strings.TrimPrefix(part, partMarker(i+1)+"\n"))
+	}
+	require.Equal(t, long, joined.String())
+
+	// A limit leaving no room next to the part marker still makes progress
+	for _, limit := range []int{len(partMarker(1)), len(partMarker(1)) + 1, 1} {
+		parts = splitReport("ab\n🚨\n", limit)
+		joined.Reset()
+		for i, part := range parts {
+			joined.WriteString(strings.TrimPrefix(part, partMarker(i+1)+"\n"))
+		}
+		require.Equal(t, "ab\n🚨\n", joined.String(), "limit %d", limit)
+	}
+}
+
+func TestPostReport_ResumesAfterPartialFailure(t *testing.T) {
+	orig := maxCommentSize
+	maxCommentSize = 140
+	t.Cleanup(func() { maxCommentSize = orig })
+
+	report := reportMarker + "\n" + strings.Repeat("- `example.com/m/pkg` — directly affected\n", 5)
+	parts := splitReport(report, maxCommentSize)
+	require.Len(t, parts, 3)
+
+	// A human comment and a stale fourth part left by a previous, longer report
+	provider := &fakeProvider{
+		comments: []*gh.IssueComment{
+			{ID: gh.Int64(1), Body: gh.String("LGTM")},
+			{ID: gh.Int64(2), Body: gh.String(partMarker(4) + "\nold tail")},
+		},
+	}
+
+	// Creating the second part fails: the PR is left with a partial report
+	provider.failCreateCall = 2
+	err := postReport(provider, "o", "r", 1, report, "")
+	require.ErrorContains(t, err, "failed to create part 2 of the PR comment")
+	require.Len(t, provider.comments, 3)
+
+	// A second part was posted by hand in the meantime, e.g. by an older run
+	provider.comments = append(provider.comments, &gh.IssueComment{ID: gh.Int64(3), Body: gh.String(partMarker(2) + "\nstale")})
+
+	// The re-run reconciles the comments with the report
+	provider.failCreateCall = 0
+	require.NoError(t, postReport(provider, "o", "r", 1, report, ""))
+
+	var bodies []string
+	for _, comment := range provider.comments {
+		bodies = append(bodies, comment.GetBody())
+	}
+	require.ElementsMatch(t, append([]string{"LGTM"}, parts...), bodies)
+
+	// Running again with the same report changes nothing
+	creates := provider.createCalls
+	require.NoError(t, postReport(provider, "o", "r", 1, report, ""))
+	require.Equal(t, creates, provider.createCalls)
+	require.Len(t, provider.comments, 4)
+
+	// A shorter report deletes the surplus parts and replaces the first one
+	require.NoError(t, postReport(provider, "o", "r", 1, reportMarker+"\nsmall\n", ""))
+	bodies = nil
+	for _, comment := range provider.comments {
+		bodies = append(bodies, comment.GetBody())
+	}
+	require.ElementsMatch(t, []string{"LGTM", reportMarker + "\nsmall\n"}, bodies)
+}
+
+func TestCommentBody_StateMarkerStaysWhole(t *testing.T) {
+	orig := maxCommentSize
+	maxCommentSize = 400
+	t.Cleanup(func() { maxCommentSize = orig })
+
+	resultOf := func(affected int) *analysis.AnalysisResult {
+		impact := &analysis.PackageImpact{ChangedPackage: "example.com/m/d"}
+		for i := 0; i < affected; i++ {
+			impact.AffectedPackages = append(impact.AffectedPackages, &analysis.AffectedPackage{Name: fmt.Sprintf("example.com/m/p%d", i), Depth: 1})
+		}
+		return &analysis.AnalysisResult{Impacts: []*analysis.PackageImpact{impact}}
+	}
+	report := reportMarker + "\n" + strings.Repeat("line of the report\n", 30)
+
+	// A small snapshot lands whole in one part of the split report
+	result := resultOf(2)
+	body, err := commentBody(report, result, config.DefaultConfig(), footerData{})
+	require.NoError(t, err)
+	parts := splitReport(body, maxCommentSize)
+	require.Greater(t, len(parts), 1)
+	snapshot, err := embeddedSnapshot(parts[len(parts)-1])
+	require.NoError(t, err)
+	require.Equal(t, result.Snapshot(), snapshot)
+
+	// One that would be cut across parts is left out
+	body, err = commentBody(report, resultOf(20), config.DefaultConfig(), footerData{})
+	require.NoError(t, err)
+	require.NotContains(t, body, "dependency-guardian:state")
+	for _, part := range splitReport(body, maxCommentSize) {
+		snapshot, err := embeddedSnapshot(part)
+		require.NoError(t, err)
+		require.Nil(t, snapshot)
+	}
+}
+
+func TestPostReport_UpdatesReportReview(t *testing.T) {
+	snapshot := &analysis.Snapshot{Affected: []string{"example.com/m/c"}}
+	state, err := stateMarker(snapshot)
+	require.NoError(t, err)
+
+	// A previous run posted its report as a review, not as a comment
+	provider := &fakeProvider{
+		comments: []*gh.IssueComment{{ID: gh.Int64(1), Body: gh.String("LGTM")}},
+		reviews: []*gh.PullRequestReview{
+			{ID: gh.Int64(7), Body: gh.String(reportMarker + "\nold\n" + state), User: &gh.User{Login: gh.String("bot")}},
+			{ID: gh.Int64(8), Body: gh.String(reportMarker + "\ncopied\n"), User: &gh.User{Login: gh.String("alice")}},
+		},
+	}
+
+	previous, err := previousSnapshot(provider, "o", "r", 1, "bot")
+	require.NoError(t, err)
+	require.Equal(t, snapshot, previous)
+
+	require.NoError(t, postReport(provider, "o", "r", 1, reportMarker+"\nnew\n", "bot"))
+	require.Zero(t, provider.createCalls, "the review is updated instead of commenting")
+	require.Equal(t, reportMarker+"\nnew\n", provider.reviews[0].GetBody())
+	require.Equal(t, reportMarker+"\ncopied\n", provider.reviews[1].GetBody())
+}
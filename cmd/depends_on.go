@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	dependsOnPathFlag     string
+	dependsOnCriticalFlag bool
+)
+
+var dependsOnCmd = &cobra.Command{
+	Use:   "depends-on <package>",
+	Short: "List every internal package a package depends on",
+	Long: `Resolve the dependency graph of a local repository and print the internal
+packages the given package depends on, directly or transitively: the packages
+whose changes may affect it. The package may be given relative to the module
+path.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runDependsOn,
+}
+
+func init() {
+	rootCmd.AddCommand(dependsOnCmd)
+
+	dependsOnCmd.Flags().StringVar(&dependsOnPathFlag, "path", ".", "Path to the repository")
+	dependsOnCmd.Flags().BoolVar(&dependsOnCriticalFlag, "critical", false, "Only list the critical dependencies")
+}
+
+func runDependsOn(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(dependsOnPathFlag, cfgFiles, requireConfig)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	applyConfigFlags(cfg)
+
+	rootPkg, err := getRootPackage(cfg, dependsOnPathFlag)
+	if err != nil {
+		return fmt.Errorf("failed to get root package: %w", err)
+	}
+
+	analyzer := analysis.NewAnalyzer(cfg, dependsOnPathFlag)
+	analyzer.SetRootPackage(rootPkg)
+
+	deps, err := analyzer.DependsOn(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to list dependencies: %w", err)
+	}
+	if dependsOnCriticalFlag {
+		var critical []string
+		for _, dep := range deps {
+			if cfg.IsCriticalPackage(dep) {
+				critical = append(critical, dep)
+			}
+		}
+		deps = critical
+	}
+
+	fmt.Print(formatDependencies(deps))
+	return nil
+}
+
+// formatDependencies renders one package per line
+func formatDependencies(deps []string) string {
+	if len(deps) == 0 {
+		return "No internal dependencies found.\n"
+	}
+	return strings.Join(deps, "\n") + "\n"
+}
@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/cosmos/dependency-guardian/pkg/report"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+var (
+	diffBaseFlag string
+	diffHeadFlag string
+	diffPathFlag string
+	sinceFlag    time.Duration
+	formatFlag   string
+
+	outputFileFlag string
+	quietFlag      bool
+)
+
+var diffImpactCmd = &cobra.Command{
+	Use:   "diff-impact",
+	Short: "Analyze the cumulative dependency impact between two refs",
+	Long: `Analyze the cumulative dependency impact of every change between two git refs
+of a local repository, such as a release tag and the main branch, or of every
+commit made within a recent time window (--since).
+This command will:
+1. Compute the files changed between the base and head refs with git diff, or
+   touched by the commits of the window with git log
+2. Check out the head ref into a temporary worktree
+3. Show the impact of the changes on other packages in the repository`,
+	RunE: runDiffImpact,
+}
+
+func init() {
+	rootCmd.AddCommand(diffImpactCmd)
+
+	diffImpactCmd.Flags().StringVar(&diffBaseFlag, "base", "", "Base git ref (e.g. v1.2.0)")
+	diffImpactCmd.Flags().DurationVar(&sinceFlag, "since", 0, "Analyze the files touched by commits within this duration before now (e.g. 24h) instead of --base")
+	diffImpactCmd.Flags().StringVar(&diffHeadFlag, "head", "HEAD", "Head git ref")
+	diffImpactCmd.Flags().StringVar(&diffPathFlag, "path", ".", "Path to the local git repository")
+	diffImpactCmd.Flags().StringVar(&formatFlag, "format", formatMarkdown, "Report format ("+strings.Join(reportFormats, ", ")+")")
+	diffImpactCmd.Flags().StringVar(&outputFileFlag, "output-file", "", "Also write the report, in the selected format, to this file")
+	diffImpactCmd.Flags().BoolVar(&quietFlag, "quiet", false, "Do not print the report to stdout")
+	diffImpactCmd.Flags().BoolVar(&noEmojiFlag, "no-emoji", false, "Render text markers such as [CRITICAL] instead of emoji, overriding output.emoji")
+	diffImpactCmd.Flags().BoolVar(&noEmojiFlag, "plain", false, "Alias of --no-emoji")
+	diffImpactCmd.Flags().MarkDeprecated("plain", "use --no-emoji instead")
+	diffImpactCmd.MarkFlagsOneRequired("base", "since")
+	diffImpactCmd.MarkFlagsMutuallyExclusive("base", "since")
+}
+
+func runDiffImpact(cmd *cobra.Command, args []string) error {
+	if err := validateFormat(formatFlag); err != nil {
+		return err
+	}
+	if cmd.Flags().Changed("since") && sinceFlag <= 0 {
+		return fmt.Errorf("--since must be positive")
+	}
+
+	var result *analysis.AnalysisResult
+	var err error
+	base := diffBaseFlag
+
+	if sinceFlag > 0 {
+		since := time.Now().Add(-sinceFlag)
+		base = "since " + since.UTC().Format(time.RFC3339)
+		result, err = analyzeSince(gitRunner, diffPathFlag, diffHeadFlag, since)
+	} else {
+		result, err = analyzeRefRange(gitRunner, diffPathFlag, diffBaseFlag, diffHeadFlag)
+	}
+	if err != nil {
+		return err
+	}
+
+	if noEmojiFlag {
+		result.NoEmoji = true
+	}
+
+	ctx := analysis.ResultContext{
+		BaseSHA: base,
+		HeadSHA: diffHeadFlag,
+	}
+	text := func() string { return result.TextWithContext(ctx) }
+	output, err := renderOutput(formatFlag, result, result.StringWithContext(ctx), text)
+	if err != nil {
+		return err
+	}
+	return emitOutput(output)
+}
+
+// Report formats accepted by --format
+const (
+	formatMarkdown = "markdown"
+	formatText     = "text"
+	formatMatrix   = "gha-matrix" // GitHub Actions matrix of the packages to test
+	formatJSON     = "json"       // The analysis result
+	formatShield   = "shield"     // shields.io endpoint badge of the impact
+	formatJUnit    = "junit"      // JUnit XML, failing the changed packages affecting critical ones
+)
+
+var reportFormats = []string{formatMarkdown, formatText, formatMatrix, formatJSON, formatShield, formatJUnit}
+
+// validateFormat checks the value of --format
+func validateFormat(format string) error {
+	if !slices.Contains(reportFormats, format) {
+		return fmt.Errorf("unsupported format %q, expected one of %s", format, strings.Join(reportFormats, ", "))
+	}
+	return nil
+}
+
+// renderOutput renders the report in format. markdown is the Markdown report,
+// which the caller may have abridged, and text renders the plain text report.
+func renderOutput(format string, result *analysis.AnalysisResult, markdown string, text func() string) (string, error) {
+	switch format {
+	case formatText:
+		return text(), nil
+	case formatMatrix:
+		return matrixJSON(result)
+	case formatJSON:
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode analysis result: %w", err)
+		}
+		return string(data), nil
+	case formatShield:
+		data, err := json.Marshal(result.Shield())
+		if err != nil {
+			return "", fmt.Errorf("failed to encode shield: %w", err)
+		}
+		return string(data), nil
+	case formatJUnit:
+		data, err := xml.MarshalIndent(report.JUnit(result), "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode JUnit report: %w", err)
+		}
+		return xml.Header + string(data), nil
+	default:
+		return markdown, nil
+	}
+}
+
+// emitOutput prints the rendered report unless --quiet is set, and writes it
+// to --output-file, creating its directory, if set
+func emitOutput(output string) error {
+	if !quietFlag {
+		fmt.Println(output)
+	}
+	if outputFileFlag == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(outputFileFlag), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for output file %s: %w", outputFileFlag, err)
+	}
+	if err := os.WriteFile(outputFileFlag, []byte(output+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write output file %s: %w", outputFileFlag, err)
+	}
+	return nil
+}
+
+// matrixJSON renders the changed and affected packages as a GitHub Actions
+// matrix, collapsed to a single entry beyond the GitHub limit
+func matrixJSON(result *analysis.AnalysisResult) (string, error) {
+	data, err := json.Marshal(result.Matrix(analysis.MaxMatrixEntries))
+	if err != nil {
+		return "", fmt.Errorf("failed to encode matrix: %w", err)
+	}
+	return string(data), nil
+}
+
+// analyzeRefRange analyzes the files changed between base and head in the
+// repository at repoPath, resolving dependencies as of head.
+func analyzeRefRange(runner GitRunner, repoPath, base, head string) (*analysis.AnalysisResult, error) {
+	changedFiles, err := runner.ChangedFiles(repoPath, base, head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute changed files between %s and %s: %w", base, head, err)
+	}
+	zap.S().Infow("computed changed files", "base", base, "head", head, "files", len(changedFiles))
+
+	return analyzeAtRef(runner, repoPath, head, changedFiles)
+}
+
+// analyzeSince analyzes the union of files touched by the commits reachable
+// from head that were made after since, resolving dependencies as of head. A
+// window without commits yields an empty result.
+func analyzeSince(runner GitRunner, repoPath, head string, since time.Time) (*analysis.AnalysisResult, error) {
+	changedFiles, err := runner.ChangedFilesSince(repoPath, head, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute files changed since %s: %w", since.Format(time.RFC3339), err)
+	}
+	zap.S().Infow("computed changed files", "since", since, "head", head, "files", len(changedFiles))
+
+	return analyzeAtRef(runner, repoPath, head, changedFiles)
+}
+
+// analyzeAtRef analyzes changedFiles against a temporary worktree of the
+// repository at repoPath checked out at head.
+func analyzeAtRef(runner GitRunner, repoPath, head string, changedFiles []string) (*analysis.AnalysisResult, error) {
+	// Analyze the tree as of head, independently of the current checkout
+	worktreeDir, err := os.MkdirTemp("", "dep-guardian-worktree-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	defer os.RemoveAll(worktreeDir)
+
+	if err := runner.AddWorktree(repoPath, worktreeDir, head); err != nil {
+		return nil, fmt.Errorf("failed to check out %s: %w", head, err)
+	}
+	defer func() {
+		if err := runner.RemoveWorktree(repoPath, worktreeDir); err != nil {
+			zap.S().Warnw("failed to remove worktree", "path", worktreeDir, "error", err)
+		}
+	}()
+
+	cfg, err := config.LoadConfig(worktreeDir, cfgFiles, requireConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+	applyConfigFlags(cfg)
+
+	rootPkg, err := getRootPackage(cfg, worktreeDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get root package: %w", err)
+	}
+
+	analyzer := analysis.NewAnalyzer(cfg, worktreeDir)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages(changedFiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to analyze changes: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeRefRange(t *testing.T) {
+	repo := newTestGitRepo(t)
+	repo.commit("initial", map[string]string{
+		"go.mod":     "module example.com/m",
+		"d/d.go":     "package d\n\nfunc D() {}",
+		"e/e.go":     "package e\n\nfunc E() {}",
+		"c/c.go":     "package c\n\nimport \"example.com/m/d\"\n\nfunc C() { d.D() }",
+		"app/app.go": "package app\n\nimport \"example.com/m/e\"\n\nfunc App() { e.E() }",
+	})
+	repo.git("tag", "v1.0.0")
+
+	repo.commit("change d", map[string]string{"d/d.go": "package d\n\nfunc D() { println() }"})
+	repo.commit("add docs", map[string]string{"README.md": "docs"})
+	repo.git("tag", "v1.1.0")
+
+	// Changes after the head tag are not part of the range
+	repo.commit("change e", map[string]string{"e/e.go": "package e\n\nfunc E() { println() }"})
+
+	result, err := analyzeRefRange(execGitRunner{}, repo.dir, "v1.0.0", "v1.1.0")
+	require.NoError(t, err)
+
+	require.Len(t, result.Impacts, 1)
+	require.Equal(t, "example.com/m/d", result.Impacts[0].ChangedPackage)
+	require.Len(t, result.Impacts[0].AffectedPackages, 1)
+	require.Equal(t, "example.com/m/c", result.Impacts[0].AffectedPackages[0].Name)
+
+	// The temporary worktree is cleaned up
+	require.NotContains(t, repo.git("worktree", "list"), "dep-guardian-worktree")
+}
+
+func TestAnalyzeSince(t *testing.T) {
+	now := time.Now()
+	repo := newTestGitRepo(t)
+	repo.commitAt("initial", now.Add(-72*time.Hour), map[string]string{
+		"go.mod":     "module example.com/m",
+		"d/d.go":     "package d\n\nfunc D() {}",
+		"e/e.go":     "package e\n\nfunc E() {}",
+		"c/c.go":     "package c\n\nimport \"example.com/m/d\"\n\nfunc C() { d.D() }",
+		"app/app.go": "package app\n\nimport \"example.com/m/e\"\n\nfunc App() { e.E() }",
+	})
+	repo.commitAt("change e", now.Add(-48*time.Hour), map[string]string{"e/e.go": "package e\n\nfunc E() { println() }"})
+	repo.commitAt("change d", now.Add(-2*time.Hour), map[string]string{"d/d.go": "package d\n\nfunc D() { println() }"})
+	repo.commitAt("change d again", now.Add(-time.Hour), map[string]string{"d/d.go": "package d\n\nfunc D() { println(1) }"})
+
+	files, err := execGitRunner{}.ChangedFilesSince(repo.dir, "HEAD", now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, []string{"d/d.go"}, files)
+
+	result, err := analyzeSince(execGitRunner{}, repo.dir, "HEAD", now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	require.Len(t, result.Impacts, 1)
+	require.Equal(t, "example.com/m/d", result.Impacts[0].ChangedPackage)
+	require.Equal(t, []string{"example.com/m/c"}, result.AffectedPackageNames())
+
+	// No commits in the window
+	result, err = analyzeSince(execGitRunner{}, repo.dir, "HEAD", now.Add(-time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, result.Impacts)
+	require.Contains(t, result.String(), "No changed packages found.")
+}
+
+func TestRunDiffImpact_InvalidSince(t *testing.T) {
+	for _, since := range []string{"0", "-1h"} {
+		require.NoError(t, diffImpactCmd.Flags().Set("since", since))
+		t.Cleanup(func() {
+			sinceFlag = 0
+			diffImpactCmd.Flags().Lookup("since").Changed = false
+		})
+
+		err := runDiffImpact(diffImpactCmd, nil)
+		require.ErrorContains(t, err, "--since must be positive")
+	}
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	explainPathFlag     string
+	explainFromFlag     string
+	explainToFlag       string
+	explainMaxPathsFlag int
+)
+
+var explainCmd = &cobra.Command{
+	Use:   "explain",
+	Short: "List every import chain from one package to another",
+	Long: `Resolve the dependency graph of a local repository and print every distinct
+import chain through which package --from depends on package --to, not only the
+shortest one. Packages may be given relative to the module path.`,
+	RunE: runExplain,
+}
+
+func init() {
+	rootCmd.AddCommand(explainCmd)
+
+	explainCmd.Flags().StringVar(&explainPathFlag, "path", ".", "Path to the repository")
+	explainCmd.Flags().StringVar(&explainFromFlag, "from", "", "Importing package")
+	explainCmd.Flags().StringVar(&explainToFlag, "to", "", "Imported package")
+	explainCmd.Flags().IntVar(&explainMaxPathsFlag, "max-paths", 20, "Maximum number of chains to print (0 prints all)")
+	explainCmd.MarkFlagRequired("from")
+	explainCmd.MarkFlagRequired("to")
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(explainPathFlag, cfgFiles, requireConfig)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	applyConfigFlags(cfg)
+
+	rootPkg, err := getRootPackage(cfg, explainPathFlag)
+	if err != nil {
+		return fmt.Errorf("failed to get root package: %w", err)
+	}
+
+	analyzer := analysis.NewAnalyzer(cfg, explainPathFlag)
+	analyzer.SetRootPackage(rootPkg)
+
+	paths, truncated, err := analyzer.ImportPaths(explainFromFlag, explainToFlag, explainMaxPathsFlag)
+	if err != nil {
+		return fmt.Errorf("failed to explain dependency: %w", err)
+	}
+
+	fmt.Print(formatImportPaths(paths, truncated))
+	return nil
+}
+
+// formatImportPaths renders one import chain per line
+func formatImportPaths(paths [][]string, truncated bool) string {
+	if len(paths) == 0 {
+		return "No import path found.\n"
+	}
+
+	var b strings.Builder
+	for i, path := range paths {
+		b.WriteString(fmt.Sprintf("%d. %s\n", i+1, strings.Join(path, " → ")))
+	}
+	if truncated {
+		b.WriteString(fmt.Sprintf("… more paths exist, showing the first %d\n", len(paths)))
+	}
+	return b.String()
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cosmos/dependency-guardian/pkg/git"
+)
+
+// GitRunner performs the git operations needed by the commands
+type GitRunner interface {
+	git.Cloner
+	// ChangedFiles lists the files that differ between the base and head refs
+	ChangedFiles(dir, base, head string) ([]string, error)
+	// ChangedFilesSince lists the files touched by commits reachable from ref
+	// that were committed after since
+	ChangedFilesSince(dir, ref string, since time.Time) ([]string, error)
+	// AddWorktree checks out ref of the repository at dir into a detached worktree at path
+	AddWorktree(dir, path, ref string) error
+	// RemoveWorktree removes the worktree at path from the repository at dir
+	RemoveWorktree(dir, path string) error
+	// FetchCommit fetches sha and its history from origin into the repository
+	// at dir, completing the history of a shallow clone
+	FetchCommit(dir, sha string) error
+	// MergeBase returns the best common ancestor of commits a and b
+	MergeBase(dir, a, b string) (string, error)
+	// Blame attributes the given lines of file at rev to their authors
+	Blame(dir, rev, file string, ranges []lineRange) ([]blameLine, error)
+	// FetchTags fetches the tags of origin into the repository at dir
+	FetchTags(dir string) error
+	// LastCommit returns the last commit reachable from rev that changed path
+	LastCommit(dir, rev, path string) (string, error)
+	// Describe returns the nearest tag containing commit, the first release
+	// that shipped it
+	Describe(dir, commit string) (string, error)
+}
+
+// gitRunner is the GitRunner used by the commands. Tests substitute a fake.
+var gitRunner GitRunner = execGitRunner{}
+
+// execGitRunner implements GitRunner by shelling out to the git binary
+type execGitRunner struct{}
+
+// Clone implements GitRunner
+func (execGitRunner) Clone(url, ref, dir string) error {
+	_, err := runGit("", "clone", "--depth", "1", "--branch", ref, url, dir)
+	return err
+}
+
+// Checkout implements GitRunner
+func (execGitRunner) Checkout(dir, sha string) error {
+	_, err := runGit(dir, "checkout", sha)
+	return err
+}
+
+// ChangedFiles implements GitRunner
+func (execGitRunner) ChangedFiles(dir, base, head string) ([]string, error) {
+	out, err := runGit(dir, "diff", "--name-only", base, head)
+	if err != nil {
+		return nil, err
+	}
+	return splitLines(out), nil
+}
+
+// ChangedFilesSince implements GitRunner
+func (execGitRunner) ChangedFilesSince(dir, ref string, since time.Time) ([]string, error) {
+	out, err := runGit(dir, "log", "--since="+since.Format(time.RFC3339), "--name-only", "--pretty=format:", ref)
+	if err != nil {
+		return nil, err
+	}
+
+	// A file touched by several commits in the window is listed once
+	seen := make(map[string]bool)
+	var files []string
+	for _, file := range splitLines(out) {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// AddWorktree implements GitRunner
+func (execGitRunner) AddWorktree(dir, path, ref string) error {
+	_, err := runGit(dir, "worktree", "add", "--detach", path, ref)
+	return err
+}
+
+// RemoveWorktree implements GitRunner
+func (execGitRunner) RemoveWorktree(dir, path string) error {
+	_, err := runGit(dir, "worktree", "remove", "--force", path)
+	return err
+}
+
+// FetchCommit implements GitRunner
+func (execGitRunner) FetchCommit(dir, sha string) error {
+	shallow, err := runGit(dir, "rev-parse", "--is-shallow-repository")
+	if err != nil {
+		return err
+	}
+	args := []string{"fetch", "-q"}
+	if strings.TrimSpace(shallow) == "true" {
+		args = append(args, "--unshallow")
+	}
+	_, err = runGit(dir, append(args, "origin", sha)...)
+	return err
+}
+
+// MergeBase implements GitRunner
+func (execGitRunner) MergeBase(dir, a, b string) (string, error) {
+	out, err := runGit(dir, "merge-base", a, b)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Blame implements GitRunner
+func (execGitRunner) Blame(dir, rev, file string, ranges []lineRange) ([]blameLine, error) {
+	args := []string{"blame", "--porcelain"}
+	for _, r := range ranges {
+		args = append(args, "-L", fmt.Sprintf("%d,%d", r.Start, r.End))
+	}
+	out, err := runGit(dir, append(args, rev, "--", file)...)
+	if err != nil {
+		return nil, err
+	}
+	return parseBlamePorcelain(out), nil
+}
+
+// FetchTags implements GitRunner
+func (execGitRunner) FetchTags(dir string) error {
+	_, err := runGit(dir, "fetch", "-q", "--tags", "origin")
+	return err
+}
+
+// LastCommit implements GitRunner
+func (execGitRunner) LastCommit(dir, rev, path string) (string, error) {
+	out, err := runGit(dir, "log", "-1", "--format=%H", rev, "--", path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Describe implements GitRunner
+func (execGitRunner) Describe(dir, commit string) (string, error) {
+	out, err := runGit(dir, "describe", "--tags", "--contains", commit)
+	if err != nil {
+		return "", err
+	}
+	// Drop the path from the tag to the commit, as in v1.2.0~3^2
+	tag, _, _ := strings.Cut(strings.TrimSpace(out), "~")
+	tag, _, _ = strings.Cut(tag, "^")
+	return tag, nil
+}
+
+// mergeBaseChangedFiles lists the files changed by the commits of head since
+// its merge-base with base, leaving out changes made to base in the meantime.
+// The history both need is fetched into the clone at dir.
+func mergeBaseChangedFiles(runner GitRunner, dir, base, head string) ([]string, error) {
+	if err := runner.FetchCommit(dir, base); err != nil {
+		return nil, fmt.Errorf("failed to fetch base revision: %w", err)
+	}
+	mergeBase, err := runner.MergeBase(dir, base, head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute merge-base: %w", err)
+	}
+	files, err := runner.ChangedFiles(dir, mergeBase, head)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files changed since merge-base %s: %w", mergeBase, err)
+	}
+	return files, nil
+}
+
+// splitLines returns the non-empty lines of git output
+func splitLines(out string) []string {
+	var lines []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// runGit executes a git subcommand, in dir if it is not empty, and returns its
+// combined output. Failures are reported as a *git.Error carrying the output.
+func runGit(dir string, args ...string) (string, error) {
+	op := args[0]
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		return "", &git.Error{Op: op, Err: err, Output: string(out)}
+	}
+	return string(out), nil
+}
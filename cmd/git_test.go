@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGitRunner is a GitRunner whose operations are provided by the test
+type fakeGitRunner struct {
+	clone    func(url, ref, dir string) error
+	checkout func(dir, sha string) error
+
+	clones    int
+	checkouts []string
+}
+
+func (f *fakeGitRunner) Clone(url, ref, dir string) error {
+	f.clones++
+	if f.clone == nil {
+		return nil
+	}
+	return f.clone(url, ref, dir)
+}
+
+func (f *fakeGitRunner) Checkout(dir, sha string) error {
+	f.checkouts = append(f.checkouts, sha)
+	if f.checkout == nil {
+		return nil
+	}
+	return f.checkout(dir, sha)
+}
+
+func (f *fakeGitRunner) ChangedFiles(dir, base, head string) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeGitRunner) ChangedFilesSince(dir, ref string, since time.Time) ([]string, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeGitRunner) AddWorktree(dir, path, ref string) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeGitRunner) RemoveWorktree(dir, path string) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeGitRunner) FetchCommit(dir, sha string) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeGitRunner) MergeBase(dir, a, b string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (f *fakeGitRunner) Blame(dir, rev, file string, ranges []lineRange) ([]blameLine, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeGitRunner) FetchTags(dir string) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeGitRunner) LastCommit(dir, rev, path string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+func (f *fakeGitRunner) Describe(dir, commit string) (string, error) {
+	return "", errors.New("not implemented")
+}
+
+// testGitRepo is a real git repository in a temporary directory
+type testGitRepo struct {
+	t   *testing.T
+	dir string
+}
+
+// newTestGitRepo initializes an empty git repository with a fixed identity
+func newTestGitRepo(t *testing.T) *testGitRepo {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not available")
+	}
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	repo := &testGitRepo{t: t, dir: t.TempDir()}
+	repo.git("init", "-q", "-b", "main")
+	return repo
+}
+
+// git runs a git command in the repository and returns its trimmed output
+func (r *testGitRepo) git(args ...string) string {
+	r.t.Helper()
+	return r.gitEnv(nil, args...)
+}
+
+// gitEnv is like git but adds env to the environment of the command
+func (r *testGitRepo) gitEnv(env []string, args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", r.dir, "-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Env = append(os.Environ(), env...)
+	out, err := cmd.CombinedOutput()
+	require.NoError(r.t, err, string(out))
+	return strings.TrimSpace(string(out))
+}
+
+// commit writes the files and commits them with the given message
+func (r *testGitRepo) commit(message string, files map[string]string) {
+	r.t.Helper()
+	r.commitAt(message, time.Now(), files)
+}
+
+// commitAt is like commit but authors and commits at the given time
+func (r *testGitRepo) commitAt(message string, when time.Time, files map[string]string) {
+	r.t.Helper()
+	for name, content := range files {
+		path := filepath.Join(r.dir, filepath.FromSlash(name))
+		require.NoError(r.t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(r.t, os.WriteFile(path, []byte(content), 0644))
+	}
+	r.git("add", "-A")
+	date := when.Format(time.RFC3339)
+	r.gitEnv([]string{"GIT_AUTHOR_DATE=" + date, "GIT_COMMITTER_DATE=" + date}, "commit", "-q", "-m", message)
+}
+
+func TestMergeBaseChangedFiles(t *testing.T) {
+	repo := newTestGitRepo(t)
+	repo.commit("initial", map[string]string{"go.mod": "module example.com/m\n", "a/a.go": "package a\n"})
+	repo.git("checkout", "-q", "-b", "feature")
+	repo.commit("feature", map[string]string{"f/f.go": "package f\n"})
+	head := repo.git("rev-parse", "HEAD")
+
+	// The base branch moves on after the PR branched off
+	repo.git("checkout", "-q", "main")
+	repo.commit("upstream", map[string]string{"u/u.go": "package u\n"})
+	base := repo.git("rev-parse", "HEAD")
+	require.Equal(t, "f/f.go\nu/u.go", repo.git("diff", "--name-only", base, head))
+
+	// Analyze clones the head shallowly
+	clone := filepath.Join(t.TempDir(), "clone")
+	repo.git("clone", "-q", "--depth", "1", "--branch", "feature", "file://"+repo.dir, clone)
+
+	files, err := mergeBaseChangedFiles(execGitRunner{}, clone, base, head)
+	require.NoError(t, err)
+	require.Equal(t, []string{"f/f.go"}, files)
+}
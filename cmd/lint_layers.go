@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintLayersPathFlag     string
+	lintLayersBasePathFlag string
+)
+
+var lintLayersCmd = &cobra.Command{
+	Use:   "lint-layers",
+	Short: "Check the imports of a local repository against the layering rules",
+	Long: `Check every import of a local repository against layering.rules, listing
+the imports between layers that the rules forbid, and fail when any is found.
+With --base-path, imports already present in that checkout of the base revision
+are reported as pre-existing and only the new ones fail the command.`,
+	RunE: runLintLayers,
+}
+
+func init() {
+	rootCmd.AddCommand(lintLayersCmd)
+
+	lintLayersCmd.Flags().StringVar(&lintLayersPathFlag, "path", ".", "Path to the repository")
+	lintLayersCmd.Flags().StringVar(&lintLayersBasePathFlag, "base-path", "", "Path to a checkout of the base revision, to tell new violations from pre-existing ones")
+}
+
+func runLintLayers(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(lintLayersPathFlag, cfgFiles, requireConfig)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	applyConfigFlags(cfg)
+	if len(cfg.Layering.Rules) == 0 {
+		return fmt.Errorf("no layering rules configured")
+	}
+
+	rootPkg, err := getRootPackage(cfg, lintLayersPathFlag)
+	if err != nil {
+		return fmt.Errorf("failed to get root package: %w", err)
+	}
+
+	analyzer := analysis.NewAnalyzer(cfg, lintLayersPathFlag)
+	if lintLayersBasePathFlag != "" {
+		analyzer.SetBaseRepo(lintLayersBasePathFlag)
+	}
+	analyzer.SetRootPackage(rootPkg)
+
+	violations, err := analyzer.LayerViolations()
+	if err != nil {
+		return fmt.Errorf("failed to check layering rules: %w", err)
+	}
+
+	fmt.Print(formatLayerViolations(violations, lintLayersBasePathFlag != ""))
+
+	failing := len(violations)
+	if lintLayersBasePathFlag != "" {
+		failing = 0
+		for _, v := range violations {
+			if v.New {
+				failing++
+			}
+		}
+	}
+	if failing > 0 {
+		return fmt.Errorf("%d layering violations found", failing)
+	}
+	return nil
+}
+
+// formatLayerViolations renders layering violations, separating the new ones
+// from the pre-existing ones when the base revision is known
+func formatLayerViolations(violations []*analysis.LayerViolation, baseKnown bool) string {
+	if len(violations) == 0 {
+		return "No layering violations found.\n"
+	}
+
+	var b strings.Builder
+	writeGroup := func(title string, group []*analysis.LayerViolation) {
+		if len(group) == 0 {
+			return
+		}
+		b.WriteString(fmt.Sprintf("%s (%d):\n", title, len(group)))
+		for _, v := range group {
+			b.WriteString(fmt.Sprintf("  %s (%s) imports %s (%s)\n", v.Importer, v.ImporterLayer, v.Imported, v.ImportedLayer))
+		}
+	}
+
+	if !baseKnown {
+		writeGroup("Layering violations", violations)
+		return b.String()
+	}
+	var introduced, existing []*analysis.LayerViolation
+	for _, v := range violations {
+		if v.New {
+			introduced = append(introduced, v)
+		} else {
+			existing = append(existing, v)
+		}
+	}
+	writeGroup("New layering violations", introduced)
+	writeGroup("Pre-existing layering violations", existing)
+	return b.String()
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	listPackagesPathFlag   string
+	listPackagesFormatFlag string
+)
+
+var listPackagesCmd = &cobra.Command{
+	Use:   "list-packages",
+	Short: "List every internal package of a local repository with its classification",
+	Long: `Resolve the dependency graph of a local repository and print an inventory of
+its internal packages: whether each matches the high-level and critical patterns
+and has tests, how many internal packages it imports directly and how many
+import it directly. Ignored packages are left out.`,
+	RunE: runListPackages,
+}
+
+func init() {
+	rootCmd.AddCommand(listPackagesCmd)
+
+	listPackagesCmd.Flags().StringVar(&listPackagesPathFlag, "path", ".", "Path to the repository")
+	listPackagesCmd.Flags().StringVar(&listPackagesFormatFlag, "format", formatText, "Output format ("+formatText+", "+formatJSON+")")
+}
+
+func runListPackages(cmd *cobra.Command, args []string) error {
+	if listPackagesFormatFlag != formatText && listPackagesFormatFlag != formatJSON {
+		return fmt.Errorf("unsupported format %q, expected one of %s, %s", listPackagesFormatFlag, formatText, formatJSON)
+	}
+
+	cfg, err := config.LoadConfig(listPackagesPathFlag, cfgFiles, requireConfig)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	applyConfigFlags(cfg)
+
+	rootPkg, err := getRootPackage(cfg, listPackagesPathFlag)
+	if err != nil {
+		return fmt.Errorf("failed to get root package: %w", err)
+	}
+
+	analyzer := analysis.NewAnalyzer(cfg, listPackagesPathFlag)
+	analyzer.SetRootPackage(rootPkg)
+
+	packages, err := analyzer.Packages()
+	if err != nil {
+		return fmt.Errorf("failed to list packages: %w", err)
+	}
+
+	if listPackagesFormatFlag == formatJSON {
+		if packages == nil {
+			packages = []*analysis.PackageInfo{}
+		}
+		data, err := json.MarshalIndent(packages, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode packages: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+	fmt.Print(formatPackages(packages))
+	return nil
+}
+
+// formatPackages renders one package per line with its flags and direct
+// import and dependent counts
+func formatPackages(packages []*analysis.PackageInfo) string {
+	if len(packages) == 0 {
+		return "No internal packages found.\n"
+	}
+	var b strings.Builder
+	for _, pkg := range packages {
+		var flags []string
+		if pkg.HighLevel {
+			flags = append(flags, "high-level")
+		}
+		if pkg.Critical {
+			flags = append(flags, "critical")
+		}
+		if pkg.HasTests {
+			flags = append(flags, "tests")
+		}
+		fmt.Fprintf(&b, "%s\timports=%d\tdependents=%d", pkg.Name, pkg.Imports, pkg.Dependents)
+		if len(flags) > 0 {
+			fmt.Fprintf(&b, "\t[%s]", strings.Join(flags, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
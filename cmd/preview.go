@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	previewPathFlag    string
+	previewChangedFlag string
+	previewRepoFlag    string
+)
+
+var previewCmd = &cobra.Command{
+	Use:   "preview",
+	Short: "Print the PR comment for local changes without calling GitHub",
+	Long: `Analyze a local repository for a list of changed files and print the exact
+comment body analyze would post, with its markers and footer, so that it can be
+checked in a Markdown previewer. A report too long for a single comment is
+printed as the successive comments it would be split into. No GitHub API call
+is made, so the command works offline.`,
+	RunE: runPreview,
+}
+
+func init() {
+	rootCmd.AddCommand(previewCmd)
+
+	previewCmd.Flags().StringVar(&previewPathFlag, "path", ".", "Path to the repository")
+	previewCmd.Flags().StringVar(&previewChangedFlag, "changed-files-from", "", "File listing the changed files relative to the repository root, one per line (- for stdin)")
+	previewCmd.Flags().StringVar(&previewRepoFlag, "repo", "", "Repository as owner/repo, for the comment footer")
+	previewCmd.Flags().IntVar(&prNumberFlag, "pr", 0, "Pull request number, for the comment footer")
+	previewCmd.Flags().StringVar(&commentFooterFlag, "comment-footer", "", "Text appended to the comment, overriding output.footer; may use {{.Repo}}, {{.PR}} and {{.Version}}")
+	previewCmd.Flags().BoolVar(&noEmojiFlag, "no-emoji", false, "Render text markers such as [CRITICAL] instead of emoji, overriding output.emoji")
+	previewCmd.Flags().StringVar(&outputFileFlag, "output-file", "", "Also write the comment to this file")
+	previewCmd.Flags().BoolVar(&quietFlag, "quiet", false, "Do not print the comment to stdout")
+	previewCmd.MarkFlagRequired("changed-files-from")
+}
+
+func runPreview(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(previewPathFlag, cfgFiles, requireConfig)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if err := validateFooter(cfg); err != nil {
+		return err
+	}
+	applyConfigFlags(cfg)
+
+	changedFiles, err := readChangedFiles(previewChangedFlag, cmd.InOrStdin())
+	if err != nil {
+		return err
+	}
+
+	rootPkg, err := getRootPackage(cfg, previewPathFlag)
+	if err != nil {
+		return fmt.Errorf("failed to get root package: %w", err)
+	}
+
+	analyzer := analysis.NewAnalyzer(cfg, previewPathFlag)
+	analyzer.SetRootPackage(rootPkg)
+	result, err := analyzer.AnalyzeChangedPackages(changedFiles)
+	if err != nil {
+		return fmt.Errorf("failed to analyze changes: %w", err)
+	}
+	if noEmojiFlag {
+		result.NoEmoji = true
+	}
+
+	body, err := commentBody(result.String(), result, cfg, footerData{Repo: previewRepoFlag, PR: prNumberFlag, Version: version})
+	if err != nil {
+		return err
+	}
+	comments := strings.Join(splitReport(body, maxCommentSize), "\n")
+	return emitOutput(strings.TrimSuffix(comments, "\n"))
+}
+
+// readChangedFiles reads the non-empty lines of the file at path, or of stdin
+// when path is "-"
+func readChangedFiles(path string, stdin io.Reader) ([]string, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read changed files: %w", err)
+	}
+	return splitLines(string(data)), nil
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPreview_MatchesPostedComment(t *testing.T) {
+	provider := draftPR()
+	setupAnalyze(t, provider, cloneOf(testRepo))
+	commentFooterFlag = "Posted for {{.Repo}}#{{.PR}}"
+	t.Cleanup(func() { commentFooterFlag = "" })
+	require.NoError(t, runAnalyze(analyzeCmd, nil))
+
+	repoPath := t.TempDir()
+	require.NoError(t, cloneOf(testRepo).clone("", "", repoPath))
+	changedFile := filepath.Join(t.TempDir(), "changed.txt")
+	require.NoError(t, os.WriteFile(changedFile, []byte("d/d.go\n"), 0644))
+	previewPathFlag, previewChangedFlag, previewRepoFlag = repoPath, changedFile, "o/r"
+	outputFileFlag, quietFlag = filepath.Join(t.TempDir(), "comment.md"), true
+	t.Cleanup(func() {
+		previewPathFlag, previewChangedFlag, previewRepoFlag = ".", "", ""
+		outputFileFlag, quietFlag = "", false
+	})
+	require.NoError(t, runPreview(previewCmd, nil))
+
+	preview, err := os.ReadFile(outputFileFlag)
+	require.NoError(t, err)
+
+	// Only the line naming the analyzed revisions of the PR differs
+	revisions := regexp.MustCompile("Analyzed base `base` against head `head` at [^\n]*\n\n")
+	require.Equal(t, revisions.ReplaceAllString(provider.comments[0].GetBody(), ""), string(preview))
+	require.Contains(t, string(preview), "<!-- dependency-guardian:state ")
+	require.Contains(t, string(preview), "\n---\nPosted for o/r#1\n")
+}
+
+func TestRunPreview_SplitsLongReports(t *testing.T) {
+	repoPath := t.TempDir()
+	require.NoError(t, cloneOf(testRepo).clone("", "", repoPath))
+	changedFile := filepath.Join(t.TempDir(), "changed.txt")
+	require.NoError(t, os.WriteFile(changedFile, []byte("d/d.go\n"), 0644))
+	previewPathFlag, previewChangedFlag = repoPath, changedFile
+	outputFileFlag, quietFlag = filepath.Join(t.TempDir(), "comment.md"), true
+	origSize := maxCommentSize
+	maxCommentSize = 200
+	t.Cleanup(func() {
+		previewPathFlag, previewChangedFlag = ".", ""
+		outputFileFlag, quietFlag = "", false
+		maxCommentSize = origSize
+	})
+	require.NoError(t, runPreview(previewCmd, nil))
+
+	preview, err := os.ReadFile(outputFileFlag)
+	require.NoError(t, err)
+	require.Contains(t, string(preview), partMarker(1))
+	require.Contains(t, string(preview), partMarker(2))
+}
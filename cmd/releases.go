@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"go.uber.org/zap"
+)
+
+// defaultMaxReleaseCalls bounds the git calls made for --annotate-releases
+const defaultMaxReleaseCalls = 200
+
+// lastReleases looks up, for each package directory relative to the
+// repository at dir, the last commit reachable from rev that changed it and
+// the nearest tag containing that commit: the release the package last
+// changed in. Packages changed since the latest tag are left out. Packages
+// are looked up in order with at most maxCalls git calls in total (0 means no
+// limit), besides fetching the history and tags.
+func lastReleases(runner GitRunner, dir, rev string, pkgDirs map[string]string, maxCalls int) (map[string]string, error) {
+	if err := runner.FetchCommit(dir, rev); err != nil {
+		return nil, fmt.Errorf("failed to fetch history for release lookup: %w", err)
+	}
+	if err := runner.FetchTags(dir); err != nil {
+		return nil, fmt.Errorf("failed to fetch tags for release lookup: %w", err)
+	}
+
+	pkgs := make([]string, 0, len(pkgDirs))
+	for pkg := range pkgDirs {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	releases := make(map[string]string)
+	tags := make(map[string]string) // Nearest tag of each commit already described
+	calls := 0
+	budgetLeft := func() bool {
+		if maxCalls > 0 && calls >= maxCalls {
+			zap.S().Warnw("release lookup git call limit reached, remaining packages are not annotated", "max_release_git_calls", maxCalls)
+			return false
+		}
+		calls++
+		return true
+	}
+	for _, pkg := range pkgs {
+		if !budgetLeft() {
+			break
+		}
+		commit, err := runner.LastCommit(dir, rev, pkgDirs[pkg])
+		if err != nil {
+			zap.S().Warnw("failed to find the last commit of package, skipping it", "package", pkg, "error", err)
+			continue
+		}
+		if commit == "" {
+			continue
+		}
+
+		tag, ok := tags[commit]
+		if !ok {
+			if !budgetLeft() {
+				break
+			}
+			// Commits no tag contains are unreleased
+			if tag, err = runner.Describe(dir, commit); err != nil {
+				zap.S().Debugw("no tag contains the last commit of package", "package", pkg, "commit", commit, "error", err)
+			}
+			tags[commit] = tag
+		}
+		if tag != "" {
+			releases[pkg] = tag
+		}
+	}
+	return releases, nil
+}
+
+// setLastReleases sets the last release of the affected packages of result
+func setLastReleases(result *analysis.AnalysisResult, releases map[string]string) {
+	for _, impact := range result.Impacts {
+		for _, pkg := range impact.AffectedPackages {
+			pkg.LastRelease = releases[pkg.Name]
+		}
+	}
+}
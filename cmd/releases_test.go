@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLastReleases(t *testing.T) {
+	repo := newTestGitRepo(t)
+	repo.commit("initial", map[string]string{
+		"go.mod": "module example.com/repo\n",
+		"a/a.go": "package a\n",
+		"b/b.go": "package b\n",
+		"c/c.go": "package c\n",
+	})
+	repo.git("tag", "v1.0.0")
+	repo.commit("change b", map[string]string{"b/b.go": "package b\n\nconst B = 1\n"})
+	repo.commit("docs", map[string]string{"README.md": "repo\n"})
+	repo.git("tag", "v1.1.0")
+	repo.commit("change c", map[string]string{"c/c.go": "package c\n\nconst C = 1\n"})
+	head := repo.git("rev-parse", "HEAD")
+
+	// The analysis runs in a shallow clone of the head, as analyze does
+	clone := filepath.Join(t.TempDir(), "clone")
+	repo.git("clone", "-q", "--depth", "1", "file://"+repo.dir, clone)
+
+	pkgDirs := map[string]string{
+		"example.com/repo/a": "a",
+		"example.com/repo/b": "b",
+		"example.com/repo/c": "c",
+	}
+	releases, err := lastReleases(execGitRunner{}, clone, head, pkgDirs, 0)
+	require.NoError(t, err)
+	// c changed after the latest release
+	require.Equal(t, map[string]string{
+		"example.com/repo/a": "v1.0.0",
+		"example.com/repo/b": "v1.1.0",
+	}, releases)
+
+	// Two git calls only look up a
+	releases, err = lastReleases(execGitRunner{}, clone, head, pkgDirs, 2)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"example.com/repo/a": "v1.0.0"}, releases)
+
+	result := &analysis.AnalysisResult{Impacts: []*analysis.PackageImpact{{
+		ChangedPackage:   "example.com/repo/c",
+		AffectedPackages: []*analysis.AffectedPackage{{Name: "example.com/repo/a", Depth: 1}},
+	}}}
+	setLastReleases(result, releases)
+	require.Contains(t, result.String(), "- `example.com/repo/a` — directly affected — last changed in v1.0.0\n")
+	require.Contains(t, result.Text(), "example.com/repo/a — directly affected — last changed in v1.0.0\n")
+}
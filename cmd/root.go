@@ -3,15 +3,24 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/cosmos/dependency-guardian/pkg/config"
+
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+// version is the release of the tool, set at build time with
+// -ldflags "-X github.com/cosmos/dependency-guardian/cmd.version=v1.2.3"
+var version = "dev"
+
 var (
-	cfgFile   string
-	logLevel  string
-	logFormat string
+	cfgFiles      []string
+	requireConfig bool
+	logLevel      string
+	logFormat     string
+	buildTagsFlag []string
+	moduleDirFlag string
 )
 
 var rootCmd = &cobra.Command{
@@ -54,7 +63,20 @@ func Execute() error {
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is .dependency-guardian.yml)")
+	rootCmd.PersistentFlags().StringArrayVar(&cfgFiles, "config", nil, "config file (default is .dependency-guardian.yml); repeat to merge several, later files taking precedence")
+	rootCmd.PersistentFlags().BoolVar(&requireConfig, "require-config", false, "Fail if no config file is found instead of using the default configuration")
+	rootCmd.PersistentFlags().StringSliceVar(&buildTagsFlag, "build-tags", nil, "Comma-separated build tags the module is built with, overriding analysis.build_tags")
+	rootCmd.PersistentFlags().StringVar(&moduleDirFlag, "module-dir", "", "Directory of the module relative to the repository root, overriding analysis.module_dir; the nearest go.mod is used when it has none")
 	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "Log level (debug, info, warn, error)")
 	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format (text, json)")
-} 
\ No newline at end of file
+}
+
+// applyConfigFlags applies the global flags overriding configuration settings
+func applyConfigFlags(cfg *config.Config) {
+	if len(buildTagsFlag) > 0 {
+		cfg.Analysis.BuildTags = buildTagsFlag
+	}
+	if moduleDirFlag != "" {
+		cfg.Analysis.ModuleDir = moduleDirFlag
+	}
+}
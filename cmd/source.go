@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/cosmos/dependency-guardian/pkg/git"
+	"github.com/cosmos/dependency-guardian/pkg/modproxy"
+)
+
+// Source kinds selectable with --source
+const (
+	sourceGit   = "git"
+	sourceProxy = "proxy"
+)
+
+// Source materializes a revision of the analyzed repository in a local directory
+type Source interface {
+	// Fetch writes the tree of commit sha, the head of branch ref, into dir
+	Fetch(ref, sha, dir string) error
+}
+
+// gitSource clones the repository with git
+type gitSource struct {
+	runner  GitRunner
+	repoURL string
+}
+
+// Fetch implements Source
+func (s gitSource) Fetch(ref, sha, dir string) error {
+	return git.Clone(s.runner, s.repoURL, ref, sha, dir)
+}
+
+// proxySource downloads the module zip of the revision from a Go module proxy,
+// so no git binary is needed. It only works for modules the proxy can resolve,
+// typically public repositories whose module path matches the repository.
+type proxySource struct {
+	client     *modproxy.Client
+	modulePath string
+}
+
+// Fetch implements Source
+func (s proxySource) Fetch(ref, sha, dir string) error {
+	if _, err := s.client.Download(s.modulePath, sha, dir); err != nil {
+		return fmt.Errorf("failed to fetch %s from module proxy: %w", sha, err)
+	}
+	return nil
+}
+
+// newSource creates the Source of the given kind for owner/repo. The module
+// path used with the proxy defaults to the GitHub import path of the repository.
+func newSource(kind, token, owner, repo, modulePath string) (Source, error) {
+	switch kind {
+	case sourceGit, "":
+		repoURL := fmt.Sprintf("https://x-access-token:%s@github.com/%s/%s.git", token, owner, repo)
+		return gitSource{runner: gitRunner, repoURL: repoURL}, nil
+	case sourceProxy:
+		if modulePath == "" {
+			modulePath = fmt.Sprintf("github.com/%s/%s", owner, repo)
+		}
+		return proxySource{client: modproxy.NewClient(os.Getenv("GOPROXY")), modulePath: modulePath}, nil
+	default:
+		return nil, fmt.Errorf("unknown source %q, expected %q or %q", kind, sourceGit, sourceProxy)
+	}
+}
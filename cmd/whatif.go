@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+var (
+	whatifPathFlag   string
+	whatifAddFlag    []string
+	whatifRemoveFlag []string
+)
+
+var whatifCmd = &cobra.Command{
+	Use:   "whatif",
+	Short: "Simulate adding or removing imports and show the impact",
+	Long: `Simulate adding or removing import edges in a local repository and show how
+the reverse dependencies of its packages would change, without modifying any
+file. Edges are written as from->to, meaning package from imports package to;
+packages may be given relative to the module path.
+
+Example:
+  dependency-guardian whatif --remove 'x/a->x/b' --add 'x/a->x/c'`,
+	RunE: runWhatIf,
+}
+
+func init() {
+	rootCmd.AddCommand(whatifCmd)
+
+	whatifCmd.Flags().StringVar(&whatifPathFlag, "path", ".", "Path to the repository")
+	whatifCmd.Flags().StringArrayVar(&whatifAddFlag, "add", nil, "Import edge to add, as from->to (repeatable)")
+	whatifCmd.Flags().StringArrayVar(&whatifRemoveFlag, "remove", nil, "Import edge to remove, as from->to (repeatable)")
+	whatifCmd.MarkFlagsOneRequired("add", "remove")
+}
+
+func runWhatIf(cmd *cobra.Command, args []string) error {
+	add, err := parseEdges(whatifAddFlag)
+	if err != nil {
+		return err
+	}
+	remove, err := parseEdges(whatifRemoveFlag)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.LoadConfig(whatifPathFlag, cfgFiles, requireConfig)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	applyConfigFlags(cfg)
+
+	rootPkg, err := getRootPackage(cfg, whatifPathFlag)
+	if err != nil {
+		return fmt.Errorf("failed to get root package: %w", err)
+	}
+
+	analyzer := analysis.NewAnalyzer(cfg, whatifPathFlag)
+	analyzer.SetRootPackage(rootPkg)
+
+	changes, err := analyzer.WhatIf(add, remove)
+	if err != nil {
+		return fmt.Errorf("failed to simulate import changes: %w", err)
+	}
+
+	fmt.Print(formatReverseDependencyChanges(changes))
+	return nil
+}
+
+// parseEdges parses the from->to edges given on the command line
+func parseEdges(values []string) ([]analysis.Edge, error) {
+	edges := make([]analysis.Edge, 0, len(values))
+	for _, value := range values {
+		edge, err := analysis.ParseEdge(value)
+		if err != nil {
+			return nil, err
+		}
+		edges = append(edges, edge)
+	}
+	return edges, nil
+}
+
+// formatReverseDependencyChanges renders the before/after reverse dependency
+// diff of each changed package
+func formatReverseDependencyChanges(changes []*analysis.ReverseDependencyChange) string {
+	if len(changes) == 0 {
+		return "No reverse dependencies change.\n"
+	}
+
+	var b strings.Builder
+	for _, c := range changes {
+		b.WriteString(c.Package + "\n")
+		for _, dep := range c.Added {
+			b.WriteString("  + " + dep + "\n")
+		}
+		for _, dep := range c.Removed {
+			b.WriteString("  - " + dep + "\n")
+		}
+	}
+	return b.String()
+}
@@ -1,25 +1,63 @@
 package analysis
 
 import (
+	"context"
 	"fmt"
+	"go/types"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 
 	"github.com/cosmos/dependency-guardian/pkg/config"
+	"go.uber.org/zap"
 )
 
 // AffectedPackage represents a package that is impacted by a change.
 type AffectedPackage struct {
 	Name       string
 	IsCritical bool
+	Depth      int      // Minimum reverse-dependency hops from the changed package (1 = direct importer)
+	Labels     []string // Sorted labels attached by the analyzer's classifiers
+	DocsURL    string   // Documentation or runbook link of a critical package
+	Badge      string   // Badge configured for a critical package, replacing the default one
+
+	// Nearest tag containing the last commit changing the package, set when
+	// releases are annotated from the git history
+	LastRelease string
 }
 
 // PackageImpact details the packages affected by a change in a single package.
 type PackageImpact struct {
 	ChangedPackage   string
 	AffectedPackages []*AffectedPackage
+	Kind             ChangeKind // Set in import-diff mode
+	AddedImports     []string   // Internal imports added relative to the base
+	RemovedImports   []string   // Internal imports removed relative to the base
+	RenamedFrom      string     // Previous import path when the package directory was moved
+
+	// Types of other packages that likely satisfied an exported interface the
+	// change altered, set when interface change detection is enabled
+	InterfaceImplementers []*InterfaceImplementer
+
+	// Distinct authors of the lines the change modifies, most recent first,
+	// set when owners are annotated from git blame
+	RecentAuthors []string
+}
+
+// PolicyViolation is a changed package outside the allowed critical sources
+// that affects a critical package
+type PolicyViolation struct {
+	ChangedPackage  string
+	CriticalPackage string
+}
+
+// DeprecatedImport is an import of a deprecated package by a changed package
+type DeprecatedImport struct {
+	Importer string
+	Imported string
 }
 
 // AnalysisResult contains the results of dependency analysis
@@ -27,127 +65,259 @@ type AnalysisResult struct {
 	Impacts              []*PackageImpact
 	DirectDependencies   []string
 	IndirectDependencies []string
+	Warnings             []string
+	CriticalCount        int      // Distinct critical packages affected
+	Severity             Severity // Escalation level reached by CriticalCount
+	WarnThreshold        int      // CriticalCount at which the analysis warns
+	BlockThreshold       int      // CriticalCount at which the analysis blocks
+	GoVersionIssues      []string // Go version policy and language compatibility problems
+	MaxAffectedShown     int      // Affected packages listed per changed package (0 lists all)
+	PolicyViolations     []*PolicyViolation
+	LayerViolations      []*LayerViolation   // Imports the layering rules forbid, new or pre-existing
+	DeprecatedImports    []*DeprecatedImport // Imports of deprecated packages by the changed packages
+	ModulePath           string              // Module path of the analyzed repository
+	RelativePaths        bool                // Render packages relative to ModulePath
+	TotalChanged         int                 // Changed packages before filtering, when Impacts is filtered
+	TotalAffected        int                 // Distinct affected packages before filtering, when Impacts is filtered
+	NewDependencies      []*ModRequire       // Modules required at head but not at the base revision
+	Delta                *ImpactDelta        // Changes since the previous analysis, when known
+	ChangedAreas         []*AreaCount        // Changed packages per top-level area of the module
+	NoEmoji              bool                // Render text markers instead of emoji
+	Incomplete           *Incompleteness     // Set when the resolution stopped before covering the repository
+	LoadErrors           int                 // Packages some files of which failed to parse
+	ConfigChanges        []*config.Change    // Pattern changes made by the change to the configuration
+}
+
+// Incompleteness describes how far the resolution of the repository got
+// before the analysis deadline
+type Incompleteness struct {
+	Resolved int // Package directories resolved
+	Total    int // Package directories in the repository
+}
+
+// ResultContext describes the revisions an analysis was run against
+type ResultContext struct {
+	BaseSHA    string
+	HeadSHA    string
+	AnalyzedAt time.Time
 }
 
 // Analyzer handles dependency analysis for a repository
 type Analyzer struct {
-	cfg        *config.Config
-	tree       *Tree
-	repoPath   string
-	rootPkgPath string
+	cfg          *config.Config
+	tree         *Tree
+	baseTree     *Tree
+	baseTypes    map[string]*types.Package // Internal packages of the base revision, type-checked on first use
+	repoPath     string
+	baseRepoPath string
+	rootPkgPath  string
+	classifiers  []Classifier
+	renamedFiles map[string]string
+	baseModFile  *ModFile
+	ignoreFile   *config.IgnoreFile
+	ctx          context.Context
+	incomplete   *Incompleteness // Set when the last resolution was cut short
+	checkpoint   string          // File checkpointing the resolution, if any
+	workspace    []*WorkspaceModule
+
+	strictImports bool                 // Fail on imports of module packages without a directory
+	onImpact      func(*PackageImpact) // Observer of each impact once computed, if any
 }
 
-// NewAnalyzer creates a new analyzer instance
+// NewAnalyzer creates a new analyzer instance. The built-in critical and
+// high-level classifiers are always applied, following the configuration of
+// each changed package.
 func NewAnalyzer(cfg *config.Config, repoPath string) *Analyzer {
+	ignoreFile, err := config.LoadIgnoreFile(repoPath)
+	if err != nil {
+		zap.S().Warnw("failed to load ignore file, ignoring it", "error", err)
+	}
+
 	return &Analyzer{
-		cfg:      cfg,
-		repoPath: repoPath,
+		cfg:        cfg,
+		repoPath:   repoPath,
+		ignoreFile: ignoreFile,
+		ctx:        context.Background(),
+	}
+}
+
+// SetContext bounds the resolution of the repository: once ctx is done, no
+// further package is resolved and the result is marked incomplete
+func (a *Analyzer) SetContext(ctx context.Context) {
+	a.ctx = ctx
+}
+
+// SetCheckpoint makes the resolution of the repository periodically save the
+// packages resolved so far to path, and resume from it when a previous run was
+// interrupted. The checkpoint is removed once resolution completes.
+func (a *Analyzer) SetCheckpoint(path string) {
+	a.checkpoint = path
+}
+
+// SetImpactObserver registers fn to receive each impact as soon as it is
+// computed by AnalyzeChangedPackages, in the order of the sorted changed
+// packages, before the aggregate result is returned. fn runs on the analyzing
+// goroutine and must not modify the impact.
+func (a *Analyzer) SetImpactObserver(fn func(*PackageImpact)) {
+	a.onImpact = fn
+}
+
+// AddClassifier registers a classifier whose labels are attached to every
+// affected package
+func (a *Analyzer) AddClassifier(c Classifier) {
+	a.classifiers = append(a.classifiers, c)
+}
+
+// classifiersFor returns the built-in classifiers for cfg followed by the
+// registered ones
+func (a *Analyzer) classifiersFor(cfg *config.Config) []Classifier {
+	return append([]Classifier{
+		criticalClassifier{cfg: cfg},
+		highLevelClassifier{cfg: cfg},
+	}, a.classifiers...)
+}
+
+// configFor returns the configuration applying to a changed package, taking
+// the overrides of its directory into account
+func (a *Analyzer) configFor(pkgName string) *config.Config {
+	if len(a.cfg.Overrides) == 0 {
+		return a.cfg
+	}
+	dir, ok := DirFor(a.rootPkgPath, pkgName)
+	if !ok || dir == "" {
+		dir = "."
 	}
+	// Override prefixes are relative to the repository root
+	return a.cfg.ForPackageDir(path.Join(a.cfg.Analysis.ModuleDir, dir))
 }
 
 // SetRootPackage sets the root package path for the analyzer
 func (a *Analyzer) SetRootPackage(rootPkg string) {
 	a.rootPkgPath = rootPkg
-	a.tree = NewTree(a.repoPath, rootPkg)
+	a.tree = NewTree(a.cfg.ModuleRoot(a.repoPath), rootPkg)
+	a.tree.IncludeTests = a.cfg.Analysis.IncludeTests
+	a.tree.AdditionalInternal = a.cfg.Analysis.AdditionalInternalPrefixes
+	a.tree.BuildTags = a.cfg.Analysis.BuildTags
+	a.tree.TargetMarker = a.cfg.Targets.MarkerFile
+	a.tree.Exclude = a.ignoredPackage
+	a.tree.Include = a.trackedExternal
+	a.tree.ResolutionKey = a.resolutionKey()
+	a.linkWorkspace(a.tree, a.repoPath)
+	if a.baseRepoPath != "" {
+		a.baseTree = NewTree(a.cfg.ModuleRoot(a.baseRepoPath), rootPkg)
+		a.baseTypes = nil
+		a.baseTree.IncludeTests = a.cfg.Analysis.IncludeTests
+		a.baseTree.AdditionalInternal = a.cfg.Analysis.AdditionalInternalPrefixes
+		a.baseTree.BuildTags = a.cfg.Analysis.BuildTags
+		a.baseTree.TargetMarker = a.cfg.Targets.MarkerFile
+		a.baseTree.Exclude = a.ignoredPackage
+		a.baseTree.Include = a.trackedExternal
+		a.baseTree.ResolutionKey = a.resolutionKey()
+		a.linkWorkspace(a.baseTree, a.baseRepoPath)
+	}
+}
+
+// SetBaseModFile sets the go.mod of the base revision, against which new
+// external dependencies are detected. Without it, the go.mod of the base
+// repository is used when one is set.
+func (a *Analyzer) SetBaseModFile(mod *ModFile) {
+	a.baseModFile = mod
+}
+
+// SetBaseRepo sets the checkout of the base revision that changed packages are
+// compared against in import-diff mode and for interface changes. It must be
+// called before SetRootPackage.
+func (a *Analyzer) SetBaseRepo(basePath string) {
+	a.baseRepoPath = basePath
 }
 
 // AnalyzeChangedPackages analyzes the dependencies of changed packages
 func (a *Analyzer) AnalyzeChangedPackages(changedFiles []string) (*AnalysisResult, error) {
 	if a.tree == nil {
-		return nil, fmt.Errorf("analyzer not initialized with root package")
+		return nil, ErrNoRootPackage
 	}
 
 	// First, resolve all packages in the repository to build a complete dependency graph
-	err := filepath.Walk(a.repoPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			// Check for .go files to identify a package directory
-			goFiles, _ := filepath.Glob(filepath.Join(path, "*.go"))
-			if len(goFiles) > 0 {
-				relPath, err := filepath.Rel(a.repoPath, path)
-				if err != nil {
-					return err
-				}
-				pkgPath := filepath.ToSlash(relPath)
-				if pkgPath == "." {
-					// skip root, it's not a real package in this context
-					return nil
-				}
-				fullPkgPath := a.rootPkgPath + "/" + pkgPath
-				if err := a.tree.Resolve(fullPkgPath); err != nil {
-					// Log a warning but continue analysis
-					fmt.Printf("Warning: failed to resolve dependencies for %s: %v\n", fullPkgPath, err)
-				}
-			}
+	if err := a.resolveAll(); err != nil {
+		return nil, err
+	}
+	if a.strictImports {
+		if err := a.checkStrictImports(); err != nil {
+			return nil, err
 		}
-		return nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("error walking repository: %w", err)
 	}
 
-	// Track unique packages
-	changedPkgs := make(map[string]bool)
-
 	// First pass: identify changed packages
-	for _, file := range changedFiles {
-		if !strings.HasSuffix(file, ".go") || strings.HasSuffix(file, "_test.go") {
-			continue
-		}
+	sortedChangedPkgs := a.ChangedPackages(changedFiles)
 
-		pkgPath := filepath.Dir(file)
-		var fullPkgPath string
-		if pkgPath == "." {
-			fullPkgPath = a.rootPkgPath
-		} else {
-			fullPkgPath = a.rootPkgPath + "/" + pkgPath
-		}
-		changedPkgs[fullPkgPath] = true
-	}
-
-	// Second pass: find impacts for each changed package
+	// Second pass: find transitive impacts for each changed package
 	var impacts []*PackageImpact
 	allAffectedPkgs := make(map[string]bool)
-
-	var sortedChangedPkgs []string
-	for pkg := range changedPkgs {
-		sortedChangedPkgs = append(sortedChangedPkgs, pkg)
-	}
-	sort.Strings(sortedChangedPkgs)
+	reverse := a.tree.reverseDependencyIndex()
+	renamed := renamedFrom(a.packageRenames())
+	reachable := make(map[*config.Config]map[string]bool)
 
 	for _, pkgName := range sortedChangedPkgs {
-		revDeps := a.tree.FindReverseDependencies(pkgName)
+		impact := &PackageImpact{ChangedPackage: pkgName, RenamedFrom: renamed[pkgName]}
+		if a.cfg.Analysis.ImportDiff && a.baseTree != nil {
+			a.classifyImportChange(impact)
+		}
+		if a.cfg.Analysis.InterfaceChanges && a.baseTree != nil {
+			// Implementers break even when the import set is unchanged
+			impact.InterfaceImplementers = a.interfaceImplementers(pkgName)
+		}
+
+		// Changes that leave the import set intact are not propagated in import-diff mode
+		if impact.Kind == ChangeKindInternalOnly {
+			impacts = append(impacts, impact)
+			a.notifyImpact(impact)
+			continue
+		}
+
+		cfg := a.configFor(pkgName)
+		if _, ok := reachable[cfg]; !ok {
+			reachable[cfg] = a.entrypointClosure(cfg)
+		}
+		classifiers := a.classifiersFor(cfg)
+		depths := reverseDepths(reverse, pkgName, cfg.Analysis.MaxDepth)
 		var affectedForPkg []*AffectedPackage
-		for _, dep := range revDeps {
-			if a.cfg.ShouldIgnorePackage(dep.Name) {
+		for dep, depth := range depths {
+			if cfg.ShouldIgnorePackage(dep) || !a.firstParty(dep) {
+				continue
+			}
+			if closure := reachable[cfg]; closure != nil && !closure[dep] {
 				continue
 			}
 
+			labels := classify(classifiers, a.tree.Packages[dep])
+
 			// Only include affected packages that are also high-level targets
-			if !a.cfg.IsHighLevelPackage(dep.Name) {
+			if !hasLabel(labels, LabelHighLevel) {
 				continue
 			}
 
 			affectedPkg := &AffectedPackage{
-				Name:       dep.Name,
-				IsCritical: a.cfg.IsCriticalPackage(dep.Name),
+				Name:       dep,
+				IsCritical: hasLabel(labels, LabelCritical),
+				Depth:      depth,
+				Labels:     labels,
+			}
+			if affectedPkg.IsCritical {
+				affectedPkg.DocsURL = cfg.DocsURL(dep)
+				affectedPkg.Badge = cfg.CriticalBadge(dep)
 			}
 
 			affectedForPkg = append(affectedForPkg, affectedPkg)
-			allAffectedPkgs[dep.Name] = true
+			allAffectedPkgs[dep] = true
 		}
 
 		sort.Slice(affectedForPkg, func(i, j int) bool {
 			return affectedForPkg[i].Name < affectedForPkg[j].Name
 		})
 
-		impacts = append(impacts, &PackageImpact{
-			ChangedPackage:   pkgName,
-			AffectedPackages: affectedForPkg,
-		})
+		impact.AffectedPackages = affectedForPkg
+		impacts = append(impacts, impact)
+		a.notifyImpact(impact)
 	}
 
 	// Re-calculate direct and indirect dependencies for the summary
@@ -175,64 +345,467 @@ func (a *Analyzer) AnalyzeChangedPackages(changedFiles []string) (*AnalysisResul
 	sort.Strings(directDepList)
 	sort.Strings(indirectDepList)
 
+	// Collect packages that could not be resolved, wherever in the walk they were reached
+	var warnings []string
+	for pkgName, err := range a.tree.Failed {
+		warnings = append(warnings, fmt.Sprintf("failed to resolve dependencies for %s: %v", pkgName, err))
+	}
+	for pkgName, err := range a.tree.LoadErrors {
+		warnings = append(warnings, fmt.Sprintf("failed to parse some files of %s, its imports come from the others: %v", pkgName, err))
+	}
+	for pkgName, dir := range a.tree.CaseMismatches {
+		warnings = append(warnings, fmt.Sprintf("import path %s does not match the case of its directory %s and will not build on case-sensitive filesystems", pkgName, dir))
+	}
+	sort.Strings(warnings)
+
+	// Escalate according to the number of distinct critical packages affected
+	criticalPkgs := make(map[string]bool)
+	for _, impact := range impacts {
+		for _, pkg := range impact.AffectedPackages {
+			if pkg.IsCritical {
+				criticalPkgs[pkg.Name] = true
+			}
+		}
+	}
+	criticalCount := len(criticalPkgs)
+
 	// Build result
 	result := &AnalysisResult{
 		Impacts:              impacts,
 		DirectDependencies:   directDepList,
 		IndirectDependencies: indirectDepList,
+		Warnings:             warnings,
+		CriticalCount:        criticalCount,
+		Severity:             severityFor(criticalCount, a.cfg.Critical.WarnThreshold, a.cfg.Critical.BlockThreshold),
+		WarnThreshold:        a.cfg.Critical.WarnThreshold,
+		BlockThreshold:       a.cfg.Critical.BlockThreshold,
+		GoVersionIssues:      a.checkGoVersion(sortedChangedPkgs),
+		NewDependencies:      a.newDependencies(),
+		MaxAffectedShown:     a.cfg.Output.MaxAffectedPerPackage,
+		PolicyViolations:     a.policyViolations(impacts),
+		LayerViolations:      a.layerViolations(),
+		DeprecatedImports:    a.deprecatedImports(sortedChangedPkgs),
+		ModulePath:           a.rootPkgPath,
+		RelativePaths:        a.cfg.Output.RelativePaths,
+		ChangedAreas:         changedAreas(a.rootPkgPath, sortedChangedPkgs),
+		NoEmoji:              !a.cfg.Output.Emoji,
+		Incomplete:           a.incomplete,
+		LoadErrors:           len(a.tree.LoadErrors),
 	}
 
 	return result, nil
 }
 
-// String returns a string representation of the analysis result
-func (r *AnalysisResult) String() string {
-	var b strings.Builder
-	b.WriteString("<!-- dependency-guardian -->\n")
-	b.WriteString("## 🔍 Dependency Impact Analysis\n\n")
+// notifyImpact passes a computed impact to the observer, if any
+func (a *Analyzer) notifyImpact(impact *PackageImpact) {
+	if a.onImpact != nil {
+		a.onImpact(impact)
+	}
+}
 
-	if len(r.Impacts) == 0 {
-		b.WriteString("No changed packages found.\n")
-		return b.String()
+// entrypointClosure returns the packages reachable from the entrypoints of cfg,
+// or nil when it defines none
+func (a *Analyzer) entrypointClosure(cfg *config.Config) map[string]bool {
+	if len(cfg.Analysis.Entrypoints) == 0 {
+		return nil
 	}
 
-	b.WriteString("### Changed Packages and Their Impacts\n\n")
-	for _, impact := range r.Impacts {
-		b.WriteString(fmt.Sprintf("#### Changed Package: `%s`\n\n", impact.ChangedPackage))
-		if len(impact.AffectedPackages) > 0 {
-			summary := fmt.Sprintf("<details><summary>Affected Packages (%d)</summary>\n\n", len(impact.AffectedPackages))
-			b.WriteString(summary)
-			for _, pkg := range impact.AffectedPackages {
-				if pkg.IsCritical {
-					b.WriteString(fmt.Sprintf("- 🚨 **`%s`** (Critical)\n", pkg.Name))
-				} else {
-					b.WriteString(fmt.Sprintf("- `%s`\n", pkg.Name))
+	var entrypoints []string
+	for name := range a.tree.Packages {
+		if cfg.IsEntrypoint(name) {
+			entrypoints = append(entrypoints, name)
+		}
+	}
+	if len(entrypoints) == 0 {
+		zap.S().Warnw("no package matches the configured entrypoints", "entrypoints", cfg.Analysis.Entrypoints)
+	}
+	return a.tree.ForwardClosure(entrypoints)
+}
+
+// newDependencies returns the modules required at head but not at the base
+// revision, or nil when the base go.mod is unknown
+func (a *Analyzer) newDependencies() []*ModRequire {
+	base := a.baseModFile
+	if base == nil && a.baseRepoPath != "" {
+		if mod, err := ReadModFile(a.cfg.ModuleRoot(a.baseRepoPath)); err == nil {
+			base = mod
+		}
+	}
+	if base == nil {
+		return nil
+	}
+
+	head, err := ReadModFile(a.cfg.ModuleRoot(a.repoPath))
+	if err != nil {
+		zap.S().Warnw("failed to read go.mod, skipping new dependency detection", "error", err)
+		return nil
+	}
+	return NewRequirements(base, head)
+}
+
+// policyViolations returns the pairs of a changed package that is not an
+// allowed critical source and a critical package it affects, in report order
+func (a *Analyzer) policyViolations(impacts []*PackageImpact) []*PolicyViolation {
+	var violations []*PolicyViolation
+	for _, impact := range impacts {
+		if a.configFor(impact.ChangedPackage).IsCriticalSource(impact.ChangedPackage) {
+			continue
+		}
+		for _, pkg := range impact.AffectedPackages {
+			if pkg.IsCritical {
+				violations = append(violations, &PolicyViolation{
+					ChangedPackage:  impact.ChangedPackage,
+					CriticalPackage: pkg.Name,
+				})
+			}
+		}
+	}
+	return violations
+}
+
+// deprecatedImports returns the imports of deprecated packages by the changed
+// packages, in report order. Deprecated packages importing one another are
+// left out, as they are expected to go away together.
+func (a *Analyzer) deprecatedImports(changedPkgs []string) []*DeprecatedImport {
+	if len(a.cfg.Policy.DeprecatedPackages) == 0 {
+		return nil
+	}
+
+	var imports []*DeprecatedImport
+	for _, name := range changedPkgs {
+		pkg, ok := a.tree.Packages[name]
+		if !ok || a.cfg.IsDeprecatedPackage(name) {
+			continue
+		}
+		for _, imp := range pkg.Imports {
+			if a.cfg.IsDeprecatedPackage(imp) {
+				imports = append(imports, &DeprecatedImport{Importer: name, Imported: imp})
+			}
+		}
+	}
+	return imports
+}
+
+// resolveAll resolves every package directory of the repository, so the tree
+// holds the complete dependency graph. Resolution failures are recorded in the
+// tree rather than returned. When the context of the analyzer is done before
+// every package is resolved, the walk stops and the analysis is marked
+// incomplete; the tree is then left unfrozen.
+func (a *Analyzer) resolveAll() error {
+	if a.tree.Frozen() {
+		return nil
+	}
+
+	pkgNames, err := a.packageDirs()
+	if err != nil {
+		return fmt.Errorf("error walking repository: %w", err)
+	}
+
+	if a.checkpoint != "" {
+		a.tree.trackDigests()
+		restored, err := a.tree.LoadCheckpoint(a.checkpoint)
+		if err != nil {
+			zap.S().Warnw("failed to load checkpoint, resolving from scratch", "error", err)
+		} else if restored > 0 {
+			zap.S().Infow("resuming resolution from checkpoint", "path", a.checkpoint, "packages", restored)
+		}
+	}
+
+	for i, fullPkgPath := range pkgNames {
+		if err := a.ctx.Err(); err != nil {
+			zap.S().Warnw("stopping resolution, analysis is incomplete", "resolved", i, "packages", len(pkgNames), "error", err)
+			a.incomplete = &Incompleteness{Resolved: i, Total: len(pkgNames)}
+			a.saveCheckpoint()
+			return nil
+		}
+		if err := a.tree.Resolve(fullPkgPath); err != nil {
+			// Log a warning but continue analysis; failures are reported in the result
+			zap.S().Warnw("failed to resolve dependencies", "package", fullPkgPath, "error", err)
+		}
+		if (i+1)%checkpointInterval == 0 {
+			a.saveCheckpoint()
+		}
+	}
+
+	// The repository is fully resolved, later queries only read the tree
+	a.incomplete = nil
+	a.tree.Freeze()
+	if a.checkpoint != "" {
+		if err := os.Remove(a.checkpoint); err != nil && !os.IsNotExist(err) {
+			zap.S().Warnw("failed to remove checkpoint", "path", a.checkpoint, "error", err)
+		}
+	}
+	return nil
+}
+
+// saveCheckpoint saves the resolution progress, if checkpointing is enabled.
+// Failing to do so only costs the ability to resume.
+func (a *Analyzer) saveCheckpoint() {
+	if a.checkpoint == "" {
+		return
+	}
+	if err := a.tree.SaveCheckpoint(a.checkpoint); err != nil {
+		zap.S().Warnw("failed to save checkpoint", "path", a.checkpoint, "error", err)
+	}
+}
+
+// packageDirs walks the module and returns the import paths of its package
+// directories, in walk order
+func (a *Analyzer) packageDirs() ([]string, error) {
+	var pkgNames []string
+	moduleRoot := a.cfg.ModuleRoot(a.repoPath)
+	err := filepath.Walk(moduleRoot, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			repoRelPath, err := filepath.Rel(a.repoPath, path)
+			if err != nil {
+				return err
+			}
+			if a.ignoreFile.Ignored(filepath.ToSlash(repoRelPath), true) || a.fileModule(filepath.ToSlash(repoRelPath)+"/") != nil {
+				return filepath.SkipDir
+			}
+			relPath, err := filepath.Rel(moduleRoot, path)
+			if err != nil {
+				return err
+			}
+
+			// Check for .go files to identify a package directory
+			goFiles, _ := filepath.Glob(filepath.Join(path, "*.go"))
+			if len(goFiles) > 0 {
+				if fullPkgPath, ok := a.packageForDir(relPath); ok {
+					pkgNames = append(pkgNames, fullPkgPath)
 				}
 			}
-			b.WriteString("\n</details>\n\n")
-		} else {
-			b.WriteString("This change does not affect any other packages.\n\n")
 		}
+		return nil
+	})
+	return pkgNames, err
+}
+
+// ChangedPackages maps changed files to the sorted, deduplicated import paths of
+// the packages containing them. It honors the file filters of the configuration
+// and does not resolve any dependencies. The full analysis starts from these
+// packages too, so a change touching only filtered files reports no impact,
+// with or without --changed-only. Packages moved to another directory
+// are only listed under their new import path. Files of other workspace modules
+// only map to packages already resolved in the tree.
+func (a *Analyzer) ChangedPackages(changedFiles []string) []string {
+	renames := a.packageRenames()
+	changedPkgs := make(map[string]bool)
+	for _, file := range changedFiles {
+		file = strings.ReplaceAll(file, `\`, "/")
+		if !strings.HasSuffix(file, ".go") {
+			continue
+		}
+		if strings.HasSuffix(file, "_test.go") && !a.cfg.Analysis.IncludeTests {
+			continue
+		}
+		if !a.cfg.ShouldAnalyzeFile(file) || a.ignoreFile.Ignored(file, false) {
+			continue
+		}
+		if module := a.fileModule(file); module != nil {
+			// Packages of other modules are only changed here when imported
+			dir := strings.TrimPrefix(strings.TrimPrefix(path.Dir(file), module.Dir), "/")
+			pkgName := ImportPathFor(module.Path, dir)
+			if _, ok := a.tree.Packages[pkgName]; ok {
+				changedPkgs[pkgName] = true
+			}
+			continue
+		}
+		moduleFile, ok := a.moduleFile(file)
+		if !ok {
+			continue
+		}
+
+		fullPkgPath, ok := a.packageForDir(path.Dir(moduleFile))
+		if !ok {
+			continue
+		}
+		if _, moved := renames[fullPkgPath]; moved {
+			continue
+		}
+		changedPkgs[fullPkgPath] = true
 	}
 
-	b.WriteString("### Analysis Summary:\n\n")
+	var sortedChangedPkgs []string
+	for pkg := range changedPkgs {
+		sortedChangedPkgs = append(sortedChangedPkgs, pkg)
+	}
+	sort.Strings(sortedChangedPkgs)
+
+	return sortedChangedPkgs
+}
+
+// trackedExternal reports whether an external package is tracked like an
+// internal one when vendored: every package with analysis.analyze_vendor,
+// otherwise those matching an import path include pattern
+func (a *Analyzer) trackedExternal(pkgName string) bool {
+	return a.cfg.Analysis.AnalyzeVendor || a.cfg.IsIncludedPackage(pkgName)
+}
+
+// firstParty reports whether a package belongs to the project, as opposed to
+// a third-party vendored package only tracked by analysis.analyze_vendor.
+// Only first-party packages are reported as affected.
+func (a *Analyzer) firstParty(pkgName string) bool {
+	if _, ok := DirFor(a.rootPkgPath, pkgName); ok || a.cfg.IsIncludedPackage(pkgName) {
+		return true
+	}
+	for _, prefix := range a.tree.AdditionalInternal {
+		if _, ok := DirFor(prefix, pkgName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// resolutionKey identifies the settings behind ignoredPackage and
+// trackedExternal, for Tree.ResolutionKey
+func (a *Analyzer) resolutionKey() string {
+	return fmt.Sprintf("%s\x00%t\x00%q\x00%s", a.cfg.Analysis.ModuleDir, a.cfg.Analysis.AnalyzeVendor, a.cfg.Patterns.IncludePatterns, a.ignoreFile)
+}
+
+// ignoredPackage reports whether the directory of an internal package is
+// excluded by the ignore file
+func (a *Analyzer) ignoredPackage(pkgName string) bool {
+	if a.ignoreFile == nil {
+		return false
+	}
+	dir := path.Join(a.cfg.Analysis.ModuleDir, "vendor", pkgName)
+	if rel, ok := DirFor(a.rootPkgPath, pkgName); ok {
+		dir = path.Join(a.cfg.Analysis.ModuleDir, rel)
+	}
+	if module := a.packageModule(pkgName); module != nil {
+		rel, _ := DirFor(module.Path, pkgName)
+		dir = path.Join(module.Dir, rel)
+	}
+	return a.ignoreFile.Ignored(dir, true)
+}
+
+// moduleFile converts a file path relative to the repository root to a path
+// relative to the module directory. It reports false for files outside the
+// module.
+func (a *Analyzer) moduleFile(file string) (string, bool) {
+	moduleDir := path.Clean("/" + a.cfg.Analysis.ModuleDir)
+	if moduleDir == "/" {
+		return file, true
+	}
+	return strings.CutPrefix(path.Clean("/"+file), moduleDir+"/")
+}
 
-	totalChanged := len(r.Impacts)
-	totalAffected := 0
-	affectedSet := make(map[string]bool)
+// packageForDir maps a directory relative to the module root to its import
+// path. The module root is the module path itself.
+// Vendored packages are only analyzed under an additional internal prefix.
+func (a *Analyzer) packageForDir(dir string) (string, bool) {
+	if vendored, ok := strings.CutPrefix(cleanDir(dir), "vendor/"); ok {
+		return vendored, a.tree.IsInternal(vendored)
+	}
+	return ImportPathFor(a.rootPkgPath, dir), true
+}
+
+// PackageDir returns the directory of an internal package, slash-separated and
+// relative to the repository root. It reports false for packages outside it.
+func (a *Analyzer) PackageDir(pkgName string) (string, bool) {
+	if a.tree == nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(a.repoPath, a.tree.packageDir(pkgName))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// ImpactGraph returns the classified impact graph of the given changed packages.
+// It must be called after AnalyzeChangedPackages has resolved the repository.
+func (a *Analyzer) ImpactGraph(changedPkgs []string) *Graph {
+	graph := a.tree.ImpactGraph(changedPkgs)
+	graph.Classify(a.cfg)
+	return graph
+}
+
+// AffectedPackageNames returns the sorted names of all distinct affected packages
+func (r *AnalysisResult) AffectedPackageNames() []string {
+	return r.affectedNames(func(*AffectedPackage) bool { return true })
+}
+
+// CriticalPackageNames returns the sorted names of all distinct critical affected packages
+func (r *AnalysisResult) CriticalPackageNames() []string {
+	return r.affectedNames(func(pkg *AffectedPackage) bool { return pkg.IsCritical })
+}
+
+// affectedNames returns the sorted, distinct names of affected packages matching keep
+func (r *AnalysisResult) affectedNames(keep func(*AffectedPackage) bool) []string {
+	seen := make(map[string]bool)
+	var names []string
 	for _, impact := range r.Impacts {
 		for _, pkg := range impact.AffectedPackages {
-			if !affectedSet[pkg.Name] {
-				affectedSet[pkg.Name] = true
-				totalAffected++
+			if !seen[pkg.Name] && keep(pkg) {
+				seen[pkg.Name] = true
+				names = append(names, pkg.Name)
 			}
 		}
 	}
+	sort.Strings(names)
+	return names
+}
 
-	b.WriteString(fmt.Sprintf("- **Changed packages**: %d\n", totalChanged))
-	b.WriteString(fmt.Sprintf("- **Affected packages**: %d\n", totalAffected))
-	b.WriteString(fmt.Sprintf("- **Direct dependencies of changed packages**: %d\n", len(r.DirectDependencies)))
-	b.WriteString(fmt.Sprintf("- **Indirectly affected packages**: %d\n", len(r.IndirectDependencies)))
+// CriticalOnly returns a copy of the result restricted to critical impacts:
+// changed packages affecting no critical package are dropped and only critical
+// affected packages are kept. Summary totals still count the full result.
+func (r *AnalysisResult) CriticalOnly() *AnalysisResult {
+	filtered := *r
+	filtered.TotalChanged = r.changedTotal()
+	filtered.TotalAffected = r.affectedTotal()
+	filtered.Impacts = nil
+
+	for _, impact := range r.Impacts {
+		var critical []*AffectedPackage
+		for _, pkg := range impact.AffectedPackages {
+			if pkg.IsCritical {
+				critical = append(critical, pkg)
+			}
+		}
+		if len(critical) == 0 {
+			continue
+		}
+		criticalImpact := *impact
+		criticalImpact.AffectedPackages = critical
+		filtered.Impacts = append(filtered.Impacts, &criticalImpact)
+	}
+	return &filtered
+}
 
-	return b.String()
-}
\ No newline at end of file
+// changedTotal returns the number of changed packages, before any filtering
+func (r *AnalysisResult) changedTotal() int {
+	if r.TotalChanged > 0 {
+		return r.TotalChanged
+	}
+	return len(r.Impacts)
+}
+
+// Empty reports whether no changed file of the analysis belongs to a package
+func (r *AnalysisResult) Empty() bool {
+	return r.changedTotal() == 0
+}
+
+// affectedTotal returns the number of distinct affected packages, before any filtering
+func (r *AnalysisResult) affectedTotal() int {
+	if r.TotalAffected > 0 {
+		return r.TotalAffected
+	}
+	return len(r.AffectedPackageNames())
+}
+
+// HasCriticalImpact reports whether any affected package is critical
+func (r *AnalysisResult) HasCriticalImpact() bool {
+	for _, impact := range r.Impacts {
+		for _, pkg := range impact.AffectedPackages {
+			if pkg.IsCritical {
+				return true
+			}
+		}
+	}
+	return false
+}
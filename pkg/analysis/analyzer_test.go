@@ -1,10 +1,14 @@
 package analysis
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/cosmos/dependency-guardian/pkg/config"
 	"github.com/stretchr/testify/require"
@@ -50,9 +54,9 @@ func C() {
 
 	// Initialize analyzer
 	cfg := config.DefaultConfig()
-	cfg.Critical.Packages = []string{
+	cfg.Critical.Packages = config.CriticalPatterns(
 		"**/c", // Mark package c as critical
-	}
+	)
 	analyzer := NewAnalyzer(cfg, repoPath)
 	analyzer.SetRootPackage(rootPkg)
 
@@ -76,4 +80,1167 @@ func C() {
 	affectedPkg := impact.AffectedPackages[0]
 	require.Equal(t, rootPkg+"/c", affectedPkg.Name, "Affected package should be c")
 	require.True(t, affectedPkg.IsCritical, "Affected package c should be marked as critical")
-} 
\ No newline at end of file
+}
+
+func TestAnalyzeChangedPackages_RootPackage(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	rootGo := fmt.Sprintf(`package b
+
+import "%s/lib"
+
+func Root() {
+	lib.L()
+}`, rootPkg)
+	libGo := `package lib
+
+func L() {}`
+
+	t.Run("root imported by subpackage", func(t *testing.T) {
+		repoPath := writeRepo(t, map[string]string{
+			"go.mod":     "module " + rootPkg,
+			"root.go":    rootGo,
+			"lib/lib.go": libGo,
+			"cmd/app/main.go": fmt.Sprintf(`package main
+
+import "%s"
+
+func main() {
+	b.Root()
+}`, rootPkg),
+		})
+
+		analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+		analyzer.SetRootPackage(rootPkg)
+
+		// A change to a root-level file is attributed to the root package
+		result, err := analyzer.AnalyzeChangedPackages([]string{"root.go"})
+		require.NoError(t, err)
+
+		require.Len(t, result.Impacts, 1)
+		impact := result.Impacts[0]
+		require.Equal(t, rootPkg, impact.ChangedPackage)
+		require.Len(t, impact.AffectedPackages, 1)
+		require.Equal(t, rootPkg+"/cmd/app", impact.AffectedPackages[0].Name)
+		require.Equal(t, []string{rootPkg + "/lib"}, result.DirectDependencies)
+	})
+
+	t.Run("root not imported by anything", func(t *testing.T) {
+		repoPath := writeRepo(t, map[string]string{
+			"go.mod":     "module " + rootPkg,
+			"root.go":    rootGo,
+			"lib/lib.go": libGo,
+		})
+
+		analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+		analyzer.SetRootPackage(rootPkg)
+
+		// The root package must still be resolved so it shows up as affected
+		result, err := analyzer.AnalyzeChangedPackages([]string{"lib/lib.go"})
+		require.NoError(t, err)
+
+		require.Len(t, result.Impacts, 1)
+		require.Len(t, result.Impacts[0].AffectedPackages, 1)
+		require.Equal(t, rootPkg, result.Impacts[0].AffectedPackages[0].Name)
+	})
+}
+
+// writeRepo creates a temporary repository containing the given files, keyed by
+// their slash-separated path relative to the repository root.
+func writeRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	repoPath := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(repoPath, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	return repoPath
+}
+
+func TestAnalyzeChangedPackages_IncludeTests(t *testing.T) {
+	// b only depends on a from its tests
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod": "module " + rootPkg,
+		"a/a.go": `package a
+
+func A() {}`,
+		"b/b.go": `package b
+
+func B() {}`,
+		"b/b_test.go": fmt.Sprintf(`package b_test
+
+import (
+	"testing"
+
+	"%[1]s/a"
+	"%[1]s/b"
+)
+
+func TestB(t *testing.T) {
+	a.A()
+	b.B()
+}`, rootPkg),
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+		analyzer.SetRootPackage(rootPkg)
+
+		result, err := analyzer.AnalyzeChangedPackages([]string{"a/a.go"})
+		require.NoError(t, err)
+		require.Len(t, result.Impacts, 1)
+		require.False(t, result.Empty())
+		require.Empty(t, result.Impacts[0].AffectedPackages, "test imports should not create dependencies")
+
+		result, err = analyzer.AnalyzeChangedPackages([]string{"b/b_test.go"})
+		require.NoError(t, err)
+		require.Empty(t, result.Impacts, "test files should not trigger changed packages")
+		require.True(t, result.Empty())
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		cfg := config.DefaultConfig()
+		cfg.Analysis.IncludeTests = true
+		analyzer := NewAnalyzer(cfg, repoPath)
+		analyzer.SetRootPackage(rootPkg)
+
+		result, err := analyzer.AnalyzeChangedPackages([]string{"a/a.go"})
+		require.NoError(t, err)
+		require.Len(t, result.Impacts, 1)
+		require.Len(t, result.Impacts[0].AffectedPackages, 1)
+		require.Equal(t, rootPkg+"/b", result.Impacts[0].AffectedPackages[0].Name)
+
+		result, err = analyzer.AnalyzeChangedPackages([]string{"b/b_test.go"})
+		require.NoError(t, err)
+		require.Len(t, result.Impacts, 1)
+		require.Equal(t, rootPkg+"/b", result.Impacts[0].ChangedPackage)
+
+		// The package under test is not a dependency of itself
+		require.Equal(t, []string{rootPkg + "/a"}, result.DirectDependencies)
+	})
+}
+
+func TestAnalysisResult_StringWithContext(t *testing.T) {
+	result := &AnalysisResult{
+		Impacts: []*PackageImpact{{ChangedPackage: "github.com/a/b/d"}},
+	}
+
+	out := result.StringWithContext(ResultContext{
+		BaseSHA:    "1111111111111111111111111111111111111111",
+		HeadSHA:    "2222222222222222222222222222222222222222",
+		AnalyzedAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+	})
+	require.Contains(t, out, "Analyzed base `1111111111111111111111111111111111111111` against head `2222222222222222222222222222222222222222` at 2024-05-01T12:30:00Z.")
+	require.True(t, strings.HasPrefix(out, "<!-- dependency-guardian -->\n"))
+
+	// Without context the header line is omitted
+	require.NotContains(t, result.String(), "Analyzed base")
+}
+
+func TestAnalyzeChangedPackages_ParseFailureWarnings(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod": "module " + rootPkg,
+		"good/good.go": `package good
+
+func Good() {}`,
+		"broken/broken.go": `package broken
+
+import (
+	"fmt"
+`,
+	})
+
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	// Capture stdout to make sure nothing is written to it
+	origStdout := os.Stdout
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	os.Stdout = w
+	result, err := analyzer.AnalyzeChangedPackages([]string{"good/good.go"})
+	os.Stdout = origStdout
+	require.NoError(t, w.Close())
+	stdout, readErr := io.ReadAll(r)
+	require.NoError(t, readErr)
+
+	require.NoError(t, err)
+	require.Empty(t, string(stdout), "warnings must not be written to stdout")
+	require.Len(t, result.Warnings, 1)
+	require.Contains(t, result.Warnings[0], rootPkg+"/broken")
+	require.Contains(t, result.String(), "Warnings (1)")
+}
+
+func TestAnalyzer_ChangedPackages(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	cfg := config.DefaultConfig()
+	cfg.Patterns.IgnoreFilePatterns = []string{"**/*_test.go", "gen/**"}
+
+	// The repository does not exist on disk: nothing may be resolved
+	analyzer := NewAnalyzer(cfg, filepath.Join(t.TempDir(), "missing"))
+	analyzer.SetRootPackage(rootPkg)
+
+	pkgs := analyzer.ChangedPackages([]string{
+		"root.go",
+		"internal/db/db.go",
+		"internal/db/query.go",
+		"internal/db/db_test.go",
+		"api/handler.go",
+		"gen/types.go",
+		"README.md",
+	})
+	require.Equal(t, []string{
+		rootPkg,
+		rootPkg + "/api",
+		rootPkg + "/internal/db",
+	}, pkgs)
+	require.Empty(t, analyzer.tree.Packages, "changed packages must be computed without resolution")
+}
+
+func TestAnalyzeChangedPackages_FileFilters(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod":       "module " + rootPkg,
+		"cmd/app.go":   "package main\n\nimport (\n\t_ \"github.com/a/b/gen\"\n\t_ \"github.com/a/b/lib\"\n)\n\nfunc main() {}",
+		"gen/types.go": "package gen",
+		"lib/lib.go":   "package lib",
+	})
+
+	// The file filters of --changed-only also select the packages the full
+	// analysis reports the impact of
+	cfg := config.DefaultConfig()
+	cfg.Patterns.IgnoreFilePatterns = []string{"gen/**"}
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+	result, err := analyzer.AnalyzeChangedPackages([]string{"gen/types.go", "lib/lib.go"})
+	require.NoError(t, err)
+	require.Len(t, result.Impacts, 1)
+	require.Equal(t, rootPkg+"/lib", result.Impacts[0].ChangedPackage)
+
+	result, err = analyzer.AnalyzeChangedPackages([]string{"gen/types.go"})
+	require.NoError(t, err)
+	require.Empty(t, result.Impacts)
+
+	cfg = config.DefaultConfig()
+	cfg.Patterns.IncludePatterns = []string{"gen/**"}
+	analyzer = NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+	result, err = analyzer.AnalyzeChangedPackages([]string{"gen/types.go", "lib/lib.go"})
+	require.NoError(t, err)
+	require.Len(t, result.Impacts, 1)
+	require.Equal(t, rootPkg+"/gen", result.Impacts[0].ChangedPackage)
+}
+
+func TestAnalyzeChangedPackages_DefaultThresholds(t *testing.T) {
+	// Two critical packages import d, and a third imports e
+	rootPkg := "github.com/a/b"
+	files := map[string]string{
+		"go.mod": "module " + rootPkg,
+		"d/d.go": "package d\n\nfunc D() {}",
+		"e/e.go": "package e\n\nfunc E() {}",
+	}
+	for name, dep := range map[string]string{"pay": "d", "auth": "d", "ledger": "e"} {
+		files[name+"/"+name+".go"] = fmt.Sprintf("package %s\n\nimport \"%s/%s\"\n\nvar _ = %s.%s", name, rootPkg, dep, dep, strings.ToUpper(dep))
+	}
+	repoPath := writeRepo(t, files)
+
+	cfg := config.DefaultConfig()
+	cfg.Critical.Packages = config.CriticalPatterns("**/pay", "**/auth", "**/ledger")
+	analyze := func(changed ...string) *AnalysisResult {
+		analyzer := NewAnalyzer(cfg, repoPath)
+		analyzer.SetRootPackage(rootPkg)
+		result, err := analyzer.AnalyzeChangedPackages(changed)
+		require.NoError(t, err)
+		return result
+	}
+
+	// Below the default block threshold, critical impacts only warn
+	result := analyze("d/d.go")
+	require.Equal(t, SeverityWarn, result.Severity)
+	require.Contains(t, result.String(), "⚠️ 2 critical packages affected (threshold: 1).")
+	require.NotContains(t, result.String(), "This change is blocking")
+
+	result = analyze("d/d.go", "e/e.go")
+	require.Equal(t, SeverityBlock, result.Severity)
+	require.Contains(t, result.String(), "🛑 **3 critical packages affected (threshold: 3)**. This change is blocking.")
+}
+
+func TestAnalyzeChangedPackages_CriticalThresholds(t *testing.T) {
+	// Three critical packages and one regular package import d
+	rootPkg := "github.com/a/b"
+	files := map[string]string{
+		"go.mod": "module " + rootPkg,
+		"d/d.go": "package d\n\nfunc D() {}",
+	}
+	for _, name := range []string{"pay", "auth", "ledger", "docs"} {
+		files[name+"/"+name+".go"] = fmt.Sprintf("package %s\n\nimport \"%s/d\"\n\nfunc F() { d.D() }", name, rootPkg)
+	}
+	repoPath := writeRepo(t, files)
+
+	tests := []struct {
+		name           string
+		warnThreshold  int
+		blockThreshold int
+		severity       Severity
+		message        string
+	}{
+		{"crosses block threshold", 1, 3, SeverityBlock, "🛑 **3 critical packages affected (threshold: 3)**"},
+		{"between thresholds", 2, 4, SeverityWarn, "⚠️ 3 critical packages affected (threshold: 2)."},
+		{"below thresholds", 4, 5, SeverityNone, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Critical.Packages = config.CriticalPatterns("**/pay", "**/auth", "**/ledger")
+			cfg.Critical.WarnThreshold = tt.warnThreshold
+			cfg.Critical.BlockThreshold = tt.blockThreshold
+			analyzer := NewAnalyzer(cfg, repoPath)
+			analyzer.SetRootPackage(rootPkg)
+
+			result, err := analyzer.AnalyzeChangedPackages([]string{"d/d.go"})
+			require.NoError(t, err)
+			require.Equal(t, 3, result.CriticalCount)
+			require.Equal(t, tt.severity, result.Severity)
+
+			if tt.message != "" {
+				require.Contains(t, result.String(), tt.message)
+			} else {
+				require.NotContains(t, result.String(), "critical packages affected")
+			}
+		})
+	}
+}
+
+func TestAnalyzeChangedPackages_Depths(t *testing.T) {
+	// Chain where each package imports the previous one: a <- b <- c <- d
+	rootPkg := "github.com/a/b"
+	files := map[string]string{
+		"go.mod": "module " + rootPkg,
+		"a/a.go": "package a\n\nfunc F() {}",
+	}
+	chain := []string{"a", "b", "c", "d"}
+	for i := 1; i < len(chain); i++ {
+		files[chain[i]+"/"+chain[i]+".go"] = fmt.Sprintf("package %s\n\nimport \"%s/%s\"\n\nfunc F() { %s.F() }", chain[i], rootPkg, chain[i-1], chain[i-1])
+	}
+	repoPath := writeRepo(t, files)
+
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"a/a.go"})
+	require.NoError(t, err)
+	require.Len(t, result.Impacts, 1)
+
+	affected := result.Impacts[0].AffectedPackages
+	require.Len(t, affected, 3)
+	for i, name := range []string{"b", "c", "d"} {
+		require.Equal(t, rootPkg+"/"+name, affected[i].Name)
+		require.Equal(t, i+1, affected[i].Depth)
+	}
+
+	report := result.String()
+	require.Contains(t, report, "- `"+rootPkg+"/b` — directly affected\n")
+	require.Contains(t, report, "- `"+rootPkg+"/d` — affected at depth 3\n")
+}
+
+func TestAnalyzeChangedPackages_ImportDiff(t *testing.T) {
+	// At head, a gains an import of util while b only changes its function body.
+	// Both are imported by app.
+	rootPkg := "github.com/a/b"
+	base := map[string]string{
+		"go.mod":       "module " + rootPkg,
+		"util/util.go": "package util\n\nfunc U() {}",
+		"a/a.go":       "package a\n\nfunc A() {}",
+		"b/b.go":       "package b\n\nfunc B() int { return 1 }",
+		"app/app.go": fmt.Sprintf(`package app
+
+import (
+	"%[1]s/a"
+	"%[1]s/b"
+)
+
+func App() { a.A(); b.B() }`, rootPkg),
+	}
+	head := make(map[string]string)
+	for name, content := range base {
+		head[name] = content
+	}
+	head["a/a.go"] = fmt.Sprintf("package a\n\nimport \"%s/util\"\n\nfunc A() { util.U() }", rootPkg)
+	head["b/b.go"] = "package b\n\nfunc B() int { return 2 }"
+
+	cfg := config.DefaultConfig()
+	cfg.Analysis.ImportDiff = true
+	analyzer := NewAnalyzer(cfg, writeRepo(t, head))
+	analyzer.SetBaseRepo(writeRepo(t, base))
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"a/a.go", "b/b.go"})
+	require.NoError(t, err)
+	require.Len(t, result.Impacts, 2)
+
+	importChange := result.Impacts[0]
+	require.Equal(t, rootPkg+"/a", importChange.ChangedPackage)
+	require.Equal(t, ChangeKindImportsChanged, importChange.Kind)
+	require.Equal(t, []string{rootPkg + "/util"}, importChange.AddedImports)
+	require.Empty(t, importChange.RemovedImports)
+	require.Len(t, importChange.AffectedPackages, 1)
+	require.Equal(t, rootPkg+"/app", importChange.AffectedPackages[0].Name)
+
+	bodyChange := result.Impacts[1]
+	require.Equal(t, rootPkg+"/b", bodyChange.ChangedPackage)
+	require.Equal(t, ChangeKindInternalOnly, bodyChange.Kind)
+	require.Empty(t, bodyChange.AffectedPackages)
+
+	report := result.String()
+	require.Contains(t, report, "Import set changed: +`"+rootPkg+"/util`")
+	require.Contains(t, report, "The import set of this package is unchanged")
+}
+
+func TestAnalyzeChangedPackages_GoVersion(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod": "module " + rootPkg + "\n\ngo 1.19\n",
+		// min is a builtin since Go 1.21
+		"a/a.go": "package a\n\nfunc A(x, y int) int { return min(x, y) }",
+		// Generics are available since Go 1.18
+		"b/b.go": "package b\n\nfunc B[T any](v T) T { return v }",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Analysis.MinGoVersion = "1.21"
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"a/a.go", "b/b.go"})
+	require.NoError(t, err)
+	require.Len(t, result.GoVersionIssues, 2)
+	require.Equal(t, "go.mod declares go 1.19, below the policy minimum of 1.21", result.GoVersionIssues[0])
+	require.Contains(t, result.GoVersionIssues[1], "a/a.go:3:")
+	require.Contains(t, result.GoVersionIssues[1], "requires go1.21")
+
+	report := result.String()
+	require.Contains(t, report, "### Go Version Compatibility")
+	require.True(t, strings.HasSuffix(report, "- ⚠️ "+result.GoVersionIssues[1]+"\n"), "compatibility issues are in the footer")
+
+	// A compliant go directive without newer features reports nothing
+	cfg.Analysis.MinGoVersion = "1.18"
+	result, err = analyzer.AnalyzeChangedPackages([]string{"b/b.go"})
+	require.NoError(t, err)
+	require.Empty(t, result.GoVersionIssues)
+	require.NotContains(t, result.String(), "Go Version Compatibility")
+}
+
+func TestAnalysisResult_MaxAffectedPerPackage(t *testing.T) {
+	// 50 packages import d; the last two in name order are critical
+	rootPkg := "github.com/a/b"
+	files := map[string]string{
+		"go.mod": "module " + rootPkg,
+		"d/d.go": "package d\n\nfunc D() {}",
+	}
+	for i := 0; i < 50; i++ {
+		name := fmt.Sprintf("p%02d", i)
+		files[name+"/"+name+".go"] = fmt.Sprintf("package %s\n\nimport \"%s/d\"\n\nfunc F() { d.D() }", name, rootPkg)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.Critical.Packages = config.CriticalPatterns(rootPkg+"/p48", rootPkg+"/p49")
+	analyzer := NewAnalyzer(cfg, writeRepo(t, files))
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"d/d.go"})
+	require.NoError(t, err)
+	require.Len(t, result.Impacts[0].AffectedPackages, 50, "the result keeps every affected package")
+	require.Equal(t, 25, result.MaxAffectedShown)
+
+	report := result.String()
+	require.Contains(t, report, "<summary>Affected Packages (50)</summary>")
+	require.Contains(t, report, "`"+rootPkg+"/p22`")
+	require.NotContains(t, report, "`"+rootPkg+"/p23`")
+	require.Contains(t, report, "🚨 **`"+rootPkg+"/p48`**")
+	require.Contains(t, report, "🚨 **`"+rootPkg+"/p49`**")
+	require.Contains(t, report, "- … and 25 more (see JSON artifact)")
+	require.Equal(t, 25, strings.Count(report, " — directly affected"))
+
+	// Without a cap every package is listed
+	result.MaxAffectedShown = 0
+	report = result.String()
+	require.Equal(t, 50, strings.Count(report, " — directly affected"))
+	require.NotContains(t, report, "more (see JSON artifact)")
+}
+
+func TestAnalyzeChangedPackages_CriticalSources(t *testing.T) {
+	// Both core and util are imported by the critical auth package
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod":                "module " + rootPkg,
+		"internal/core/core.go": "package core\n\nfunc C() {}",
+		"util/util.go":          "package util\n\nfunc U() {}",
+		"auth/auth.go": fmt.Sprintf(`package auth
+
+import (
+	"%[1]s/internal/core"
+	"%[1]s/util"
+)
+
+func A() { core.C(); util.U() }`, rootPkg),
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Critical.Packages = config.CriticalPatterns("**/auth")
+	cfg.Policy.CriticalSources = []string{"**/internal/core/**", "**/internal/core"}
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"internal/core/core.go", "util/util.go"})
+	require.NoError(t, err)
+	require.Equal(t, []*PolicyViolation{
+		{ChangedPackage: rootPkg + "/util", CriticalPackage: rootPkg + "/auth"},
+	}, result.PolicyViolations)
+	require.Contains(t, result.String(), "🚫 **1 critical source policy violations**")
+
+	// Without an allowlist any package may affect critical packages
+	cfg.Policy.CriticalSources = nil
+	result, err = analyzer.AnalyzeChangedPackages([]string{"util/util.go"})
+	require.NoError(t, err)
+	require.Empty(t, result.PolicyViolations)
+}
+
+func TestAnalysisResult_RelativePaths(t *testing.T) {
+	rootPkg := "github.com/org/repo"
+	result := &AnalysisResult{
+		Impacts: []*PackageImpact{
+			{
+				ChangedPackage: rootPkg + "/internal/services/billing",
+				AffectedPackages: []*AffectedPackage{
+					{Name: rootPkg, Depth: 2},
+					{Name: rootPkg + "/cmd/server", IsCritical: true, Depth: 1},
+				},
+			},
+		},
+		ModulePath: rootPkg,
+	}
+
+	report := result.String()
+	require.Contains(t, report, "#### Changed Package: `github.com/org/repo/internal/services/billing`")
+	require.Contains(t, report, "- 🚨 **`github.com/org/repo/cmd/server`** (Critical)")
+
+	result.RelativePaths = true
+	report = result.String()
+	require.Contains(t, report, "#### Changed Package: `internal/services/billing`")
+	require.Contains(t, report, "- 🚨 **`cmd/server`** (Critical)")
+	// The module root keeps its full path
+	require.Contains(t, report, "- `github.com/org/repo` — affected at depth 2")
+	require.NotContains(t, report, "github.com/org/repo/")
+}
+
+func TestAnalysisResult_CriticalSummary(t *testing.T) {
+	result := &AnalysisResult{
+		Impacts: []*PackageImpact{
+			{
+				ChangedPackage: "example.com/m/a",
+				AffectedPackages: []*AffectedPackage{
+					{Name: "example.com/m/auth", IsCritical: true, Depth: 1},
+					{Name: "example.com/m/leaf", Depth: 1},
+				},
+			},
+			{
+				ChangedPackage: "example.com/m/b",
+				AffectedPackages: []*AffectedPackage{
+					{Name: "example.com/m/auth", IsCritical: true, Depth: 2},
+					{Name: "example.com/m/billing", IsCritical: true, Depth: 1},
+				},
+			},
+		},
+	}
+
+	report := result.String()
+	require.Contains(t, report, "- **Critical packages affected**: 2\n")
+
+	critical := strings.Index(report, "### 🚨 Critical Packages Affected (2)\n\n- **`example.com/m/auth`**\n- **`example.com/m/billing`**\n")
+	breakdown := strings.Index(report, "### Changed Packages and Their Impacts")
+	require.NotEqual(t, -1, critical)
+	require.Less(t, critical, breakdown, "critical packages lead the report")
+
+	// Without critical impact there is no leading list
+	result.Impacts = result.Impacts[:1]
+	result.Impacts[0].AffectedPackages = result.Impacts[0].AffectedPackages[1:]
+	report = result.String()
+	require.Contains(t, report, "- **Critical packages affected**: 0\n")
+	require.NotContains(t, report, "### 🚨 Critical Packages Affected")
+}
+
+func TestAnalyzeChangedPackages_AdditionalInternalPrefixes(t *testing.T) {
+	// app imports a vendored package of the shared module, which in turn imports
+	// another shared package. An unrelated third-party package is vendored too,
+	// as is a sibling module whose name extends the internal prefix.
+	rootPkg := "github.com/org/repo"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod": "module " + rootPkg,
+		"vendor/github.com/org/shared/log/log.go":   "package log\n\nimport \"github.com/org/shared/fmtx\"\n\nfunc L() { fmtx.F() }",
+		"vendor/github.com/org/shared/fmtx/fmtx.go": "package fmtx\n\nfunc F() {}",
+		"vendor/github.com/other/lib/lib.go":        "package lib\n\nfunc X() {}",
+		"vendor/github.com/org/shared-utils/x/x.go": "package x\n\nfunc X() {}",
+		"app/app.go": `package app
+
+import (
+	"github.com/org/shared-utils/x"
+	"github.com/org/shared/log"
+	"github.com/other/lib"
+)
+
+func App() { log.L(); lib.X(); x.X() }`,
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Analysis.AdditionalInternalPrefixes = []string{"github.com/org/shared"}
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{
+		"vendor/github.com/org/shared/fmtx/fmtx.go",
+		"vendor/github.com/other/lib/lib.go",
+		"vendor/github.com/org/shared-utils/x/x.go",
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Impacts, 1, "only vendored packages under an internal prefix are analyzed")
+	require.Equal(t, "github.com/org/shared/fmtx", result.Impacts[0].ChangedPackage)
+	require.Equal(t, []string{rootPkg + "/app", "github.com/org/shared/log"}, result.AffectedPackageNames())
+
+	app := analyzer.tree.Packages[rootPkg+"/app"]
+	require.Equal(t, []string{"github.com/org/shared/log"}, app.Imports)
+	require.True(t, analyzer.tree.Packages["github.com/org/shared/log"].Internal)
+	require.False(t, analyzer.tree.IsInternal("github.com/org/shared-utils/x"), "a longer sibling module name is not under the prefix")
+	require.NotContains(t, analyzer.tree.Packages, rootPkg+"/vendor/github.com/org/shared/log")
+}
+
+func TestAnalyzeChangedPackages_IncludePatterns(t *testing.T) {
+	// app imports a vendored first-party package matching an include pattern,
+	// another one that is not vendored and an unrelated third-party package
+	rootPkg := "github.com/org/repo"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod": "module " + rootPkg,
+		"vendor/github.com/org/shared/log/log.go": "package log\n\nfunc L() {}",
+		"vendor/github.com/other/lib/lib.go":      "package lib\n\nfunc X() {}",
+		"app/app.go": `package app
+
+import (
+	"github.com/org/shared/log"
+	"github.com/org/shared/metrics"
+	"github.com/other/lib"
+)
+
+func App() { log.L(); metrics.M(); lib.X() }`,
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Patterns.IncludePatterns = []string{"github.com/org/shared/**"}
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	// The import path pattern does not restrict the changed files
+	result, err := analyzer.AnalyzeChangedPackages([]string{
+		"vendor/github.com/org/shared/log/log.go",
+		"vendor/github.com/other/lib/lib.go",
+	})
+	require.NoError(t, err)
+	require.Len(t, result.Impacts, 1)
+	require.Equal(t, "github.com/org/shared/log", result.Impacts[0].ChangedPackage)
+	require.Equal(t, []string{rootPkg + "/app"}, result.AffectedPackageNames())
+
+	// Only the matching import found on disk is tracked
+	require.Equal(t, []string{"github.com/org/shared/log"}, analyzer.tree.Packages[rootPkg+"/app"].Imports)
+	require.Empty(t, analyzer.tree.Failed)
+}
+
+func TestAnalyzeChangedPackages_AnalyzeVendor(t *testing.T) {
+	// app imports y through the vendored z, lib imports y directly, and so
+	// does a vendored fork whose module path extends the root path
+	rootPkg := "github.com/org/repo"
+	files := map[string]string{
+		"go.mod":                                 "module " + rootPkg,
+		"vendor/github.com/x/y/y.go":             "package y\n\nfunc Y() {}",
+		"vendor/github.com/org/repo-fork/f/f.go": "package f\n\nimport \"github.com/x/y\"\n\nfunc F() { y.Y() }",
+		"vendor/github.com/x/z/z.go":             "package z\n\nimport \"github.com/x/y\"\n\nfunc Z() { y.Y() }",
+		"app/app.go":                             "package app\n\nimport \"github.com/x/z\"\n\nfunc App() { z.Z() }",
+		"lib/lib.go":                             "package lib\n\nimport (\n\t\"github.com/org/repo-fork/f\"\n\t\"github.com/x/y\"\n)\n\nfunc Lib() { y.Y(); f.F() }",
+		"other/other.go":                         "package other",
+	}
+	changed := []string{"vendor/github.com/x/y/y.go"}
+
+	// Vendored packages are skipped by default
+	analyzer := NewAnalyzer(config.DefaultConfig(), writeRepo(t, files))
+	analyzer.SetRootPackage(rootPkg)
+	result, err := analyzer.AnalyzeChangedPackages(changed)
+	require.NoError(t, err)
+	require.Empty(t, result.Impacts)
+
+	cfg := config.DefaultConfig()
+	cfg.Analysis.AnalyzeVendor = true
+	analyzer = NewAnalyzer(cfg, writeRepo(t, files))
+	analyzer.SetRootPackage(rootPkg)
+	result, err = analyzer.AnalyzeChangedPackages(changed)
+	require.NoError(t, err)
+	require.Len(t, result.Impacts, 1)
+	require.Equal(t, "github.com/x/y", result.Impacts[0].ChangedPackage)
+
+	// Only first-party importers are reported, the vendored z and fork are not
+	require.Equal(t, []string{rootPkg + "/app", rootPkg + "/lib"}, result.AffectedPackageNames())
+}
+
+func TestAnalysisResult_CriticalOnly(t *testing.T) {
+	result := &AnalysisResult{
+		Impacts: []*PackageImpact{
+			{
+				ChangedPackage: "example.com/m/a",
+				AffectedPackages: []*AffectedPackage{
+					{Name: "example.com/m/auth", IsCritical: true, Depth: 1},
+					{Name: "example.com/m/leaf", Depth: 1},
+				},
+			},
+			{
+				ChangedPackage:   "example.com/m/b",
+				AffectedPackages: []*AffectedPackage{{Name: "example.com/m/other", Depth: 1}},
+			},
+		},
+	}
+
+	filtered := result.CriticalOnly()
+	require.Len(t, filtered.Impacts, 1)
+	require.Equal(t, "example.com/m/a", filtered.Impacts[0].ChangedPackage)
+	require.Equal(t, []string{"example.com/m/auth"}, filtered.AffectedPackageNames())
+	require.Len(t, result.Impacts[0].AffectedPackages, 2, "the original result is unchanged")
+
+	report := filtered.String()
+	require.NotContains(t, report, "example.com/m/leaf")
+	require.NotContains(t, report, "example.com/m/b`")
+	// The summary counts the unfiltered result
+	require.Contains(t, report, "- **Changed packages**: 2\n")
+	require.Contains(t, report, "- **Affected packages**: 3\n")
+
+	// Without any critical impact the breakdown says so
+	result.Impacts = result.Impacts[1:]
+	report = result.CriticalOnly().String()
+	require.Contains(t, report, "No critical packages are affected.")
+	require.Contains(t, report, "- **Changed packages**: 1\n")
+}
+
+func TestAnalyzeChangedPackages_DocsLinks(t *testing.T) {
+	// d is imported by the critical billing and auth packages and by lib
+	rootPkg := "github.com/a/b"
+	imports := fmt.Sprintf("import \"%s/d\"\n\nfunc F() { d.D() }", rootPkg)
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod":             "module " + rootPkg,
+		"d/d.go":             "package d\n\nfunc D() {}",
+		"billing/billing.go": "package billing\n\n" + imports,
+		"auth/auth.go":       "package auth\n\n" + imports,
+		"lib/lib.go":         "package lib\n\n" + imports,
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Critical.Packages = config.CriticalPatterns("**/billing", "**/auth")
+	cfg.Docs = map[string]string{
+		"**/billing": "https://runbook.example.com/billing",
+		"**/lib":     "https://docs.example.com/lib",
+	}
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"d/d.go"})
+	require.NoError(t, err)
+
+	report := result.String()
+	require.Contains(t, report, "- **[`"+rootPkg+"/billing`](https://runbook.example.com/billing)**\n")
+	require.Contains(t, report, "- 🚨 **[`"+rootPkg+"/billing`](https://runbook.example.com/billing)** (Critical)")
+	require.Contains(t, report, "- 🚨 **`"+rootPkg+"/auth`** (Critical)")
+	// Only critical packages are linked
+	require.Contains(t, report, "- `"+rootPkg+"/lib` — directly affected")
+	require.NotContains(t, report, "https://docs.example.com/lib")
+}
+
+func TestAnalyzeChangedPackages_CriticalBadges(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	imports := fmt.Sprintf("import \"%s/d\"\n\nfunc F() { d.D() }", rootPkg)
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod":             "module " + rootPkg,
+		"d/d.go":             "package d\n\nfunc D() {}",
+		"billing/billing.go": "package billing\n\n" + imports,
+		"auth/auth.go":       "package auth\n\n" + imports,
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Critical.Packages = []config.CriticalPattern{
+		{Pattern: "**/billing", Badge: "💳"},
+		{Pattern: "**/auth"},
+	}
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"d/d.go"})
+	require.NoError(t, err)
+
+	report := result.String()
+	require.Contains(t, report, "- 💳 **`"+rootPkg+"/billing`** (Critical)")
+	require.Contains(t, report, "- 🚨 **`"+rootPkg+"/auth`** (Critical)")
+
+	// Without emoji the default marker is used for every package
+	result.NoEmoji = true
+	require.NotContains(t, result.String(), "💳")
+}
+
+func TestAnalyzeChangedPackages_NewDependencies(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	base := `module github.com/a/b
+
+go 1.22
+
+require (
+	github.com/stretchr/testify v1.9.0
+	golang.org/x/sync v0.6.0 // indirect
+)
+`
+	head := `module github.com/a/b
+
+go 1.22
+
+require (
+	github.com/stretchr/testify v1.10.0
+	golang.org/x/sync v0.6.0 // indirect
+	github.com/evil/left-pad v0.1.0
+)
+
+require github.com/davecgh/go-spew v1.1.1 // indirect
+`
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod": head,
+		"a/a.go": "package a",
+	})
+
+	baseMod, err := ParseModFile([]byte(base))
+	require.NoError(t, err)
+	require.Len(t, baseMod.Requires, 2)
+	require.True(t, baseMod.Requires[1].Indirect)
+
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetBaseModFile(baseMod)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"go.mod"})
+	require.NoError(t, err)
+	require.Equal(t, []*ModRequire{
+		{Path: "github.com/davecgh/go-spew", Version: "v1.1.1", Indirect: true},
+		{Path: "github.com/evil/left-pad", Version: "v0.1.0"},
+	}, result.NewDependencies, "version bumps are not new dependencies")
+
+	report := result.String()
+	require.Contains(t, report, "### 📦 New External Dependencies (2)\n\n- `github.com/davecgh/go-spew` v1.1.1 (indirect)\n- `github.com/evil/left-pad` v0.1.0\n")
+}
+
+func TestAnalyzeChangedPackages_ConfigOverrides(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"internal/payments/core/core.go": "package core",
+		"internal/payments/ledger/l.go":  "package ledger\n\nimport _ \"github.com/a/b/internal/payments/core\"\n",
+		"lib/lib.go":                     "package lib",
+		"auth/auth.go":                   "package auth\n\nimport (\n\t_ \"github.com/a/b/internal/payments/core\"\n\t_ \"github.com/a/b/lib\"\n)\n",
+	})
+
+	cfg, err := config.LoadConfigFromReader(strings.NewReader(`
+critical:
+  packages: ["**/auth"]
+overrides:
+  - path_prefix: internal/payments
+    config:
+      critical:
+        packages: ["**/ledger"]
+`))
+	require.NoError(t, err)
+
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"internal/payments/core/core.go", "lib/lib.go"})
+	require.NoError(t, err)
+	require.Len(t, result.Impacts, 2)
+
+	// The payments change uses the override's critical list
+	payments := result.Impacts[0]
+	require.Equal(t, rootPkg+"/internal/payments/core", payments.ChangedPackage)
+	require.Equal(t, []*AffectedPackage{
+		{Name: rootPkg + "/auth", Depth: 1, Labels: []string{LabelHighLevel}},
+		{Name: rootPkg + "/internal/payments/ledger", IsCritical: true, Depth: 1, Labels: []string{LabelCritical, LabelHighLevel}},
+	}, payments.AffectedPackages)
+
+	// Other changes use the base configuration
+	lib := result.Impacts[1]
+	require.Equal(t, rootPkg+"/lib", lib.ChangedPackage)
+	require.Len(t, lib.AffectedPackages, 1)
+	require.True(t, lib.AffectedPackages[0].IsCritical)
+
+	require.Equal(t, 2, result.CriticalCount)
+}
+
+func TestAnalyzeChangedPackages_IgnoreFile(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		config.DefaultIgnoreFileName: "# Generated mocks\nmocks/\n",
+		"core/core.go":               "package core",
+		"app/app.go":                 "package app\n\nimport _ \"github.com/a/b/core\"\n",
+		"mocks/core/mock.go":         "package core\n\nimport _ \"github.com/a/b/core\"\n",
+	})
+
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"core/core.go", "mocks/core/mock.go"})
+	require.NoError(t, err)
+
+	// The ignored directory is neither a changed package nor part of the graph
+	require.Len(t, result.Impacts, 1)
+	require.Equal(t, rootPkg+"/core", result.Impacts[0].ChangedPackage)
+	require.Equal(t, []string{rootPkg + "/app"}, result.AffectedPackageNames())
+	require.NotContains(t, analyzer.tree.Packages, rootPkg+"/mocks/core")
+
+	// A module whose path merely extends the root path is vendored, not a
+	// directory of the repository
+	require.True(t, analyzer.ignoredPackage(rootPkg+"/mocks/core"))
+	require.False(t, analyzer.ignoredPackage("github.com/a/bmocks/core"))
+}
+
+func TestAnalyzeChangedPackages_Entrypoints(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"core/core.go":              "package core",
+		"svc/svc.go":                "package svc\n\nimport _ \"github.com/a/b/core\"\n",
+		"cmd/app/main.go":           "package main\n\nimport _ \"github.com/a/b/svc\"\n",
+		"experimental/exp/exp.go":   "package exp\n\nimport _ \"github.com/a/b/core\"\n",
+		"experimental/tool/main.go": "package main\n\nimport _ \"github.com/a/b/experimental/exp\"\n",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Analysis.Entrypoints = []string{"**/cmd/*"}
+
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"core/core.go"})
+	require.NoError(t, err)
+
+	// exp is only reachable from a tool that is not an entrypoint
+	require.Equal(t, []string{rootPkg + "/cmd/app", rootPkg + "/svc"}, result.AffectedPackageNames())
+}
+
+func TestAnalyzeChangedPackages_ModuleDir(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go/go.mod":      "module github.com/a/b\n",
+		"go/core/c.go":   "package core",
+		"go/app/app.go":  "package app\n\nimport _ \"github.com/a/b/core\"\n",
+		"tools/gen.go":   "package tools",
+		"docs/readme.go": "package docs",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Analysis.ModuleDir = "go"
+
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	// Changed files are relative to the repository root
+	result, err := analyzer.AnalyzeChangedPackages([]string{"go/core/c.go", "tools/gen.go"})
+	require.NoError(t, err)
+	require.Len(t, result.Impacts, 1)
+	require.Equal(t, rootPkg+"/core", result.Impacts[0].ChangedPackage)
+	require.Equal(t, []string{rootPkg + "/app"}, result.AffectedPackageNames())
+	require.Empty(t, result.Warnings)
+	require.NotContains(t, analyzer.tree.Packages, rootPkg+"/go/core")
+
+	// Override path prefixes are relative to the repository root as well
+	cfg, err = config.LoadConfigFromReader(strings.NewReader(`
+analysis:
+  module_dir: go
+overrides:
+  - path_prefix: go/core
+    config:
+      critical:
+        packages: ["**/app"]
+`))
+	require.NoError(t, err)
+	analyzer = NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+	result, err = analyzer.AnalyzeChangedPackages([]string{"go/core/c.go"})
+	require.NoError(t, err)
+	require.Equal(t, []string{rootPkg + "/app"}, result.CriticalPackageNames())
+}
+
+func TestFindModuleDir(t *testing.T) {
+	repoPath := writeRepo(t, map[string]string{
+		"services/api/go.mod":        "module github.com/a/api\n",
+		"services/api/core/c.go":     "package core",
+		"services/web/go.mod":        "module github.com/a/web\n",
+		"services/api/vendor/go.mod": "module github.com/v/v\n",
+		"tools/x/y/go.mod":           "module github.com/a/tools\n",
+		"docs/readme.md":             "",
+	})
+
+	for _, tc := range []struct {
+		moduleDir string
+		want      string
+	}{
+		{"services/api", "services/api"},
+		{"services/api/core", "services/api"}, // Upward to the closest ancestor
+		{"", "services/api"},                  // Downward to the shallowest, first in lexical order
+		{"services/web/", "services/web"},
+		{"tools", "tools/x/y"},
+	} {
+		dir, err := FindModuleDir(repoPath, tc.moduleDir)
+		require.NoError(t, err, tc.moduleDir)
+		require.Equal(t, tc.want, dir, tc.moduleDir)
+	}
+
+	_, err := FindModuleDir(repoPath, "docs")
+	require.ErrorContains(t, err, "failed to find go.mod")
+}
+
+func TestAnalyzeChangedPackages_DeprecatedImports(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod":          "module github.com/a/b\n",
+		"legacy/db/db.go": "package db",
+		"legacy/orm/o.go": "package orm\n\nimport _ \"github.com/a/b/legacy/db\"\n",
+		"store/store.go":  "package store\n\nimport _ \"github.com/a/b/legacy/db\"\n",
+		"api/api.go":      "package api\n\nimport _ \"github.com/a/b/legacy/orm\"\n",
+		"service/svc.go":  "package service\n\nimport _ \"github.com/a/b/store\"\n",
+		"unchanged/u.go":  "package unchanged\n\nimport _ \"github.com/a/b/legacy/db\"\n",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Policy.DeprecatedPackages = []string{"github.com/a/b/legacy/**"}
+
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"store/store.go", "api/api.go", "legacy/orm/o.go", "service/svc.go"})
+	require.NoError(t, err)
+	require.Equal(t, []*DeprecatedImport{
+		{Importer: rootPkg + "/api", Imported: rootPkg + "/legacy/orm"},
+		{Importer: rootPkg + "/store", Imported: rootPkg + "/legacy/db"},
+	}, result.DeprecatedImports, "deprecated packages importing one another and unchanged importers are not flagged")
+	require.Contains(t, result.String(), "### 📛 Deprecated Imports (2)")
+	require.Contains(t, result.String(), "- `github.com/a/b/store` imports `github.com/a/b/legacy/db`\n")
+}
+
+func TestAnalyzeChangedPackages_ChangedAreas(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"main.go":              "package b",
+		"internal/db/db.go":    "package db",
+		"internal/db/sql/s.go": "package sql",
+		"internal/cache/c.go":  "package cache",
+		"cmd/app/main.go":      "package main",
+		"api/api.go":           "package api",
+	})
+
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{
+		"main.go", "internal/db/db.go", "internal/db/sql/s.go", "internal/cache/c.go", "cmd/app/main.go", "api/api.go",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []*AreaCount{
+		{Area: "internal", Packages: 3},
+		{Area: "(root)", Packages: 1},
+		{Area: "api", Packages: 1},
+		{Area: "cmd", Packages: 1},
+	}, result.ChangedAreas)
+
+	require.Contains(t, result.String(), "| Area | Changed packages |\n|------|-----------------:|\n| `internal` | 3 |\n| `(root)` | 1 |\n| `api` | 1 |\n| `cmd` | 1 |\n")
+}
+
+func TestAnalyzeChangedPackages_Deadline(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"a/a.go": "package a",
+		"b/b.go": "package b\n\nimport _ \"github.com/a/b/a\"\n",
+		"c/c.go": "package c",
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetContext(ctx)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"a/a.go"})
+	require.NoError(t, err)
+	require.Equal(t, &Incompleteness{Resolved: 0, Total: 3}, result.Incomplete)
+	require.Contains(t, result.String(), "**Analysis incomplete** (deadline reached after resolving 0 of 3 packages)")
+	require.Contains(t, result.Text(), "Analysis incomplete: deadline reached after resolving 0 of 3 packages.")
+	require.False(t, analyzer.tree.Frozen())
+
+	// Given time, the same analyzer completes the resolution
+	analyzer.SetContext(context.Background())
+	result, err = analyzer.AnalyzeChangedPackages([]string{"a/a.go"})
+	require.NoError(t, err)
+	require.Nil(t, result.Incomplete)
+	require.Equal(t, rootPkg+"/b", result.Impacts[0].AffectedPackages[0].Name)
+}
+
+func TestAnalyzeChangedPackages_ImpactObserver(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"a/a.go": "package a",
+		"b/b.go": "package b\n\nimport _ \"github.com/a/b/a\"\n",
+		"c/c.go": "package c\n\nimport _ \"github.com/a/b/b\"\n",
+		"d/d.go": "package d",
+	})
+
+	var observed []*PackageImpact
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetRootPackage(rootPkg)
+	analyzer.SetImpactObserver(func(impact *PackageImpact) { observed = append(observed, impact) })
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"d/d.go", "b/b.go", "a/a.go"})
+	require.NoError(t, err)
+
+	// Every impact is observed once, in sorted order, as returned
+	require.Equal(t, result.Impacts, observed)
+	var names []string
+	for _, impact := range observed {
+		names = append(names, impact.ChangedPackage)
+	}
+	require.Equal(t, []string{rootPkg + "/a", rootPkg + "/b", rootPkg + "/d"}, names)
+	require.Len(t, observed[0].AffectedPackages, 2)
+}
+
+func TestAnalyzeChangedPackages_LoadErrors(t *testing.T) {
+	// b has a file with a malformed import; its other file still imports d. e
+	// also imports a path outside the repository, which is refused, not parsed.
+	rootPkg := "example.com/m"
+	imports := fmt.Sprintf("import \"%s/d\"\n\nfunc F() { d.D() }", rootPkg)
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod":   "module " + rootPkg,
+		"d/d.go":   "package d\n\nfunc D() {}",
+		"b/b.go":   "package b\n\n" + imports,
+		"b/bad.go": "package b\n\nimport \"unterminated\n",
+		"c/c.go":   "package c\n\n" + imports,
+		"e/e.go":   "package e\n\nimport _ \"example.com/m/../secret\"\n\n" + imports,
+	})
+
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"d/d.go"})
+	require.NoError(t, err)
+	require.Equal(t, []string{rootPkg + "/b", rootPkg + "/c", rootPkg + "/e"}, result.AffectedPackageNames())
+	require.Equal(t, 1, result.LoadErrors, "only parse failures are load errors")
+	require.Len(t, result.Warnings, 2)
+	require.Contains(t, result.Warnings[0], "failed to parse some files of "+rootPkg+"/b")
+
+	require.Contains(t, result.String(), "**1 packages had load errors**; impact may be incomplete.")
+	require.Contains(t, result.Text(), "1 packages had load errors; impact may be incomplete.")
+}
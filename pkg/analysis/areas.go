@@ -0,0 +1,51 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+)
+
+// rootArea names the area of the module root package
+const rootArea = "(root)"
+
+// AreaCount is the number of changed packages in a top-level area of the
+// module, the first path segment after the module path
+type AreaCount struct {
+	Area     string
+	Packages int
+}
+
+// changedAreas groups the changed packages by top-level area, ordered by
+// decreasing count, then by area
+func changedAreas(modulePath string, changedPkgs []string) []*AreaCount {
+	counts := make(map[string]int)
+	for _, pkg := range changedPkgs {
+		counts[packageArea(modulePath, pkg)]++
+	}
+
+	areas := make([]*AreaCount, 0, len(counts))
+	for area, n := range counts {
+		areas = append(areas, &AreaCount{Area: area, Packages: n})
+	}
+	sort.Slice(areas, func(i, j int) bool {
+		if areas[i].Packages != areas[j].Packages {
+			return areas[i].Packages > areas[j].Packages
+		}
+		return areas[i].Area < areas[j].Area
+	})
+	return areas
+}
+
+// packageArea returns the top-level area of a package. Packages outside the
+// module, such as vendored ones, are their own area.
+func packageArea(modulePath, pkg string) string {
+	if pkg == modulePath {
+		return rootArea
+	}
+	rel, ok := strings.CutPrefix(pkg, modulePath+"/")
+	if !ok {
+		return pkg
+	}
+	area, _, _ := strings.Cut(rel, "/")
+	return area
+}
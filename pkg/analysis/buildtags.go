@@ -0,0 +1,147 @@
+package analysis
+
+import (
+	"bufio"
+	"go/build/constraint"
+	"os"
+	"slices"
+	"strings"
+)
+
+// ignoreTag is the build tag conventionally used to keep standalone programs,
+// such as code generators, out of the package of their directory
+const ignoreTag = "ignore"
+
+// maxConstraintTags bounds the free tags of a build constraint that
+// excludedByBuildTags enumerates. Larger constraints are assumed buildable.
+const maxConstraintTags = 12
+
+// knownOS and knownArch are the GOOS and GOARCH values known to the Go
+// toolchain
+var (
+	knownOS = map[string]bool{
+		"aix": true, "android": true, "darwin": true, "dragonfly": true, "freebsd": true,
+		"hurd": true, "illumos": true, "ios": true, "js": true, "linux": true, "nacl": true,
+		"netbsd": true, "openbsd": true, "plan9": true, "solaris": true, "wasip1": true,
+		"windows": true, "zos": true,
+	}
+	knownArch = map[string]bool{
+		"386": true, "amd64": true, "amd64p32": true, "arm": true, "armbe": true, "arm64": true,
+		"arm64be": true, "loong64": true, "mips": true, "mipsle": true, "mips64": true,
+		"mips64le": true, "mips64p32": true, "mips64p32le": true, "ppc": true, "ppc64": true,
+		"ppc64le": true, "riscv": true, "riscv64": true, "s390": true, "s390x": true,
+		"sparc": true, "sparc64": true, "wasm": true,
+	}
+)
+
+// platformTag reports whether tag is set by the platform or toolchain a
+// package is built for, rather than by the tags passed to the build
+func platformTag(tag string) bool {
+	switch {
+	case knownOS[tag], knownArch[tag]:
+		return true
+	case tag == "unix", tag == "cgo", tag == "gc", tag == "gccgo":
+		return true
+	}
+	return strings.HasPrefix(tag, "go1.") || strings.HasPrefix(tag, "goexperiment.")
+}
+
+// excludedByBuildTags reports whether the build constraint of the Go file at
+// path is unsatisfiable, whatever the platform: platform tags may take any
+// value, so the files of every platform are analyzed. When tags is nil, other
+// tags may too, except the ignore tag, so only files like those marked
+// "//go:build ignore" are excluded. Otherwise, other tags are set exactly when
+// listed in tags, as with go build -tags.
+func excludedByBuildTags(path string, tags []string) bool {
+	expr := buildConstraint(path)
+	if expr == nil {
+		return false
+	}
+
+	var free []string
+	seen := make(map[string]bool)
+	collectTags(expr, func(tag string) {
+		if seen[tag] {
+			return
+		}
+		seen[tag] = true
+		if platformTag(tag) || (tags == nil && tag != ignoreTag) {
+			free = append(free, tag)
+		}
+	})
+	if len(free) > maxConstraintTags {
+		return false
+	}
+
+	// The file is excluded when no combination of the free tags satisfies the
+	// constraint
+	for set := 0; set < 1<<len(free); set++ {
+		satisfied := expr.Eval(func(tag string) bool {
+			for i, t := range free {
+				if t == tag {
+					return set&(1<<i) != 0
+				}
+			}
+			return slices.Contains(tags, tag)
+		})
+		if satisfied {
+			return false
+		}
+	}
+	return true
+}
+
+// buildConstraint returns the build constraint of the Go file at path, from
+// its //go:build line or else its // +build lines, or nil if it has none
+func buildConstraint(path string) constraint.Expr {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+
+	// Constraints precede the package clause, among blank lines and comments
+	var plusBuild constraint.Expr
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "//") {
+			break
+		}
+		if !constraint.IsGoBuild(line) && !constraint.IsPlusBuild(line) {
+			continue
+		}
+		expr, err := constraint.Parse(line)
+		if err != nil {
+			continue
+		}
+		if constraint.IsGoBuild(line) {
+			return expr
+		}
+		if plusBuild == nil {
+			plusBuild = expr
+		} else {
+			plusBuild = &constraint.AndExpr{X: plusBuild, Y: expr}
+		}
+	}
+	return plusBuild
+}
+
+// collectTags calls fn for every tag of expr
+func collectTags(expr constraint.Expr, fn func(tag string)) {
+	switch e := expr.(type) {
+	case *constraint.TagExpr:
+		fn(e.Tag)
+	case *constraint.NotExpr:
+		collectTags(e.X, fn)
+	case *constraint.AndExpr:
+		collectTags(e.X, fn)
+		collectTags(e.Y, fn)
+	case *constraint.OrExpr:
+		collectTags(e.X, fn)
+		collectTags(e.Y, fn)
+	}
+}
@@ -0,0 +1,128 @@
+package analysis
+
+import (
+	"encoding/json"
+	"sort"
+)
+
+// MarshalJSON encodes the canonical form of the result, so that the JSON of
+// two runs over the same change is byte-identical
+func (r *AnalysisResult) MarshalJSON() ([]byte, error) {
+	// plain has the fields of AnalysisResult without its methods, avoiding
+	// recursion into MarshalJSON
+	type plain AnalysisResult
+	return json.Marshal((*plain)(canonicalize(r)))
+}
+
+// canonicalize returns a copy of the result in canonical order, leaving r
+// untouched. Every list is ordered by package name, or by module path for
+// dependencies, with a stable secondary key breaking ties:
+//
+//   - impacts by changed package, then previous name
+//   - affected packages by name, then depth; their labels alphabetically
+//   - interface implementers by interface, package, then type
+//   - policy violations by changed package, then critical package
+//   - new dependencies by path, then version
+//   - plain string lists, such as warnings, alphabetically
+//
+// Changed areas keep their documented order, by package count then name, and
+// recent authors theirs, most recent first.
+func canonicalize(r *AnalysisResult) *AnalysisResult {
+	c := *r
+	c.Impacts = make([]*PackageImpact, len(r.Impacts))
+	for i, impact := range r.Impacts {
+		ci := *impact
+		ci.AffectedPackages = make([]*AffectedPackage, len(impact.AffectedPackages))
+		for j, pkg := range impact.AffectedPackages {
+			cp := *pkg
+			cp.Labels = sortedStrings(pkg.Labels)
+			ci.AffectedPackages[j] = &cp
+		}
+		sort.SliceStable(ci.AffectedPackages, func(a, b int) bool {
+			x, y := ci.AffectedPackages[a], ci.AffectedPackages[b]
+			if x.Name != y.Name {
+				return x.Name < y.Name
+			}
+			return x.Depth < y.Depth
+		})
+		ci.AddedImports = sortedStrings(impact.AddedImports)
+		ci.RemovedImports = sortedStrings(impact.RemovedImports)
+		ci.InterfaceImplementers = append([]*InterfaceImplementer(nil), impact.InterfaceImplementers...)
+		sort.SliceStable(ci.InterfaceImplementers, func(a, b int) bool {
+			x, y := ci.InterfaceImplementers[a], ci.InterfaceImplementers[b]
+			if x.Interface != y.Interface {
+				return x.Interface < y.Interface
+			}
+			if x.Package != y.Package {
+				return x.Package < y.Package
+			}
+			return x.Type < y.Type
+		})
+		c.Impacts[i] = &ci
+	}
+	sort.SliceStable(c.Impacts, func(a, b int) bool {
+		x, y := c.Impacts[a], c.Impacts[b]
+		if x.ChangedPackage != y.ChangedPackage {
+			return x.ChangedPackage < y.ChangedPackage
+		}
+		return x.RenamedFrom < y.RenamedFrom
+	})
+
+	c.PolicyViolations = append([]*PolicyViolation(nil), r.PolicyViolations...)
+	sort.SliceStable(c.PolicyViolations, func(a, b int) bool {
+		x, y := c.PolicyViolations[a], c.PolicyViolations[b]
+		if x.ChangedPackage != y.ChangedPackage {
+			return x.ChangedPackage < y.ChangedPackage
+		}
+		return x.CriticalPackage < y.CriticalPackage
+	})
+
+	c.LayerViolations = append([]*LayerViolation(nil), r.LayerViolations...)
+	sort.SliceStable(c.LayerViolations, func(a, b int) bool {
+		x, y := c.LayerViolations[a], c.LayerViolations[b]
+		if x.Importer != y.Importer {
+			return x.Importer < y.Importer
+		}
+		return x.Imported < y.Imported
+	})
+
+	c.DeprecatedImports = append([]*DeprecatedImport(nil), r.DeprecatedImports...)
+	sort.SliceStable(c.DeprecatedImports, func(a, b int) bool {
+		x, y := c.DeprecatedImports[a], c.DeprecatedImports[b]
+		if x.Importer != y.Importer {
+			return x.Importer < y.Importer
+		}
+		return x.Imported < y.Imported
+	})
+
+	c.NewDependencies = append([]*ModRequire(nil), r.NewDependencies...)
+	sort.SliceStable(c.NewDependencies, func(a, b int) bool {
+		x, y := c.NewDependencies[a], c.NewDependencies[b]
+		if x.Path != y.Path {
+			return x.Path < y.Path
+		}
+		return x.Version < y.Version
+	})
+
+	c.DirectDependencies = sortedStrings(r.DirectDependencies)
+	c.IndirectDependencies = sortedStrings(r.IndirectDependencies)
+	c.Warnings = sortedStrings(r.Warnings)
+	c.GoVersionIssues = sortedStrings(r.GoVersionIssues)
+	if r.Delta != nil {
+		delta := *r.Delta
+		delta.NewlyAffected = sortedStrings(r.Delta.NewlyAffected)
+		delta.NoLongerAffected = sortedStrings(r.Delta.NoLongerAffected)
+		c.Delta = &delta
+	}
+	return &c
+}
+
+// sortedStrings returns a sorted copy of list, keeping nil as nil
+func sortedStrings(list []string) []string {
+	if list == nil {
+		return nil
+	}
+	sorted := append([]string{}, list...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"encoding/json"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalysisResult_CanonicalJSON(t *testing.T) {
+	newResult := func() *AnalysisResult {
+		return &AnalysisResult{
+			Impacts: []*PackageImpact{
+				{
+					ChangedPackage: "example.com/m/b",
+					AffectedPackages: []*AffectedPackage{
+						{Name: "example.com/m/y", Depth: 2, Labels: []string{"team:b", "critical"}},
+						{Name: "example.com/m/x", Depth: 1},
+					},
+					AddedImports: []string{"example.com/m/q", "example.com/m/p"},
+				},
+				{ChangedPackage: "example.com/m/a"},
+			},
+			PolicyViolations: []*PolicyViolation{
+				{ChangedPackage: "example.com/m/b", CriticalPackage: "example.com/m/y"},
+				{ChangedPackage: "example.com/m/a", CriticalPackage: "example.com/m/z"},
+			},
+			NewDependencies: []*ModRequire{{Path: "example.com/z", Version: "v1.0.0"}, {Path: "example.com/a", Version: "v0.1.0"}},
+			Warnings:        []string{"w2", "w1"},
+			Delta:           &ImpactDelta{NewlyAffected: []string{"example.com/m/y", "example.com/m/x"}},
+		}
+	}
+
+	canonical, err := json.Marshal(newResult())
+	require.NoError(t, err)
+
+	for i := 0; i < 20; i++ {
+		shuffled := newResult()
+		rand.Shuffle(len(shuffled.Impacts), func(a, b int) {
+			shuffled.Impacts[a], shuffled.Impacts[b] = shuffled.Impacts[b], shuffled.Impacts[a]
+		})
+		affected := shuffled.Impacts[0].AffectedPackages
+		rand.Shuffle(len(affected), func(a, b int) { affected[a], affected[b] = affected[b], affected[a] })
+		rand.Shuffle(len(shuffled.PolicyViolations), func(a, b int) {
+			shuffled.PolicyViolations[a], shuffled.PolicyViolations[b] = shuffled.PolicyViolations[b], shuffled.PolicyViolations[a]
+		})
+		rand.Shuffle(len(shuffled.Warnings), func(a, b int) {
+			shuffled.Warnings[a], shuffled.Warnings[b] = shuffled.Warnings[b], shuffled.Warnings[a]
+		})
+
+		data, err := json.Marshal(shuffled)
+		require.NoError(t, err)
+		require.Equal(t, string(canonical), string(data))
+	}
+
+	// Serializing leaves the result itself untouched
+	result := newResult()
+	_, err = json.Marshal(result)
+	require.NoError(t, err)
+	require.Equal(t, "example.com/m/b", result.Impacts[0].ChangedPackage)
+	require.Equal(t, []string{"w2", "w1"}, result.Warnings)
+}
+
+func TestAnalyzeChangedPackages_DeterministicJSON(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	files := map[string]string{
+		"go.mod": "module " + rootPkg,
+		"a/a.go": "package a",
+		"b/b.go": "package b",
+		"c/c.go": "package c\n\nimport (\n\t_ \"github.com/a/b/a\"\n\t_ \"github.com/a/b/b\"\n)\n",
+		"d/d.go": "package d\n\nimport _ \"github.com/a/b/c\"\n",
+	}
+
+	var first []byte
+	for _, changed := range [][]string{{"a/a.go", "b/b.go"}, {"b/b.go", "a/a.go"}} {
+		analyzer := NewAnalyzer(config.DefaultConfig(), writeRepo(t, files))
+		analyzer.SetRootPackage(rootPkg)
+		result, err := analyzer.AnalyzeChangedPackages(changed)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(result)
+		require.NoError(t, err)
+		if first == nil {
+			first = data
+			continue
+		}
+		require.Equal(t, string(first), string(data))
+	}
+}
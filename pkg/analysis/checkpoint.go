@@ -0,0 +1,296 @@
+package analysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// checkpointVersion is bumped whenever the checkpoint format or the way
+// packages are resolved changes, invalidating older checkpoints
+const checkpointVersion = 4
+
+// checkpointInterval is the number of packages resolved between checkpoints
+const checkpointInterval = 50
+
+// checkpoint is the on-disk form of a partially resolved tree
+type checkpoint struct {
+	Version     int                  `json:"version"`
+	RootPkgPath string               `json:"root_pkg_path"`
+	Config      string               `json:"config"` // See Tree.resolutionDigest
+	Packages    []*checkpointPackage `json:"packages"`
+}
+
+// checkpointPackage is a resolved package. Files are relative to the root
+// directory, so that a checkpoint outlives the checkout it was written from.
+type checkpointPackage struct {
+	Name        string   `json:"name"`
+	PackageName string   `json:"package_name,omitempty"`
+	Internal    bool     `json:"internal"`
+	Target      bool     `json:"target,omitempty"`
+	Files       []string `json:"files,omitempty"`
+	Imports     []string `json:"imports,omitempty"`
+	TestImports []string `json:"test_imports,omitempty"`
+	Failed      string   `json:"failed,omitempty"`       // Resolution error, if any
+	LoadError   string   `json:"load_error,omitempty"`   // See Tree.LoadErrors
+	CaseOnDisk  string   `json:"case_on_disk,omitempty"` // See Tree.CaseMismatches
+	Digest      string   `json:"digest"`                 // Digest of the Go files of the package directory
+}
+
+// SaveCheckpoint writes the packages resolved so far to path, replacing it
+// atomically. Only the packages of completed Resolve calls are written: every
+// import of a checkpointed package is itself checkpointed.
+func (t *Tree) SaveCheckpoint(path string) error {
+	t.mu.RLock()
+	cp := &checkpoint{
+		Version:     checkpointVersion,
+		RootPkgPath: t.RootPkgPath,
+		Config:      t.resolutionDigest(),
+	}
+	for name, pkg := range t.Packages {
+		entry := &checkpointPackage{
+			Name:        name,
+			PackageName: pkg.PackageName,
+			Internal:    pkg.Internal,
+			Target:      pkg.Target,
+			Imports:     pkg.Imports,
+			TestImports: pkg.TestImports,
+			CaseOnDisk:  t.CaseMismatches[name],
+			Digest:      t.digests[name],
+		}
+		for _, file := range pkg.Files {
+			rel, err := filepath.Rel(t.RootDir, file)
+			if err != nil {
+				t.mu.RUnlock()
+				return fmt.Errorf("failed to checkpoint package %s: %w", name, err)
+			}
+			entry.Files = append(entry.Files, filepath.ToSlash(rel))
+		}
+		if err, failed := t.Failed[name]; failed {
+			entry.Failed = err.Error()
+		}
+		if err, ok := t.LoadErrors[name]; ok {
+			entry.LoadError = err.Error()
+		}
+		cp.Packages = append(cp.Packages, entry)
+	}
+	t.mu.RUnlock()
+
+	sort.Slice(cp.Packages, func(i, j int) bool { return cp.Packages[i].Name < cp.Packages[j].Name })
+	for _, entry := range cp.Packages {
+		if entry.Digest != "" {
+			continue
+		}
+		// Without a digest recorded on resolution, the files are read now
+		digest, err := t.packageDigest(entry.Name)
+		if err != nil {
+			return fmt.Errorf("failed to checkpoint package %s: %w", entry.Name, err)
+		}
+		entry.Digest = digest
+	}
+
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	return nil
+}
+
+// LoadCheckpoint restores the packages of the checkpoint at path into the
+// tree, which must not have resolved any package yet, and returns how many
+// were restored. Resolving them again is then a no-op. A missing checkpoint
+// restores nothing. A checkpoint written for another module, version or
+// resolution configuration, or in which any package's files changed since, is
+// discarded as a whole.
+func (t *Tree) LoadCheckpoint(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read checkpoint %s: %w", path, err)
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		zap.S().Warnw("failed to decode checkpoint, resolving from scratch", "path", path, "error", err)
+		return 0, nil
+	}
+	if cp.Version != checkpointVersion || cp.RootPkgPath != t.RootPkgPath || cp.Config != t.resolutionDigest() {
+		zap.S().Infow("checkpoint was written for another configuration, resolving from scratch", "path", path)
+		return 0, nil
+	}
+
+	entries := make(map[string]*checkpointPackage, len(cp.Packages))
+	for _, entry := range cp.Packages {
+		entries[entry.Name] = entry
+	}
+	for _, entry := range cp.Packages {
+		digest, err := t.packageDigest(entry.Name)
+		if err != nil {
+			return 0, fmt.Errorf("failed to validate checkpoint %s: %w", path, err)
+		}
+		if digest != entry.Digest {
+			zap.S().Infow("package changed since the checkpoint, resolving from scratch", "path", path, "package", entry.Name)
+			return 0, nil
+		}
+		for _, imp := range entry.Imports {
+			if entries[imp] == nil {
+				zap.S().Warnw("checkpoint is missing an import, resolving from scratch", "path", path, "package", entry.Name, "import", imp)
+				return 0, nil
+			}
+		}
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.Packages) > 0 || t.frozen {
+		return 0, fmt.Errorf("failed to load checkpoint %s: tree already has resolved packages", path)
+	}
+
+	for _, entry := range cp.Packages {
+		pkg := &Pkg{
+			Name:        entry.Name,
+			PackageName: entry.PackageName,
+			Internal:    entry.Internal,
+			Target:      entry.Target,
+			Files:       make([]string, 0, len(entry.Files)),
+			Imports:     append(make([]string, 0, len(entry.Imports)), entry.Imports...),
+			TestImports: entry.TestImports,
+		}
+		for _, file := range entry.Files {
+			pkg.Files = append(pkg.Files, filepath.Join(t.RootDir, filepath.FromSlash(file)))
+		}
+		t.Packages[entry.Name] = pkg
+		if entry.Failed != "" {
+			t.Failed[entry.Name] = errors.New(entry.Failed)
+		}
+		if entry.LoadError != "" {
+			t.LoadErrors[entry.Name] = errors.New(entry.LoadError)
+		}
+		if entry.CaseOnDisk != "" {
+			t.CaseMismatches[entry.Name] = entry.CaseOnDisk
+		}
+		if t.digests != nil {
+			t.digests[entry.Name] = entry.Digest
+		}
+	}
+
+	// Link dependencies the way Resolve does
+	for _, pkg := range t.Packages {
+		for _, importPath := range pkg.Imports {
+			if _, failed := t.Failed[importPath]; failed {
+				continue
+			}
+			pkg.Dependencies = append(pkg.Dependencies, t.Packages[importPath])
+		}
+	}
+	return len(cp.Packages), nil
+}
+
+// resolutionDigest hashes the settings of the tree that decide which packages
+// are resolved and how, so a checkpoint is only resumed under the same ones
+func (t *Tree) resolutionDigest() string {
+	moduleDirs := make([]string, 0, len(t.ModuleDirs))
+	for modulePath, dir := range t.ModuleDirs {
+		moduleDirs = append(moduleDirs, modulePath+"="+dir)
+	}
+	sort.Strings(moduleDirs)
+
+	h := sha256.New()
+	for _, field := range [][]string{
+		{strconv.FormatBool(t.IncludeTests)},
+		t.BuildTags,
+		{t.TargetMarker},
+		t.AdditionalInternal,
+		moduleDirs,
+		{t.ResolutionKey},
+	} {
+		fmt.Fprintf(h, "%q\x00", field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// trackDigests makes the tree record the digest of each package it resolves
+// from then on, so checkpoints do not read the files of every package again
+func (t *Tree) trackDigests() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.digests == nil {
+		t.digests = make(map[string]string)
+	}
+}
+
+// recordDigest records the digest of a package just resolved, when digests
+// are tracked. The caller must hold the lock.
+func (t *Tree) recordDigest(pkgName string) {
+	if t.digests == nil {
+		return
+	}
+	digest, err := t.packageDigest(pkgName)
+	if err != nil {
+		// SaveCheckpoint tries again
+		zap.S().Debugw("failed to digest package", "package", pkgName, "error", err)
+		return
+	}
+	t.digests[pkgName] = digest
+}
+
+// packageDigest hashes the names and contents of the Go files in the
+// directory of a package. A missing directory has an empty digest.
+func (t *Tree) packageDigest(pkgName string) (string, error) {
+	dir := t.packageDir(pkgName)
+	if !withinDir(t.RootDir, dir) {
+		return "", nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	h := sha256.New()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00", entry.Name())
+		_, err = io.Copy(h, f)
+		f.Close()
+		if err != nil {
+			return "", err
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,142 @@
+package analysis
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+// countdownContext is canceled once Err has been called n times
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+func (c *countdownContext) Err() error {
+	if c.n == 0 {
+		return context.Canceled
+	}
+	c.n--
+	return nil
+}
+
+// graphOf renders the packages of a tree with their files and dependencies
+func graphOf(tree *Tree) map[string][]string {
+	graph := make(map[string][]string)
+	for name, pkg := range tree.Packages {
+		var entries []string
+		entries = append(entries, pkg.Files...)
+		for _, dep := range pkg.Dependencies {
+			entries = append(entries, "-> "+dep.Name)
+		}
+		if _, failed := tree.Failed[name]; failed {
+			entries = append(entries, "failed")
+		}
+		sort.Strings(entries)
+		graph[name] = entries
+	}
+	return graph
+}
+
+func TestResolveAll_ResumesFromCheckpoint(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"a/a.go":      "package a",
+		"b/b.go":      "package b\n\nimport _ \"github.com/a/b/a\"\n",
+		"c/c.go":      "package c\n\nimport (\n\t_ \"github.com/a/b/b\"\n\t_ \"github.com/a/b/missing\"\n)\n",
+		"d/d.go":      "package d\n\nimport _ \"github.com/a/b/c\"\n",
+		"e/e.go":      "package e\n\nimport _ \"github.com/a/b/a\"\n",
+		"broken/x.go": "package broken\n\nimport (\n",
+	})
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	full := NewAnalyzer(config.DefaultConfig(), repoPath)
+	full.SetRootPackage(rootPkg)
+	require.NoError(t, full.resolveAll())
+
+	// The first run is interrupted after resolving three packages
+	interrupted := NewAnalyzer(config.DefaultConfig(), repoPath)
+	interrupted.SetContext(&countdownContext{Context: context.Background(), n: 3})
+	interrupted.SetCheckpoint(checkpointPath)
+	interrupted.SetRootPackage(rootPkg)
+	require.NoError(t, interrupted.resolveAll())
+	require.NotNil(t, interrupted.incomplete)
+	require.FileExists(t, checkpointPath)
+
+	// Digests were recorded as packages were resolved
+	require.Len(t, interrupted.tree.digests, len(interrupted.tree.Packages))
+
+	probe := NewAnalyzer(config.DefaultConfig(), repoPath)
+	probe.SetRootPackage(rootPkg)
+	restored, err := probe.tree.LoadCheckpoint(checkpointPath)
+	require.NoError(t, err)
+	require.Equal(t, len(interrupted.tree.Packages), restored)
+
+	// A run ignoring other packages does not resume
+	otherCfg := config.DefaultConfig()
+	otherCfg.Patterns.IncludePatterns = []string{"github.com/x/**"}
+	other := NewAnalyzer(otherCfg, repoPath)
+	other.SetRootPackage(rootPkg)
+	restored, err = other.tree.LoadCheckpoint(checkpointPath)
+	require.NoError(t, err)
+	require.Zero(t, restored)
+
+	// The next run resumes and ends with the same graph
+	resumed := NewAnalyzer(config.DefaultConfig(), repoPath)
+	resumed.SetCheckpoint(checkpointPath)
+	resumed.SetRootPackage(rootPkg)
+	require.NoError(t, resumed.resolveAll())
+	require.Nil(t, resumed.incomplete)
+	require.Equal(t, graphOf(full.tree), graphOf(resumed.tree))
+	require.NoFileExists(t, checkpointPath)
+}
+
+func TestTreeLoadCheckpoint_InvalidatedByChanges(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"a/a.go": "package a",
+		"b/b.go": "package b\n\nimport _ \"github.com/a/b/a\"\n",
+	})
+	checkpointPath := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	tree := NewTree(repoPath, rootPkg)
+	require.NoError(t, tree.Resolve(rootPkg+"/b"))
+	require.NoError(t, tree.SaveCheckpoint(checkpointPath))
+
+	loaded := NewTree(repoPath, rootPkg)
+	restored, err := loaded.LoadCheckpoint(checkpointPath)
+	require.NoError(t, err)
+	require.Equal(t, 2, restored)
+	require.Equal(t, "b", loaded.Packages[rootPkg+"/b"].PackageName)
+
+	// Adding a file to a resolved package discards the whole checkpoint
+	require.NoError(t, os.WriteFile(filepath.Join(repoPath, "a", "extra.go"), []byte("package a\n\nimport _ \"github.com/a/b/c\"\n"), 0644))
+	fresh := NewTree(repoPath, rootPkg)
+	restored, err = fresh.LoadCheckpoint(checkpointPath)
+	require.NoError(t, err)
+	require.Zero(t, restored)
+	require.Empty(t, fresh.Packages)
+
+	// So does a checkpoint of another module
+	restored, err = NewTree(repoPath, "github.com/other/repo").LoadCheckpoint(checkpointPath)
+	require.NoError(t, err)
+	require.Zero(t, restored)
+
+	// Or one written under other resolution settings
+	for _, configure := range []func(*Tree){
+		func(tree *Tree) { tree.AdditionalInternal = []string{"github.com/x/"} },
+		func(tree *Tree) { tree.TargetMarker = ".target" },
+		func(tree *Tree) { tree.ResolutionKey = "other" },
+	} {
+		tree := NewTree(repoPath, rootPkg)
+		configure(tree)
+		restored, err = tree.LoadCheckpoint(checkpointPath)
+		require.NoError(t, err)
+		require.Zero(t, restored)
+	}
+}
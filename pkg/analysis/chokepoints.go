@@ -0,0 +1,113 @@
+package analysis
+
+import (
+	"sort"
+)
+
+// Chokepoint is a package that dependency paths funnel through
+type Chokepoint struct {
+	Name       string
+	Dependents int // Packages that depend on it, directly or transitively
+	Depends    int // Packages it depends on, directly or transitively
+	Pairs      int // (dependent, dependency) pairs connected through it
+}
+
+// Chokepoints ranks the packages of the tree by the number of reachable
+// (source, target) pairs routed through them: a package lies on a dependency
+// path from every package that depends on it to every package it depends on.
+// Packages that route no pair are omitted. A positive n limits the result to
+// the top n packages; ties are ordered by name.
+func (t *Tree) Chokepoints(n int) []*Chokepoint {
+	reverse := t.reverseDependencyIndex()
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var chokepoints []*Chokepoint
+	for name, pkg := range t.Packages {
+		dependents := len(reverseDepths(reverse, name, 0))
+		depends := len(t.transitiveDependencies(pkg))
+		if dependents == 0 || depends == 0 {
+			continue
+		}
+		chokepoints = append(chokepoints, &Chokepoint{
+			Name:       name,
+			Dependents: dependents,
+			Depends:    depends,
+			Pairs:      dependents * depends,
+		})
+	}
+
+	sort.Slice(chokepoints, func(i, j int) bool {
+		if chokepoints[i].Pairs != chokepoints[j].Pairs {
+			return chokepoints[i].Pairs > chokepoints[j].Pairs
+		}
+		return chokepoints[i].Name < chokepoints[j].Name
+	})
+	if n > 0 && len(chokepoints) > n {
+		chokepoints = chokepoints[:n]
+	}
+	return chokepoints
+}
+
+// ForwardClosure returns the names of the given packages and of every package
+// they depend on, directly or transitively
+func (t *Tree) ForwardClosure(roots []string) map[string]bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	closure := make(map[string]bool)
+	for _, name := range roots {
+		pkg, ok := t.Packages[name]
+		if !ok || closure[name] {
+			continue
+		}
+		closure[name] = true
+		for dep := range t.transitiveDependencies(pkg) {
+			closure[dep] = true
+		}
+	}
+	return closure
+}
+
+// transitiveDependencies returns the names of every package pkg depends on,
+// directly or transitively
+func (t *Tree) transitiveDependencies(pkg *Pkg) map[string]bool {
+	seen := make(map[string]bool)
+	queue := []*Pkg{pkg}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range current.Dependencies {
+			if dep.Name == pkg.Name || seen[dep.Name] {
+				continue
+			}
+			seen[dep.Name] = true
+			queue = append(queue, dep)
+		}
+	}
+	return seen
+}
+
+// Chokepoints resolves the whole repository and returns its top n chokepoint
+// packages, leaving out ignored packages
+func (a *Analyzer) Chokepoints(n int) ([]*Chokepoint, error) {
+	if a.tree == nil {
+		return nil, ErrNoRootPackage
+	}
+	if err := a.resolveAll(); err != nil {
+		return nil, err
+	}
+
+	var chokepoints []*Chokepoint
+	for _, c := range a.tree.Chokepoints(0) {
+		if a.cfg.ShouldIgnorePackage(c.Name) {
+			continue
+		}
+		chokepoints = append(chokepoints, c)
+		if n > 0 && len(chokepoints) == n {
+			break
+		}
+	}
+	return chokepoints, nil
+}
@@ -0,0 +1,29 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeChokepoints(t *testing.T) {
+	// Diamond: app imports b and c, which both import d, which imports log
+	tree := newTestTree(map[string][]string{
+		"example.com/m/app": {"example.com/m/b", "example.com/m/c"},
+		"example.com/m/b":   {"example.com/m/d"},
+		"example.com/m/c":   {"example.com/m/d"},
+		"example.com/m/d":   {"example.com/m/log"},
+		"example.com/m/log": nil,
+	})
+
+	chokepoints := tree.Chokepoints(0)
+	require.Equal(t, []*Chokepoint{
+		{Name: "example.com/m/d", Dependents: 3, Depends: 1, Pairs: 3},
+		{Name: "example.com/m/b", Dependents: 1, Depends: 2, Pairs: 2},
+		{Name: "example.com/m/c", Dependents: 1, Depends: 2, Pairs: 2},
+	}, chokepoints, "the top and bottom of the graph route no pairs")
+
+	top := tree.Chokepoints(1)
+	require.Len(t, top, 1)
+	require.Equal(t, "example.com/m/d", top[0].Name)
+}
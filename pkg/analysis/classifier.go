@@ -0,0 +1,96 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+)
+
+// Labels attached by the built-in classifiers
+const (
+	LabelCritical  = "critical"
+	LabelHighLevel = "high-level"
+)
+
+// Classifier attaches labels to an affected package. Labels are arbitrary
+// strings; the analyzer only interprets those of the built-in classifiers.
+type Classifier interface {
+	Classify(pkg *Pkg) []string
+}
+
+// ClassifierFunc adapts a function to the Classifier interface
+type ClassifierFunc func(pkg *Pkg) []string
+
+// Classify calls f(pkg)
+func (f ClassifierFunc) Classify(pkg *Pkg) []string {
+	return f(pkg)
+}
+
+// criticalClassifier labels the critical packages of the configuration
+type criticalClassifier struct {
+	cfg *config.Config
+}
+
+func (c criticalClassifier) Classify(pkg *Pkg) []string {
+	if c.cfg.IsCriticalPackage(pkg.Name) {
+		return []string{LabelCritical}
+	}
+	return nil
+}
+
+// highLevelClassifier labels the high-level packages of the configuration
+type highLevelClassifier struct {
+	cfg *config.Config
+}
+
+func (c highLevelClassifier) Classify(pkg *Pkg) []string {
+	if isHighLevel(c.cfg, pkg) {
+		return []string{LabelHighLevel}
+	}
+	return nil
+}
+
+// isHighLevel reports whether a package is high-level: it matches a
+// high-level pattern, holds the target marker file or, when mains are
+// high-level, is a main package
+func isHighLevel(cfg *config.Config, pkg *Pkg) bool {
+	return cfg.IsHighLevelPackage(pkg.Name) || pkg.Target || (cfg.Analysis.MainsAreHighLevel && pkg.PackageName == "main")
+}
+
+// classify returns the sorted, distinct labels of pkg across all classifiers
+func classify(classifiers []Classifier, pkg *Pkg) []string {
+	seen := make(map[string]bool)
+	var labels []string
+	for _, classifier := range classifiers {
+		for _, label := range classifier.Classify(pkg) {
+			if !seen[label] {
+				seen[label] = true
+				labels = append(labels, label)
+			}
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// hasLabel reports whether labels contains label
+func hasLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// customLabels returns the labels not attached by the built-in classifiers,
+// whose meaning is already conveyed elsewhere in the report
+func customLabels(labels []string) []string {
+	var custom []string
+	for _, label := range labels {
+		if label != LabelCritical && label != LabelHighLevel {
+			custom = append(custom, label)
+		}
+	}
+	return custom
+}
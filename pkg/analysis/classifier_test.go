@@ -0,0 +1,118 @@
+package analysis
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzer_AddClassifier(t *testing.T) {
+	// d is imported by lib and core, and lib is imported by the cmd tool
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod":           "module " + rootPkg,
+		"d/d.go":           "package d\n\nfunc D() {}",
+		"lib/lib.go":       fmt.Sprintf("package lib\n\nimport \"%s/d\"\n\nfunc L() { d.D() }", rootPkg),
+		"core/core.go":     fmt.Sprintf("package core\n\nimport \"%s/d\"\n\nfunc C() { d.D() }", rootPkg),
+		"cmd/tool/main.go": fmt.Sprintf("package main\n\nimport \"%s/lib\"\n\nfunc main() { lib.L() }", rootPkg),
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Critical.Packages = config.CriticalPatterns(rootPkg + "/core")
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	// A package is important when a command imports it
+	analyzer.AddClassifier(ClassifierFunc(func(pkg *Pkg) []string {
+		for _, importer := range analyzer.tree.FindReverseDependencies(pkg.Name) {
+			if importer.Name == rootPkg+"/cmd/tool" {
+				return []string{"used-by-cmd"}
+			}
+		}
+		return nil
+	}))
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"d/d.go"})
+	require.NoError(t, err)
+	require.Len(t, result.Impacts, 1)
+
+	labels := make(map[string][]string)
+	for _, pkg := range result.Impacts[0].AffectedPackages {
+		labels[pkg.Name] = pkg.Labels
+	}
+	require.Equal(t, []string{LabelHighLevel}, labels[rootPkg+"/cmd/tool"])
+	require.Equal(t, []string{LabelCritical, LabelHighLevel}, labels[rootPkg+"/core"])
+	require.Equal(t, []string{LabelHighLevel, "used-by-cmd"}, labels[rootPkg+"/lib"])
+
+	report := result.String()
+	require.Contains(t, report, "- `"+rootPkg+"/lib` `used-by-cmd` — directly affected")
+	require.Contains(t, report, "- 🚨 **`"+rootPkg+"/core`** (Critical) — directly affected")
+	require.NotContains(t, report, "`"+LabelHighLevel+"`", "built-in labels are not repeated")
+}
+
+func TestAnalyzeChangedPackages_MainsAreHighLevel(t *testing.T) {
+	// d is imported by lib and by the cmd/server main, and no pattern but an
+	// unrelated one is high-level
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod":             "module " + rootPkg,
+		"d/d.go":             "package d\n\nfunc D() {}",
+		"lib/lib.go":         fmt.Sprintf("package lib\n\nimport \"%s/d\"\n\nfunc L() { d.D() }", rootPkg),
+		"cmd/server/main.go": fmt.Sprintf("package main\n\nimport \"%s/d\"\n\nfunc main() { d.D() }", rootPkg),
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Targets.HighLevelPackages = []string{"**/api"}
+	cfg.Analysis.MainsAreHighLevel = true
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"d/d.go"})
+	require.NoError(t, err)
+	require.Equal(t, []string{rootPkg + "/cmd/server"}, result.AffectedPackageNames())
+	require.Equal(t, "main", analyzer.tree.Packages[rootPkg+"/cmd/server"].PackageName)
+	require.Equal(t, "lib", analyzer.tree.Packages[rootPkg+"/lib"].PackageName)
+
+	// Without the option, nothing is high-level
+	cfg.Analysis.MainsAreHighLevel = false
+	analyzer = NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+	result, err = analyzer.AnalyzeChangedPackages([]string{"d/d.go"})
+	require.NoError(t, err)
+	require.Empty(t, result.AffectedPackageNames())
+}
+
+func TestAnalyzeChangedPackages_TargetMarker(t *testing.T) {
+	// d is imported by api, lib and tools; api holds the default marker and
+	// tools a custom one, and no pattern matches them
+	rootPkg := "github.com/a/b"
+	imports := fmt.Sprintf("import \"%s/d\"\n\nfunc F() { d.D() }", rootPkg)
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod":                            "module " + rootPkg,
+		"d/d.go":                            "package d\n\nfunc D() {}",
+		"api/api.go":                        "package api\n\n" + imports,
+		"api/" + config.DefaultTargetMarker: "",
+		"lib/lib.go":                        "package lib\n\n" + imports,
+		"tools/tools.go":                    "package tools\n\n" + imports,
+		"tools/TARGET":                      "",
+	})
+
+	analyze := func(marker string) []string {
+		cfg := config.DefaultConfig()
+		cfg.Targets.HighLevelPackages = []string{"**/cmd/*"}
+		if marker != config.DefaultTargetMarker {
+			cfg.Targets.MarkerFile = marker
+		}
+		analyzer := NewAnalyzer(cfg, repoPath)
+		analyzer.SetRootPackage(rootPkg)
+		result, err := analyzer.AnalyzeChangedPackages([]string{"d/d.go"})
+		require.NoError(t, err)
+		return result.AffectedPackageNames()
+	}
+
+	require.Equal(t, []string{rootPkg + "/api"}, analyze(config.DefaultTargetMarker))
+	require.Equal(t, []string{rootPkg + "/tools"}, analyze("TARGET"))
+	require.Empty(t, analyze(""), "markers are disabled")
+}
@@ -0,0 +1,60 @@
+package analysis
+
+import "sort"
+
+// Snapshot is the part of an analysis result remembered between runs, so a
+// later run can report how the impact changed
+type Snapshot struct {
+	Affected []string `json:"affected"`           // Sorted distinct affected packages
+	Critical []string `json:"critical,omitempty"` // Sorted distinct critical affected packages
+}
+
+// ImpactDelta is how the affected packages changed since a previous analysis
+type ImpactDelta struct {
+	NewlyAffected    []string
+	NoLongerAffected []string
+	Critical         map[string]bool // Critical packages among both lists
+}
+
+// Snapshot returns the snapshot of the result
+func (r *AnalysisResult) Snapshot() *Snapshot {
+	return &Snapshot{
+		Affected: r.AffectedPackageNames(),
+		Critical: r.CriticalPackageNames(),
+	}
+}
+
+// DeltaSince returns how the affected packages of the result differ from
+// those of a previous snapshot
+func (r *AnalysisResult) DeltaSince(prev *Snapshot) *ImpactDelta {
+	delta := &ImpactDelta{Critical: make(map[string]bool)}
+	current := r.Snapshot()
+
+	before := make(map[string]bool, len(prev.Affected))
+	for _, name := range prev.Affected {
+		before[name] = true
+	}
+	after := make(map[string]bool, len(current.Affected))
+	for _, name := range current.Affected {
+		after[name] = true
+		if !before[name] {
+			delta.NewlyAffected = append(delta.NewlyAffected, name)
+		}
+	}
+	for _, name := range prev.Affected {
+		if !after[name] {
+			delta.NoLongerAffected = append(delta.NoLongerAffected, name)
+		}
+	}
+	sort.Strings(delta.NoLongerAffected)
+
+	for _, name := range append(prev.Critical, current.Critical...) {
+		delta.Critical[name] = true
+	}
+	return delta
+}
+
+// Empty reports whether the affected packages are unchanged
+func (d *ImpactDelta) Empty() bool {
+	return len(d.NewlyAffected) == 0 && len(d.NoLongerAffected) == 0
+}
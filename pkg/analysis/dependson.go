@@ -0,0 +1,32 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DependsOn resolves the whole repository and returns the sorted internal
+// packages that package name depends on, directly or transitively, leaving out
+// ignored packages. The package may be given relative to the root package.
+func (a *Analyzer) DependsOn(name string) ([]string, error) {
+	if a.tree == nil {
+		return nil, ErrNoRootPackage
+	}
+	if err := a.resolveAll(); err != nil {
+		return nil, err
+	}
+
+	name = a.qualifyPackage(name)
+	if _, ok := a.tree.Packages[name]; !ok {
+		return nil, fmt.Errorf("unknown package %s", name)
+	}
+
+	var deps []string
+	for dep := range a.tree.ForwardClosure([]string{name}) {
+		if dep != name && a.tree.Packages[dep].Internal && !a.cfg.ShouldIgnorePackage(dep) {
+			deps = append(deps, dep)
+		}
+	}
+	sort.Strings(deps)
+	return deps, nil
+}
@@ -0,0 +1,35 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzerDependsOn(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	// A chain a → b → c → d, with d importing b back and e unrelated, and c
+	// importing an external package
+	repoPath := writeRepo(t, map[string]string{
+		"a/a.go": "package a\n\nimport _ \"github.com/a/b/b\"\n",
+		"b/b.go": "package b\n\nimport (\n\t_ \"fmt\"\n\t_ \"github.com/a/b/c\"\n)\n",
+		"c/c.go": "package c\n\nimport (\n\t_ \"github.com/a/b/d\"\n\t_ \"github.com/x/y\"\n)\n",
+		"d/d.go": "package d\n\nimport _ \"github.com/a/b/b\"\n",
+		"e/e.go": "package e\n\nimport _ \"github.com/a/b/a\"\n",
+	})
+
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	deps, err := analyzer.DependsOn("a")
+	require.NoError(t, err)
+	require.Equal(t, []string{rootPkg + "/b", rootPkg + "/c", rootPkg + "/d"}, deps)
+
+	deps, err = analyzer.DependsOn(rootPkg + "/c")
+	require.NoError(t, err)
+	require.Equal(t, []string{rootPkg + "/b", rootPkg + "/d"}, deps, "the cycle back to c is not listed")
+
+	_, err = analyzer.DependsOn("missing")
+	require.ErrorContains(t, err, "unknown package")
+}
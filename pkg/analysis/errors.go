@@ -0,0 +1,12 @@
+package analysis
+
+import "errors"
+
+// ErrNoRootPackage is returned by the analyses run before SetRootPackage
+var ErrNoRootPackage = errors.New("analyzer not initialized with root package")
+
+// ErrNoChangedPackages reports that no changed file belongs to a package. The
+// analyses return their empty result instead, which may still hold new
+// dependencies and warnings; callers that need an error check
+// AnalysisResult.Empty and return this one.
+var ErrNoChangedPackages = errors.New("no changed packages")
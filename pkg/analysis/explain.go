@@ -0,0 +1,88 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ImportPaths returns the distinct import chains from package from to package
+// to, each listing the packages from the importer to the imported one. Chains
+// never visit a package twice and are enumerated depth-first in name order. A
+// positive limit caps the number of chains returned; truncated reports whether
+// more exist.
+func (t *Tree) ImportPaths(from, to string, limit int) (paths [][]string, truncated bool) {
+	// Only packages importing to, directly or not, can continue a chain, so
+	// the search never enters a branch without one
+	reaching := reverseDepths(t.reverseDependencyIndex(), to, 0)
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	start, ok := t.Packages[from]
+	if !ok {
+		return nil, false
+	}
+
+	onPath := map[string]bool{from: true}
+	chain := []string{from}
+
+	var visit func(pkg *Pkg) bool
+	visit = func(pkg *Pkg) bool {
+		deps := append([]*Pkg(nil), pkg.Dependencies...)
+		sort.Slice(deps, func(i, j int) bool {
+			return deps[i].Name < deps[j].Name
+		})
+
+		for _, dep := range deps {
+			if onPath[dep.Name] {
+				continue
+			}
+			if _, ok := reaching[dep.Name]; !ok && dep.Name != to {
+				continue
+			}
+			if dep.Name == to {
+				if limit > 0 && len(paths) == limit {
+					truncated = true
+					return false
+				}
+				paths = append(paths, append(append([]string(nil), chain...), to))
+				continue
+			}
+
+			onPath[dep.Name] = true
+			chain = append(chain, dep.Name)
+			more := visit(dep)
+			chain = chain[:len(chain)-1]
+			delete(onPath, dep.Name)
+			if !more {
+				return false
+			}
+		}
+		return true
+	}
+	if from != to {
+		visit(start)
+	}
+	return paths, truncated
+}
+
+// ImportPaths resolves the whole repository and returns the import chains from
+// package from to package to, which may be given relative to the root package
+func (a *Analyzer) ImportPaths(from, to string, limit int) ([][]string, bool, error) {
+	if a.tree == nil {
+		return nil, false, ErrNoRootPackage
+	}
+	if err := a.resolveAll(); err != nil {
+		return nil, false, err
+	}
+
+	from, to = a.qualifyPackage(from), a.qualifyPackage(to)
+	for _, name := range []string{from, to} {
+		if _, ok := a.tree.Packages[name]; !ok {
+			return nil, false, fmt.Errorf("unknown package %s", name)
+		}
+	}
+
+	paths, truncated := a.tree.ImportPaths(from, to, limit)
+	return paths, truncated, nil
+}
@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeImportPaths_Diamond(t *testing.T) {
+	// a imports b and c, which both import d
+	tree := newTestTree(map[string][]string{
+		"example.com/m/a": {"example.com/m/c", "example.com/m/b"},
+		"example.com/m/b": {"example.com/m/d"},
+		"example.com/m/c": {"example.com/m/d"},
+		"example.com/m/d": nil,
+	})
+
+	paths, truncated := tree.ImportPaths("example.com/m/a", "example.com/m/d", 0)
+	require.False(t, truncated)
+	require.Equal(t, [][]string{
+		{"example.com/m/a", "example.com/m/b", "example.com/m/d"},
+		{"example.com/m/a", "example.com/m/c", "example.com/m/d"},
+	}, paths)
+
+	paths, truncated = tree.ImportPaths("example.com/m/a", "example.com/m/d", 1)
+	require.True(t, truncated)
+	require.Len(t, paths, 1)
+
+	paths, _ = tree.ImportPaths("example.com/m/d", "example.com/m/a", 0)
+	require.Empty(t, paths)
+}
+
+func TestTreeImportPaths_WideLattice(t *testing.T) {
+	// a imports every package of the first of 30 layers of 4 packages, each
+	// importing every package of the next layer, and reaches z only through
+	// y: the lattice holds 4^30 chains, none of them leading to z
+	const layers, width = 30, 4
+	graph := map[string][]string{"example.com/m/a": {"example.com/m/y"}, "example.com/m/y": {"example.com/m/z"}, "example.com/m/z": nil}
+	layer := func(i int) []string {
+		var names []string
+		for j := 0; j < width; j++ {
+			names = append(names, fmt.Sprintf("example.com/m/l%d/p%d", i, j))
+		}
+		return names
+	}
+	graph["example.com/m/a"] = append(graph["example.com/m/a"], layer(0)...)
+	for i := 0; i < layers; i++ {
+		for _, name := range layer(i) {
+			if i+1 < layers {
+				graph[name] = layer(i + 1)
+			} else {
+				graph[name] = nil
+			}
+		}
+	}
+	tree := newTestTree(graph)
+
+	paths, truncated := tree.ImportPaths("example.com/m/a", "example.com/m/z", 0)
+	require.False(t, truncated)
+	require.Equal(t, [][]string{{"example.com/m/a", "example.com/m/y", "example.com/m/z"}}, paths)
+
+	// Chains through the lattice stop at the limit
+	paths, truncated = tree.ImportPaths("example.com/m/a", "example.com/m/l29/p0", 3)
+	require.True(t, truncated)
+	require.Len(t, paths, 3)
+}
+
+func TestAnalyzerImportPaths(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"a/a.go": "package a\n\nimport (\n\t_ \"github.com/a/b/b\"\n\t_ \"github.com/a/b/c\"\n)\n",
+		"b/b.go": "package b\n\nimport _ \"github.com/a/b/c\"\n",
+		"c/c.go": "package c",
+	})
+
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	paths, truncated, err := analyzer.ImportPaths("a", "c", 10)
+	require.NoError(t, err)
+	require.False(t, truncated)
+	require.Equal(t, [][]string{
+		{rootPkg + "/a", rootPkg + "/b", rootPkg + "/c"},
+		{rootPkg + "/a", rootPkg + "/c"},
+	}, paths)
+
+	_, _, err = analyzer.ImportPaths("a", "missing", 10)
+	require.ErrorContains(t, err, "unknown package missing")
+}
@@ -0,0 +1,176 @@
+package analysis
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ModFile holds the directives of a go.mod file relevant to the analysis
+type ModFile struct {
+	Module    string // Module path
+	GoVersion string // Version of the go directive (e.g., "1.21"), empty if absent
+	Requires  []*ModRequire
+	Replaces  []string // Module paths replaced by a replace directive
+}
+
+// ModRequire is a module listed in a require directive
+type ModRequire struct {
+	Path     string
+	Version  string
+	Indirect bool // Marked with an "// indirect" comment
+}
+
+// ReadModFile parses the go.mod file in dir
+func ReadModFile(dir string) (*ModFile, error) {
+	content, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	return ParseModFile(content)
+}
+
+// FindModuleDir locates the go.mod of the module in the repository at
+// repoPath, given the module directory the configuration expects (relative to
+// the repository root, "" for the root). It returns the directory holding the
+// nearest go.mod, in the same form: moduleDir itself or its closest ancestor
+// with one, or else the shallowest directory below moduleDir with one, the
+// first in lexical order among equally deep ones. Directories the go command
+// ignores, such as vendor, testdata and those starting with "." or "_", are not
+// searched.
+func FindModuleDir(repoPath, moduleDir string) (string, error) {
+	moduleDir = path.Clean("/" + filepath.ToSlash(moduleDir))[1:]
+	hasModFile := func(dir string) bool {
+		info, err := os.Stat(filepath.Join(repoPath, filepath.FromSlash(dir), "go.mod"))
+		return err == nil && !info.IsDir()
+	}
+
+	for dir := moduleDir; ; dir = path.Dir(dir) {
+		if dir == "." {
+			dir = ""
+		}
+		if hasModFile(dir) {
+			return dir, nil
+		}
+		if dir == "" {
+			break
+		}
+	}
+
+	found, foundDepth := "", -1
+	searchRoot := filepath.Join(repoPath, filepath.FromSlash(moduleDir))
+	err := filepath.WalkDir(searchRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.IsDir() || p == searchRoot {
+			return nil
+		}
+		name := d.Name()
+		if name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(repoPath, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		depth := strings.Count(rel, "/")
+		if foundDepth >= 0 && depth >= foundDepth {
+			return filepath.SkipDir
+		}
+		if hasModFile(rel) {
+			found, foundDepth = rel, depth
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to search for go.mod: %w", err)
+	}
+	if foundDepth < 0 {
+		return "", fmt.Errorf("failed to find go.mod in %s, its parents or its subdirectories", filepath.Join(repoPath, filepath.FromSlash(moduleDir)))
+	}
+	return found, nil
+}
+
+// ParseModFile extracts the module path, go directive, requirements and
+// replaced module paths from go.mod content. Other directives are skipped.
+func ParseModFile(content []byte) (*ModFile, error) {
+	mod := &ModFile{}
+	block := ""
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		line, comment, _ := strings.Cut(scanner.Text(), "//")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		// Directives may be grouped in parenthesized blocks
+		if block != "" {
+			if fields[0] == ")" {
+				block = ""
+				continue
+			}
+			fields = append([]string{block}, fields...)
+		} else if len(fields) == 2 && fields[1] == "(" {
+			block = fields[0]
+			continue
+		}
+
+		switch {
+		case fields[0] == "module" && len(fields) == 2:
+			mod.Module = unquoteModField(fields[1])
+		case fields[0] == "go" && len(fields) == 2:
+			mod.GoVersion = fields[1]
+		case fields[0] == "require" && len(fields) == 3:
+			mod.Requires = append(mod.Requires, &ModRequire{
+				Path:     unquoteModField(fields[1]),
+				Version:  fields[2],
+				Indirect: strings.HasPrefix(strings.TrimSpace(comment), "indirect"),
+			})
+		case fields[0] == "replace" && len(fields) >= 4:
+			mod.Replaces = append(mod.Replaces, unquoteModField(fields[1]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse go.mod: %w", err)
+	}
+	if mod.Module == "" {
+		return nil, fmt.Errorf("failed to parse go.mod: no module directive")
+	}
+	return mod, nil
+}
+
+// unquoteModField removes the optional quotes around a go.mod field
+func unquoteModField(field string) string {
+	if unquoted, err := strconv.Unquote(field); err == nil {
+		return unquoted
+	}
+	return field
+}
+
+// NewRequirements returns the requirements of head on modules that base does
+// not require at any version, sorted by module path
+func NewRequirements(base, head *ModFile) []*ModRequire {
+	known := make(map[string]bool, len(base.Requires))
+	for _, req := range base.Requires {
+		known[req.Path] = true
+	}
+
+	var added []*ModRequire
+	for _, req := range head.Requires {
+		if !known[req.Path] {
+			added = append(added, req)
+		}
+	}
+	sort.Slice(added, func(i, j int) bool { return added[i].Path < added[j].Path })
+	return added
+}
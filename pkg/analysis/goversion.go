@@ -0,0 +1,91 @@
+package analysis
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"go/version"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// checkGoVersion reports compatibility problems of the module's go directive:
+// a declared version below the configured policy minimum, and language
+// features used by the changed packages that are newer than the declared
+// version. A repository without go.mod or go directive is not checked.
+func (a *Analyzer) checkGoVersion(changedPkgs []string) []string {
+	mod, err := ReadModFile(a.cfg.ModuleRoot(a.repoPath))
+	if err != nil || mod.GoVersion == "" {
+		return nil
+	}
+	declared := "go" + mod.GoVersion
+
+	var issues []string
+	if minimum := a.cfg.Analysis.MinGoVersion; minimum != "" {
+		if version.Compare(declared, "go"+minimum) < 0 {
+			issues = append(issues, fmt.Sprintf("go.mod declares go %s, below the policy minimum of %s", mod.GoVersion, minimum))
+		}
+	}
+
+	for _, pkgName := range changedPkgs {
+		pkg, ok := a.tree.Packages[pkgName]
+		if !ok {
+			continue
+		}
+		issues = append(issues, a.newerLanguageFeatures(pkg, declared)...)
+	}
+	return issues
+}
+
+// newerLanguageFeatures type-checks the files of pkg against goVersion and
+// returns the uses of language features that require a newer version. Imports
+// are not resolved; only the resulting version errors are kept.
+func (a *Analyzer) newerLanguageFeatures(pkg *Pkg, goVersion string) []string {
+	fset := token.NewFileSet()
+
+	// Files of an external test package are checked separately from the package
+	filesByPkg := make(map[string][]*ast.File)
+	for _, filename := range pkg.Files {
+		file, err := parser.ParseFile(fset, filename, nil, parser.SkipObjectResolution)
+		if err != nil {
+			// Unparsable packages are already reported by the resolver
+			return nil
+		}
+		filesByPkg[file.Name.Name] = append(filesByPkg[file.Name.Name], file)
+	}
+
+	var issues []string
+	for _, files := range filesByPkg {
+		conf := types.Config{
+			GoVersion: goVersion,
+			Importer:  stubImporter{},
+			Error: func(err error) {
+				typeErr, ok := err.(types.Error)
+				if !ok || !strings.Contains(typeErr.Msg, "requires go") {
+					return
+				}
+				pos := typeErr.Fset.Position(typeErr.Pos)
+				if rel, err := filepath.Rel(a.repoPath, pos.Filename); err == nil {
+					pos.Filename = filepath.ToSlash(rel)
+				}
+				issues = append(issues, fmt.Sprintf("%s: %s", pos, typeErr.Msg))
+			},
+		}
+		_, _ = conf.Check(pkg.Name, fset, files, nil)
+	}
+	sort.Strings(issues)
+	return issues
+}
+
+// stubImporter satisfies imports with empty packages so type checking can run
+// without the module's dependencies being available
+type stubImporter struct{}
+
+func (stubImporter) Import(path string) (*types.Package, error) {
+	pkg := types.NewPackage(path, filepath.Base(path))
+	pkg.MarkComplete()
+	return pkg, nil
+}
@@ -0,0 +1,123 @@
+package analysis
+
+import (
+	"sort"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+)
+
+// GraphNode is a package that is part of an impact graph
+type GraphNode struct {
+	Name        string `json:"name"`
+	Changed     bool   `json:"changed"`
+	IsCritical  bool   `json:"is_critical"`
+	IsHighLevel bool   `json:"is_high_level"`
+
+	packageName string // Declared package name, see Pkg.PackageName
+	target      bool   // See Pkg.Target
+}
+
+// GraphEdge is a reverse dependency edge: To imports From, so a change in From
+// reaches To.
+type GraphEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Graph is the structured impact graph of a set of changed packages, suitable
+// for programmatic consumption and JSON serialization.
+type Graph struct {
+	Nodes []*GraphNode `json:"nodes"`
+	Edges []*GraphEdge `json:"edges"`
+}
+
+// ImpactGraph returns the graph of every package reachable from the changed
+// packages over reverse dependency edges, including the changed packages
+// themselves. Nodes and edges are sorted by name.
+func (t *Tree) ImpactGraph(changedPkgs []string) *Graph {
+	reverse := t.reverseDependencyIndex()
+
+	nodes := make(map[string]*GraphNode)
+	var edges []*GraphEdge
+	var queue []string
+
+	for _, name := range changedPkgs {
+		if _, ok := nodes[name]; ok {
+			continue
+		}
+		nodes[name] = &GraphNode{Name: name, Changed: true}
+		queue = append(queue, name)
+	}
+
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		for _, importer := range reverse[current] {
+			edges = append(edges, &GraphEdge{From: current, To: importer.Name})
+			if _, ok := nodes[importer.Name]; ok {
+				continue
+			}
+			nodes[importer.Name] = &GraphNode{Name: importer.Name}
+			queue = append(queue, importer.Name)
+		}
+	}
+
+	graph := &Graph{
+		Nodes: make([]*GraphNode, 0, len(nodes)),
+		Edges: edges,
+	}
+	t.mu.RLock()
+	for _, node := range nodes {
+		if pkg, ok := t.Packages[node.Name]; ok {
+			node.packageName, node.target = pkg.PackageName, pkg.Target
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	t.mu.RUnlock()
+	sort.Slice(graph.Nodes, func(i, j int) bool {
+		return graph.Nodes[i].Name < graph.Nodes[j].Name
+	})
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		if graph.Edges[i].From != graph.Edges[j].From {
+			return graph.Edges[i].From < graph.Edges[j].From
+		}
+		return graph.Edges[i].To < graph.Edges[j].To
+	})
+
+	return graph
+}
+
+// Classify sets the critical and high-level flags of every node from the config
+func (g *Graph) Classify(cfg *config.Config) {
+	for _, node := range g.Nodes {
+		node.IsCritical = cfg.IsCriticalPackage(node.Name)
+		node.IsHighLevel = isHighLevel(cfg, &Pkg{Name: node.Name, PackageName: node.packageName, Target: node.target})
+	}
+}
+
+// reverseDependencyIndex maps each package to the packages that directly import
+// it. The index of a frozen tree is shared and must not be modified.
+func (t *Tree) reverseDependencyIndex() map[string][]*Pkg {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.frozen {
+		return t.reverse
+	}
+	return t.buildReverseIndex()
+}
+
+// buildReverseIndex computes the reverse dependency index. The caller must hold
+// the lock.
+func (t *Tree) buildReverseIndex() map[string][]*Pkg {
+	index := make(map[string][]*Pkg)
+	for _, pkg := range t.Packages {
+		for _, dep := range pkg.Dependencies {
+			if dep.Name == pkg.Name {
+				continue
+			}
+			index[dep.Name] = append(index[dep.Name], pkg)
+		}
+	}
+	return index
+}
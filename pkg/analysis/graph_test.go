@@ -0,0 +1,60 @@
+package analysis
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTree builds a resolved tree from a map of package name to the names
+// of the packages it imports.
+func newTestTree(imports map[string][]string) *Tree {
+	tree := NewTree("", "example.com/m")
+	for name := range imports {
+		tree.Packages[name] = &Pkg{Name: name, Internal: true}
+	}
+	for name, deps := range imports {
+		for _, dep := range deps {
+			tree.Packages[name].Imports = append(tree.Packages[name].Imports, dep)
+			tree.Packages[name].Dependencies = append(tree.Packages[name].Dependencies, tree.Packages[dep])
+		}
+	}
+	return tree
+}
+
+func TestTreeImpactGraph(t *testing.T) {
+	// a -> c -> d, b -> d, e is unrelated
+	tree := newTestTree(map[string][]string{
+		"example.com/m/a": {"example.com/m/c"},
+		"example.com/m/b": {"example.com/m/d"},
+		"example.com/m/c": {"example.com/m/d"},
+		"example.com/m/d": nil,
+		"example.com/m/e": nil,
+	})
+
+	graph := tree.ImpactGraph([]string{"example.com/m/d"})
+
+	cfg := config.DefaultConfig()
+	cfg.Targets.HighLevelPackages = []string{"**/a", "**/b"}
+	cfg.Critical.Packages = config.CriticalPatterns("**/a")
+	graph.Classify(cfg)
+
+	require.Equal(t, []*GraphNode{
+		{Name: "example.com/m/a", IsCritical: true, IsHighLevel: true},
+		{Name: "example.com/m/b", IsHighLevel: true},
+		{Name: "example.com/m/c"},
+		{Name: "example.com/m/d", Changed: true},
+	}, graph.Nodes)
+	require.Equal(t, []*GraphEdge{
+		{From: "example.com/m/c", To: "example.com/m/a"},
+		{From: "example.com/m/d", To: "example.com/m/b"},
+		{From: "example.com/m/d", To: "example.com/m/c"},
+	}, graph.Edges)
+
+	data, err := json.Marshal(graph)
+	require.NoError(t, err)
+	require.Contains(t, string(data), `{"name":"example.com/m/d","changed":true,"is_critical":false,"is_high_level":false}`)
+	require.Contains(t, string(data), `{"from":"example.com/m/c","to":"example.com/m/a"}`)
+}
@@ -0,0 +1,58 @@
+package analysis
+
+import (
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// ChangeKind classifies the change made to a package in import-diff mode
+type ChangeKind string
+
+const (
+	// ChangeKindImportsChanged means the package's internal import set changed
+	ChangeKindImportsChanged ChangeKind = "imports_changed"
+	// ChangeKindInternalOnly means only the package's implementation changed
+	ChangeKindInternalOnly ChangeKind = "internal_only"
+)
+
+// classifyImportChange compares the imports of the changed package at head with
+// those at the base revision and records the difference on the impact.
+func (a *Analyzer) classifyImportChange(impact *PackageImpact) {
+	pkgName := impact.ChangedPackage
+	if err := a.baseTree.Resolve(pkgName); err != nil {
+		zap.S().Warnw("failed to resolve package at base revision", "package", pkgName, "error", err)
+	}
+
+	var headImports, baseImports []string
+	if p, ok := a.tree.Packages[pkgName]; ok {
+		headImports = p.Imports
+	}
+	if p, ok := a.baseTree.Packages[pkgName]; ok {
+		baseImports = p.Imports
+	}
+
+	impact.AddedImports = difference(headImports, baseImports)
+	impact.RemovedImports = difference(baseImports, headImports)
+	if len(impact.AddedImports) > 0 || len(impact.RemovedImports) > 0 {
+		impact.Kind = ChangeKindImportsChanged
+	} else {
+		impact.Kind = ChangeKindInternalOnly
+	}
+}
+
+// difference returns the sorted elements of a that are not in b
+func difference(a, b []string) []string {
+	inB := make(map[string]bool, len(b))
+	for _, s := range b {
+		inB[s] = true
+	}
+	var diff []string
+	for _, s := range a {
+		if !inB[s] {
+			diff = append(diff, s)
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
@@ -0,0 +1,38 @@
+package analysis
+
+import (
+	"path"
+	"strings"
+)
+
+// ImportPathFor returns the import path of the package in repoRelDir, a
+// directory relative to the module root. Windows separators, "." elements and
+// duplicate or trailing slashes are normalized, and the module root itself
+// ("." or "") is the root package.
+func ImportPathFor(rootPkg, repoRelDir string) string {
+	rootPkg = strings.TrimRight(rootPkg, "/")
+	dir := cleanDir(repoRelDir)
+	if dir == "" {
+		return rootPkg
+	}
+	return rootPkg + "/" + dir
+}
+
+// DirFor is the inverse of ImportPathFor: it returns the slash-separated
+// directory, relative to the module root, of a package of the module rootPkg,
+// "" for the root package. It reports false for packages outside the module,
+// including those of modules whose path merely extends rootPkg.
+func DirFor(rootPkg, importPath string) (string, bool) {
+	rootPkg = strings.TrimRight(rootPkg, "/")
+	if importPath == rootPkg {
+		return "", true
+	}
+	return strings.CutPrefix(importPath, rootPkg+"/")
+}
+
+// cleanDir normalizes a relative directory to slash-separated form without
+// leading or trailing slashes. The root is "".
+func cleanDir(dir string) string {
+	dir = strings.ReplaceAll(dir, `\`, "/")
+	return strings.Trim(path.Clean("/"+dir), "/")
+}
@@ -0,0 +1,66 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportPathFor(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	for dir, want := range map[string]string{
+		"":               rootPkg,
+		".":              rootPkg,
+		"./":             rootPkg,
+		`.\`:             rootPkg,
+		"pkg/foo":        rootPkg + "/pkg/foo",
+		"pkg/foo/":       rootPkg + "/pkg/foo",
+		"./pkg//foo":     rootPkg + "/pkg/foo",
+		`pkg\foo`:        rootPkg + "/pkg/foo",
+		`.\pkg\foo\`:     rootPkg + "/pkg/foo",
+		`pkg\foo/bar`:    rootPkg + "/pkg/foo/bar",
+		"/pkg/foo":       rootPkg + "/pkg/foo",
+		"pkg/./foo/../x": rootPkg + "/pkg/x",
+	} {
+		require.Equal(t, want, ImportPathFor(rootPkg, dir), dir)
+	}
+
+	// A trailing slash on the module path is not doubled
+	require.Equal(t, rootPkg+"/pkg", ImportPathFor(rootPkg+"/", "pkg"))
+	require.Equal(t, rootPkg, ImportPathFor(rootPkg+"/", "."))
+}
+
+func TestDirFor(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	for pkg, want := range map[string]string{
+		rootPkg:                rootPkg,
+		rootPkg + "/pkg/foo":   rootPkg + "/pkg/foo",
+		rootPkg + "/pkg/x/y/z": rootPkg + "/pkg/x/y/z",
+	} {
+		dir, ok := DirFor(rootPkg, pkg)
+		require.True(t, ok, pkg)
+		require.Equal(t, want, ImportPathFor(rootPkg, dir), "DirFor inverts ImportPathFor")
+	}
+
+	dir, ok := DirFor(rootPkg+"/", rootPkg+"/pkg")
+	require.True(t, ok)
+	require.Equal(t, "pkg", dir)
+
+	// Modules whose path extends the root path are not below it
+	for _, pkg := range []string{"github.com/a/bc", "github.com/a/b-tools/x", "github.com/a", "fmt"} {
+		_, ok := DirFor(rootPkg, pkg)
+		require.False(t, ok, pkg)
+	}
+}
+
+func TestChangedPackages_WindowsPaths(t *testing.T) {
+	repoPath := writeRepo(t, map[string]string{
+		"main.go":    "package main",
+		"pkg/foo.go": "package pkg",
+	})
+
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetRootPackage("github.com/a/b")
+	require.Equal(t, []string{"github.com/a/b", "github.com/a/b/pkg"}, analyzer.ChangedPackages([]string{`main.go`, `pkg\foo.go`}))
+}
@@ -0,0 +1,202 @@
+package analysis
+
+import (
+	"go/types"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"golang.org/x/tools/go/packages"
+)
+
+// InterfaceImplementer is a type of another package that satisfied an exported
+// interface altered by the change at the base revision, and may no longer
+// satisfy it
+type InterfaceImplementer struct {
+	Interface string // Name of the changed interface
+	Package   string // Package declaring the implementing type
+	Type      string // Name of the implementing type, with a * when only the pointer satisfied it
+}
+
+// methodSet maps method names to their signatures, rendered without parameter
+// names
+type methodSet map[string]string
+
+// interfaceImplementers compares the exported interfaces of the changed
+// package at the base revision and at head, and returns the types of other
+// packages of the repository that implemented a changed interface at the base
+// revision. Packages are type-checked with go/packages, so implementations
+// through embedded types of any package are found.
+func (a *Analyzer) interfaceImplementers(pkgName string) []*InterfaceImplementer {
+	headPkgs, err := loadTypes(a.tree.packageDir(a.rootPkgPath), a.tree.BuildTags, []string{pkgName})
+	if err != nil {
+		zap.S().Warnw("failed to load the package at head, skipping interface change detection", "package", pkgName, "error", err)
+		return nil
+	}
+	if a.baseTypes == nil {
+		a.baseTypes, err = loadTypes(a.baseTree.packageDir(a.rootPkgPath), a.baseTree.BuildTags, a.internalPackages())
+		if err != nil {
+			zap.S().Warnw("failed to load the packages at the base revision, skipping interface change detection", "error", err)
+			return nil
+		}
+	}
+	head, base := headPkgs[pkgName], a.baseTypes[pkgName]
+	if head == nil || base == nil {
+		return nil
+	}
+
+	// Only interfaces existing on both sides can have implementers to break
+	baseInterfaces := exportedInterfaces(base)
+	headInterfaces := exportedInterfaces(head)
+	var changed []string
+	for name, iface := range baseInterfaces {
+		if current, ok := headInterfaces[name]; ok && !sameMethods(methods(iface), methods(current)) {
+			changed = append(changed, name)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+
+	var implementers []*InterfaceImplementer
+	for otherName, other := range a.baseTypes {
+		if otherName == pkgName {
+			continue
+		}
+		scope := other.Scope()
+		for _, typeName := range scope.Names() {
+			obj, ok := scope.Lookup(typeName).(*types.TypeName)
+			if !ok || obj.IsAlias() {
+				continue
+			}
+			named, ok := obj.Type().(*types.Named)
+			if !ok || named.TypeParams().Len() > 0 || types.IsInterface(named) {
+				continue
+			}
+			for _, ifaceName := range changed {
+				switch iface := baseInterfaces[ifaceName]; {
+				case types.Implements(named, iface):
+					implementers = append(implementers, &InterfaceImplementer{Interface: ifaceName, Package: otherName, Type: typeName})
+				case types.Implements(types.NewPointer(named), iface):
+					implementers = append(implementers, &InterfaceImplementer{Interface: ifaceName, Package: otherName, Type: "*" + typeName})
+				}
+			}
+		}
+	}
+
+	sort.Slice(implementers, func(i, j int) bool {
+		x, y := implementers[i], implementers[j]
+		if x.Interface != y.Interface {
+			return x.Interface < y.Interface
+		}
+		if x.Package != y.Package {
+			return x.Package < y.Package
+		}
+		return x.Type < y.Type
+	})
+	return implementers
+}
+
+// internalPackages returns the sorted internal packages of the repository
+// that are not ignored
+func (a *Analyzer) internalPackages() []string {
+	var names []string
+	for name, pkg := range a.tree.Packages {
+		if pkg.Internal && !a.cfg.ShouldIgnorePackage(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadTypes type-checks the packages of the module in dir with the given
+// import paths, under buildTags, and returns them by import path. Packages
+// that fail to load, such as ones missing from the module, are left out.
+func loadTypes(dir string, buildTags []string, pkgNames []string) (map[string]*types.Package, error) {
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedImports | packages.NeedDeps,
+		Dir:  dir,
+	}
+	if len(buildTags) > 0 {
+		cfg.BuildFlags = []string{"-tags=" + strings.Join(buildTags, ",")}
+	}
+	pkgs, err := packages.Load(cfg, pkgNames...)
+	if err != nil {
+		return nil, err
+	}
+
+	loaded := make(map[string]*types.Package)
+	for _, pkg := range pkgs {
+		if len(pkg.Errors) > 0 || pkg.Types == nil {
+			zap.S().Debugw("failed to type-check package, skipping it for interface change detection", "package", pkg.PkgPath, "errors", pkg.Errors)
+			continue
+		}
+		loaded[pkg.PkgPath] = pkg.Types
+	}
+	return loaded, nil
+}
+
+// exportedInterfaces returns the exported interfaces of pkg that have methods
+// and can be implemented, leaving out generic interfaces and constraints
+func exportedInterfaces(pkg *types.Package) map[string]*types.Interface {
+	interfaces := make(map[string]*types.Interface)
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		obj, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok || !obj.Exported() {
+			continue
+		}
+		if named, ok := obj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+			continue
+		}
+		iface, ok := obj.Type().Underlying().(*types.Interface)
+		// Every type implements an interface without methods
+		if ok && iface.IsMethodSet() && iface.NumMethods() > 0 {
+			interfaces[name] = iface
+		}
+	}
+	return interfaces
+}
+
+// methods returns the method set of an interface, including embedded methods
+func methods(iface *types.Interface) methodSet {
+	set := make(methodSet)
+	for i := range iface.NumMethods() {
+		method := iface.Method(i)
+		set[method.Name()] = signature(method.Type().(*types.Signature))
+	}
+	return set
+}
+
+// signature renders the parameter and result types of a function, leaving out
+// parameter names so that renaming a parameter does not change it
+func signature(sig *types.Signature) string {
+	tuple := func(vars *types.Tuple, variadic bool) string {
+		var list []string
+		for i := range vars.Len() {
+			typ := types.TypeString(vars.At(i).Type(), nil)
+			if variadic && i == vars.Len()-1 {
+				typ = "..." + strings.TrimPrefix(typ, "[]")
+			}
+			list = append(list, typ)
+		}
+		return strings.Join(list, ", ")
+	}
+	return "(" + tuple(sig.Params(), sig.Variadic()) + ") (" + tuple(sig.Results(), false) + ")"
+}
+
+// sameMethods reports whether two method sets have the same names and
+// signatures
+func sameMethods(a, b methodSet) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for name, sig := range a {
+		if b[name] != sig {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeChangedPackages_InterfaceChanges(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	base := map[string]string{
+		"go.mod": "module " + rootPkg,
+		"store/store.go": `package store
+
+type Reader interface {
+	Get(key string) ([]byte, error)
+}
+
+// Store embeds Reader, so its method set includes Get
+type Store interface {
+	Reader
+	Put(key string, value []byte) error
+}
+
+type internalOnly interface {
+	Get(key string) ([]byte, error)
+}
+`,
+		// disk satisfies Store without importing the store package
+		"disk/disk.go": `package disk
+
+type Disk struct{}
+
+func (d *Disk) Get(key string) ([]byte, error) { return nil, nil }
+func (d *Disk) Put(key string, value []byte) error { return nil }
+`,
+		// mem satisfies Store through the methods of an embedded struct
+		"mem/mem.go": `package mem
+
+type base struct{}
+
+func (base) Get(k string) ([]byte, error) { return nil, nil }
+
+type Mem struct {
+	base
+}
+
+func (m Mem) Put(k string, v []byte) error { return nil }
+`,
+		// wrapped satisfies Store through a struct embedded from another package
+		"wrapped/wrapped.go": `package wrapped
+
+import "github.com/a/b/disk"
+
+type Wrapped struct {
+	disk.Disk
+}
+`,
+		// wrong declares the method names of Store with other signatures
+		"wrong/wrong.go": `package wrong
+
+type Wrong struct{}
+
+func (Wrong) Get(key int) ([]byte, error) { return nil, nil }
+func (Wrong) Put(key string, value []byte) error { return nil }
+`,
+		"cache/cache.go": `package cache
+
+type Cache[K comparable] struct{}
+
+func (c Cache[K]) Get(key string) ([]byte, error) { return nil, nil }
+`,
+	}
+	head := make(map[string]string)
+	for name, content := range base {
+		head[name] = content
+	}
+	// Adding a method to Store breaks Disk, Reader is unchanged
+	head["store/store.go"] = `package store
+
+type Reader interface {
+	Get(key string) ([]byte, error)
+}
+
+type Store interface {
+	Reader
+	Put(key string, value []byte) error
+	Delete(key string) error
+}
+`
+
+	cfg := config.DefaultConfig()
+	cfg.Analysis.InterfaceChanges = true
+	analyzer := NewAnalyzer(cfg, writeRepo(t, head))
+	analyzer.SetBaseRepo(writeRepo(t, base))
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"store/store.go"})
+	require.NoError(t, err)
+	require.Len(t, result.Impacts, 1)
+	require.Equal(t, []*InterfaceImplementer{
+		{Interface: "Store", Package: rootPkg + "/disk", Type: "*Disk"},
+		{Interface: "Store", Package: rootPkg + "/mem", Type: "Mem"},
+		{Interface: "Store", Package: rootPkg + "/wrapped", Type: "*Wrapped"},
+	}, result.Impacts[0].InterfaceImplementers, "only the pointer to Disk has its methods")
+	require.Contains(t, result.String(), "- `github.com/a/b/disk`: `*Disk` implements `Store`")
+
+	// Without a change to the interfaces nothing is flagged
+	result, err = analyzer.AnalyzeChangedPackages([]string{"disk/disk.go"})
+	require.NoError(t, err)
+	require.Empty(t, result.Impacts[0].InterfaceImplementers)
+}
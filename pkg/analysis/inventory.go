@@ -0,0 +1,56 @@
+package analysis
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// PackageInfo classifies an internal package of the repository
+type PackageInfo struct {
+	Name       string `json:"name"`
+	HighLevel  bool   `json:"high_level"`
+	Critical   bool   `json:"critical"`
+	HasTests   bool   `json:"has_tests"`
+	Imports    int    `json:"imports"`    // Internal packages it imports directly
+	Dependents int    `json:"dependents"` // Internal packages importing it directly
+}
+
+// Packages resolves the whole repository and returns every internal package
+// it holds, sorted by name, leaving out ignored packages. Packages are
+// classified with the configuration applying to their directory.
+func (a *Analyzer) Packages() ([]*PackageInfo, error) {
+	if a.tree == nil {
+		return nil, ErrNoRootPackage
+	}
+	if err := a.resolveAll(); err != nil {
+		return nil, err
+	}
+
+	reverse := a.tree.reverseDependencyIndex()
+	var infos []*PackageInfo
+	for name, pkg := range a.tree.Packages {
+		if !pkg.Internal || a.cfg.ShouldIgnorePackage(name) {
+			continue
+		}
+		labels := classify(a.classifiersFor(a.configFor(name)), pkg)
+		infos = append(infos, &PackageInfo{
+			Name:       name,
+			HighLevel:  hasLabel(labels, LabelHighLevel),
+			Critical:   hasLabel(labels, LabelCritical),
+			HasTests:   a.tree.hasTestFiles(name),
+			Imports:    len(pkg.Imports),
+			Dependents: len(reverse[name]),
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos, nil
+}
+
+// hasTestFiles reports whether the directory of a package holds test files,
+// whether or not tests are analyzed
+func (t *Tree) hasTestFiles(pkgName string) bool {
+	matches, _ := filepath.Glob(filepath.Join(t.packageDir(pkgName), "*_test.go"))
+	return len(matches) > 0
+}
@@ -0,0 +1,35 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzerPackages(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod":              "module " + rootPkg,
+		"cmd/app/main.go":     "package main\n\nimport _ \"github.com/a/b/store\"\n\nfunc main() {}\n",
+		"store/store.go":      "package store\n\nimport _ \"github.com/a/b/util\"\n",
+		"store/store_test.go": "package store\n",
+		"util/util.go":        "package util\n\nimport _ \"fmt\"\n",
+		"internal/gen/gen.go": "package gen\n",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Targets.HighLevelPackages = []string{"**/cmd/**"}
+	cfg.Critical.Packages = config.CriticalPatterns("**/store")
+	cfg.Patterns.IgnorePackagePatterns = []string{"**/internal/**"}
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	packages, err := analyzer.Packages()
+	require.NoError(t, err)
+	require.Equal(t, []*PackageInfo{
+		{Name: rootPkg + "/cmd/app", HighLevel: true, Imports: 1},
+		{Name: rootPkg + "/store", Critical: true, HasTests: true, Imports: 1, Dependents: 1},
+		{Name: rootPkg + "/util", Dependents: 1},
+	}, packages)
+}
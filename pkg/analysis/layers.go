@@ -0,0 +1,92 @@
+package analysis
+
+import (
+	"slices"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// LayerViolation is an import that the layering rules forbid
+type LayerViolation struct {
+	Importer      string
+	Imported      string
+	ImporterLayer string
+	ImportedLayer string
+	New           bool // The import is absent at the base revision
+}
+
+// LayerViolations resolves the whole repository and returns the imports
+// violating the layering rules, sorted by importer then imported package.
+// When a base revision is set, violations whose import it lacks are marked
+// new.
+func (a *Analyzer) LayerViolations() ([]*LayerViolation, error) {
+	if a.tree == nil {
+		return nil, ErrNoRootPackage
+	}
+	if err := a.resolveAll(); err != nil {
+		return nil, err
+	}
+	return a.layerViolations(), nil
+}
+
+// layerViolations returns the imports of the resolved packages violating the
+// layering rules
+func (a *Analyzer) layerViolations() []*LayerViolation {
+	if len(a.cfg.Layering.Rules) == 0 {
+		return nil
+	}
+
+	var violations []*LayerViolation
+	for name, pkg := range a.tree.Packages {
+		importerLayer := a.cfg.LayerOf(name)
+		if importerLayer == "" {
+			continue
+		}
+		for _, imp := range pkg.Imports {
+			importedLayer := a.cfg.LayerOf(imp)
+			if a.cfg.LayerImportAllowed(importerLayer, importedLayer) {
+				continue
+			}
+			violations = append(violations, &LayerViolation{
+				Importer:      name,
+				Imported:      imp,
+				ImporterLayer: importerLayer,
+				ImportedLayer: importedLayer,
+				New:           a.baseTree != nil && !a.importedAtBase(name, imp),
+			})
+		}
+	}
+
+	sort.Slice(violations, func(i, j int) bool {
+		x, y := violations[i], violations[j]
+		if x.Importer != y.Importer {
+			return x.Importer < y.Importer
+		}
+		return x.Imported < y.Imported
+	})
+	return violations
+}
+
+// importedAtBase reports whether a package imports another at the base
+// revision
+func (a *Analyzer) importedAtBase(importer, imported string) bool {
+	if err := a.baseTree.Resolve(importer); err != nil {
+		zap.S().Warnw("failed to resolve package at base revision", "package", importer, "error", err)
+	}
+	pkg, ok := a.baseTree.Packages[importer]
+	return ok && slices.Contains(pkg.Imports, imported)
+}
+
+// splitLayerViolations returns the layering violations introduced by the
+// change and the pre-existing ones
+func (r *AnalysisResult) splitLayerViolations() (introduced, existing []*LayerViolation) {
+	for _, v := range r.LayerViolations {
+		if v.New {
+			introduced = append(introduced, v)
+		} else {
+			existing = append(existing, v)
+		}
+	}
+	return introduced, existing
+}
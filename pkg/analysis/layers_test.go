@@ -0,0 +1,57 @@
+package analysis
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeChangedPackages_LayerViolations(t *testing.T) {
+	// service already imports api at the base; the change makes repo import
+	// service too
+	rootPkg := "example.com/m"
+	importing := func(pkg, imported string) string {
+		return fmt.Sprintf("package %s\n\nimport \"%s/%s\"\n\nfunc F() { %s.F() }", pkg, rootPkg, imported, imported)
+	}
+	base := map[string]string{
+		"go.mod":             "module " + rootPkg,
+		"api/api.go":         importing("api", "service"),
+		"service/service.go": importing("service", "api"),
+		"repo/repo.go":       "package repo\n\nfunc F() {}",
+	}
+	head := make(map[string]string)
+	for name, content := range base {
+		head[name] = content
+	}
+	head["repo/repo.go"] = importing("repo", "service")
+
+	cfg := config.DefaultConfig()
+	cfg.Layering = config.LayeringConfig{
+		Layers: map[string][]string{
+			"api":     {"**/api"},
+			"service": {"**/service"},
+			"repo":    {"**/repo"},
+		},
+		Rules: []*config.LayerRule{
+			{From: "api", Allow: []string{"service"}},
+			{From: "service", Allow: []string{"repo"}},
+			{From: "repo", Deny: []string{"api", "service"}},
+		},
+	}
+	analyzer := NewAnalyzer(cfg, writeRepo(t, head))
+	analyzer.SetBaseRepo(writeRepo(t, base))
+	analyzer.SetRootPackage(rootPkg)
+
+	result, err := analyzer.AnalyzeChangedPackages([]string{"repo/repo.go"})
+	require.NoError(t, err)
+	require.Equal(t, []*LayerViolation{
+		{Importer: rootPkg + "/repo", Imported: rootPkg + "/service", ImporterLayer: "repo", ImportedLayer: "service", New: true},
+		{Importer: rootPkg + "/service", Imported: rootPkg + "/api", ImporterLayer: "service", ImportedLayer: "api"},
+	}, result.LayerViolations)
+
+	report := result.String()
+	require.Contains(t, report, "🧱 **1 layering violations introduced by this change**:\n\n- `"+rootPkg+"/repo` (repo) imports `"+rootPkg+"/service` (service)\n")
+	require.Contains(t, report, "<details><summary>1 pre-existing layering violations</summary>\n\n- `"+rootPkg+"/service` (service) imports `"+rootPkg+"/api` (api)\n")
+}
@@ -0,0 +1,97 @@
+package analysis
+
+import (
+	"sort"
+	"strings"
+)
+
+// MaxMatrixEntries is the number of jobs GitHub Actions allows a matrix to
+// generate
+const MaxMatrixEntries = 256
+
+// Matrix is a GitHub Actions matrix, for use with fromJSON in strategy.matrix
+type Matrix struct {
+	Include []MatrixEntry `json:"include"`
+}
+
+// MatrixEntry is a single job of a matrix
+type MatrixEntry struct {
+	Package string `json:"package"`
+}
+
+// TestTargets returns the sorted go test patterns, relative to the module
+// root, of the changed and affected packages of the module. Packages outside
+// the module, such as vendored ones, are left out, as are packages already
+// covered by the pattern of an enclosing package.
+func (r *AnalysisResult) TestTargets() []string {
+	seen := make(map[string]bool)
+	var targets []string
+	add := func(pkgName string) {
+		target, ok := r.testTarget(pkgName)
+		if ok && !seen[target] {
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	for _, impact := range r.Impacts {
+		add(impact.ChangedPackage)
+		for _, pkg := range impact.AffectedPackages {
+			add(pkg.Name)
+		}
+	}
+	sort.Strings(targets)
+
+	kept := targets[:0]
+	for _, target := range targets {
+		if !coveredTarget(target, seen) {
+			kept = append(kept, target)
+		}
+	}
+	return kept
+}
+
+// coveredTarget reports whether the recursive pattern of an enclosing
+// package, among targets, already covers target
+func coveredTarget(target string, targets map[string]bool) bool {
+	dir := strings.TrimSuffix(strings.TrimPrefix(target, "./"), "/...")
+	for {
+		i := strings.LastIndex(dir, "/")
+		if i < 0 {
+			return false
+		}
+		dir = dir[:i]
+		if targets["./"+dir+"/..."] {
+			return true
+		}
+	}
+}
+
+// testTarget converts an import path of the module to its go test pattern,
+// covering the packages below it as well. The module root is tested alone, as
+// its pattern would cover the whole module.
+func (r *AnalysisResult) testTarget(pkgName string) (string, bool) {
+	dir, ok := DirFor(r.ModulePath, pkgName)
+	if !ok {
+		return "", false
+	}
+	if dir == "" {
+		return ".", true
+	}
+	return "./" + dir + "/...", true
+}
+
+// Matrix returns a matrix with one entry per test target. When there are more
+// than limit targets, the matrix collapses to a single entry testing the whole
+// module.
+func (r *AnalysisResult) Matrix(limit int) *Matrix {
+	targets := r.TestTargets()
+	matrix := &Matrix{Include: []MatrixEntry{}}
+	if len(targets) > limit {
+		matrix.Include = append(matrix.Include, MatrixEntry{Package: "./..."})
+		return matrix
+	}
+	for _, target := range targets {
+		matrix.Include = append(matrix.Include, MatrixEntry{Package: target})
+	}
+	return matrix
+}
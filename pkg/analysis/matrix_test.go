@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalysisResult_Matrix(t *testing.T) {
+	result := &AnalysisResult{
+		ModulePath: "example.com/m",
+		Impacts: []*PackageImpact{
+			{
+				ChangedPackage: "example.com/m/internal/foo",
+				AffectedPackages: []*AffectedPackage{
+					{Name: "example.com/m"},
+					{Name: "example.com/m/app"},
+				},
+			},
+			{
+				ChangedPackage:   "example.com/shared/log",
+				AffectedPackages: []*AffectedPackage{{Name: "example.com/m/app"}},
+			},
+		},
+	}
+
+	data, err := json.Marshal(result.Matrix(MaxMatrixEntries))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"include": [
+		{"package": "."},
+		{"package": "./app/..."},
+		{"package": "./internal/foo/..."}
+	]}`, string(data))
+
+	// Beyond the limit, a single entry runs everything
+	data, err = json.Marshal(result.Matrix(2))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"include": [{"package": "./..."}]}`, string(data))
+
+	// An empty matrix still has an include list
+	data, err = json.Marshal((&AnalysisResult{ModulePath: "example.com/m"}).Matrix(MaxMatrixEntries))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"include": []}`, string(data))
+}
+
+func TestAnalysisResult_MatrixLimit(t *testing.T) {
+	impact := &PackageImpact{ChangedPackage: "example.com/m/a"}
+	for i := 0; i < MaxMatrixEntries; i++ {
+		impact.AffectedPackages = append(impact.AffectedPackages, &AffectedPackage{Name: fmt.Sprintf("example.com/m/p%d", i)})
+	}
+	result := &AnalysisResult{ModulePath: "example.com/m", Impacts: []*PackageImpact{impact}}
+
+	// 257 targets exceed what GitHub Actions accepts
+	require.Len(t, result.TestTargets(), MaxMatrixEntries+1)
+	require.Equal(t, []MatrixEntry{{Package: "./..."}}, result.Matrix(MaxMatrixEntries).Include)
+
+	// Packages below a target are covered by its pattern and do not count
+	// towards the limit
+	impact.ChangedPackage = "example.com/m/p0"
+	for i := 0; i < MaxMatrixEntries; i++ {
+		impact.AffectedPackages[i].Name = fmt.Sprintf("example.com/m/p0/sub%d", i)
+	}
+	impact.AffectedPackages = append(impact.AffectedPackages, &AffectedPackage{Name: "example.com/m/p0-extra"})
+	require.Equal(t, []string{"./p0-extra/...", "./p0/..."}, result.TestTargets())
+	require.Equal(t, []MatrixEntry{{Package: "./p0-extra/..."}, {Package: "./p0/..."}}, result.Matrix(MaxMatrixEntries).Include)
+}
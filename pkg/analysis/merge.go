@@ -0,0 +1,172 @@
+package analysis
+
+import (
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+)
+
+// Merge folds into r the result of analyzing the same change in another module
+// of a workspace. Impacts of a package changed in both are combined, keeping
+// the shortest depth of each affected package, and the critical count is
+// recomputed over the distinct critical packages of both. Severity keeps the
+// higher level; recompute it with the thresholds when they differ.
+func (r *AnalysisResult) Merge(other *AnalysisResult) {
+	byChanged := make(map[string]*PackageImpact, len(r.Impacts))
+	for _, impact := range r.Impacts {
+		byChanged[impact.ChangedPackage] = impact
+	}
+	for _, impact := range other.Impacts {
+		existing, ok := byChanged[impact.ChangedPackage]
+		if !ok {
+			byChanged[impact.ChangedPackage] = impact
+			r.Impacts = append(r.Impacts, impact)
+			continue
+		}
+		existing.AffectedPackages = mergeAffected(existing.AffectedPackages, impact.AffectedPackages)
+		existing.AddedImports = unionStrings(existing.AddedImports, impact.AddedImports)
+		existing.RemovedImports = unionStrings(existing.RemovedImports, impact.RemovedImports)
+		if existing.Kind == ChangeKindInternalOnly && impact.Kind != "" {
+			existing.Kind = impact.Kind
+		}
+		for _, implementer := range impact.InterfaceImplementers {
+			if !slices.ContainsFunc(existing.InterfaceImplementers, func(i *InterfaceImplementer) bool { return *i == *implementer }) {
+				existing.InterfaceImplementers = append(existing.InterfaceImplementers, implementer)
+			}
+		}
+		for _, author := range impact.RecentAuthors {
+			if !slices.Contains(existing.RecentAuthors, author) {
+				existing.RecentAuthors = append(existing.RecentAuthors, author)
+			}
+		}
+	}
+	sort.Slice(r.Impacts, func(i, j int) bool {
+		return r.Impacts[i].ChangedPackage < r.Impacts[j].ChangedPackage
+	})
+
+	// A package directly imported in one module is no longer indirect
+	r.DirectDependencies = unionStrings(r.DirectDependencies, other.DirectDependencies)
+	var indirect []string
+	for _, dep := range unionStrings(r.IndirectDependencies, other.IndirectDependencies) {
+		if _, direct := slices.BinarySearch(r.DirectDependencies, dep); !direct {
+			indirect = append(indirect, dep)
+		}
+	}
+	r.IndirectDependencies = indirect
+
+	r.Warnings = unionStrings(r.Warnings, other.Warnings)
+	r.GoVersionIssues = unionStrings(r.GoVersionIssues, other.GoVersionIssues)
+	r.PolicyViolations = appendUnique(r.PolicyViolations, other.PolicyViolations)
+	r.LayerViolations = appendUnique(r.LayerViolations, other.LayerViolations)
+	r.DeprecatedImports = appendUnique(r.DeprecatedImports, other.DeprecatedImports)
+	r.NewDependencies = appendUnique(r.NewDependencies, other.NewDependencies)
+	for _, change := range other.ConfigChanges {
+		if !slices.ContainsFunc(r.ConfigChanges, func(c *config.Change) bool { return c.Setting == change.Setting }) {
+			r.ConfigChanges = append(r.ConfigChanges, change)
+		}
+	}
+
+	r.CriticalCount = len(r.CriticalPackageNames())
+	if severityRank(other.Severity) > severityRank(r.Severity) {
+		r.Severity = other.Severity
+	}
+	r.WarnThreshold = max(r.WarnThreshold, other.WarnThreshold)
+	r.BlockThreshold = max(r.BlockThreshold, other.BlockThreshold)
+	r.LoadErrors += other.LoadErrors
+	if other.Incomplete != nil {
+		incomplete := Incompleteness{}
+		if r.Incomplete != nil {
+			incomplete = *r.Incomplete
+		}
+		incomplete.Resolved += other.Incomplete.Resolved
+		incomplete.Total += other.Incomplete.Total
+		r.Incomplete = &incomplete
+	}
+
+	// Areas and relative paths are relative to the path the modules share
+	r.ModulePath = commonPathPrefix(r.ModulePath, other.ModulePath)
+	changed := make([]string, 0, len(r.Impacts))
+	for _, impact := range r.Impacts {
+		changed = append(changed, impact.ChangedPackage)
+	}
+	r.ChangedAreas = changedAreas(r.ModulePath, changed)
+}
+
+// mergeAffected returns the union of two lists of affected packages sorted by
+// name, keeping the shortest depth of a package listed in both
+func mergeAffected(list, other []*AffectedPackage) []*AffectedPackage {
+	byName := make(map[string]*AffectedPackage, len(list))
+	for _, pkg := range list {
+		byName[pkg.Name] = pkg
+	}
+	for _, pkg := range other {
+		existing, ok := byName[pkg.Name]
+		if !ok {
+			byName[pkg.Name] = pkg
+			list = append(list, pkg)
+			continue
+		}
+		existing.Depth = min(existing.Depth, pkg.Depth)
+		existing.IsCritical = existing.IsCritical || pkg.IsCritical
+		existing.Labels = unionStrings(existing.Labels, pkg.Labels)
+		if existing.DocsURL == "" {
+			existing.DocsURL = pkg.DocsURL
+		}
+		if existing.Badge == "" {
+			existing.Badge = pkg.Badge
+		}
+		if existing.LastRelease == "" {
+			existing.LastRelease = pkg.LastRelease
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// unionStrings returns the sorted, distinct strings of both lists, nil when
+// both are empty
+func unionStrings(list, other []string) []string {
+	if len(list) == 0 && len(other) == 0 {
+		return nil
+	}
+	union := append(slices.Clone(list), other...)
+	slices.Sort(union)
+	return slices.Compact(union)
+}
+
+// appendUnique appends the entries of other whose value list lacks
+func appendUnique[T comparable](list, other []*T) []*T {
+	for _, entry := range other {
+		if !slices.ContainsFunc(list, func(e *T) bool { return *e == *entry }) {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+// severityRank orders severities from none to block
+func severityRank(severity Severity) int {
+	switch severity {
+	case SeverityBlock:
+		return 2
+	case SeverityWarn:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// commonPathPrefix returns the longest slash-separated path prefix of two
+// import paths, "" when they share none
+func commonPathPrefix(a, b string) string {
+	x, y := strings.Split(a, "/"), strings.Split(b, "/")
+	n := 0
+	for n < len(x) && n < len(y) && x[n] == y[n] {
+		n++
+	}
+	return strings.Join(x[:n], "/")
+}
@@ -2,115 +2,315 @@ package analysis
 
 import (
 	"fmt"
+	"go/ast"
 	"go/parser"
 	"go/token"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"unicode"
 
 	"go.uber.org/zap"
 )
 
+// cgoPseudoImport is the import that enables cgo. It names no package.
+const cgoPseudoImport = "C"
+
 // Pkg represents a Go package and its dependencies
 type Pkg struct {
-	Name          string   // Package name (e.g., "github.com/org/repo/pkg/foo")
-	Files         []string // Source files in this package
-	Imports       []string // Direct imports
-	Dependencies  []*Pkg   // Resolved dependency tree
+	Name         string   // Package name (e.g., "github.com/org/repo/pkg/foo")
+	PackageName  string   // Package clause of the production files (e.g., "foo" or "main"), empty without non-test Go files
+	Files        []string // Source files in this package
+	Imports      []string // Direct imports
+	TestImports  []string // Direct imports of the test files, whether or not tests are analyzed
+	Dependencies []*Pkg   // Resolved dependency tree
 	Internal     bool     // Whether this is an internal package
+	Target       bool     // Whether the package directory holds the target marker file
 }
 
-// Tree represents a package dependency tree
+// Tree represents a package dependency tree. Its methods are safe for
+// concurrent use: resolutions are serialized and queries share a read lock.
+// Call Freeze once resolution is complete to make the tree read-only; the
+// Packages and Failed maps may then be read directly from any goroutine.
 type Tree struct {
-	Root        *Pkg              // Root package being analyzed
-	Packages    map[string]*Pkg   // All packages in the tree
-	RootDir     string           // Root directory of the project
-	RootPkgPath string          // Root package path (e.g., "github.com/org/repo")
+	Root         *Pkg             // Root package being analyzed
+	Packages     map[string]*Pkg  // All packages in the tree
+	RootDir      string           // Root directory of the project
+	RootPkgPath  string           // Root package path (e.g., "github.com/org/repo")
+	IncludeTests bool             // Whether test files contribute files and imports to their package
+	BuildTags    []string         // Tags set when evaluating build constraints, see excludedByBuildTags
+	TargetMarker string           // Name of the file marking a package directory as a high-level target, if any
+	Failed       map[string]error // Packages that could not be resolved
+
+	// LoadErrors maps the packages some files of which failed to parse to the
+	// first error. They are resolved from the files that parsed, so their
+	// imports may be incomplete.
+	LoadErrors map[string]error
+
+	// CaseMismatches maps the packages whose import path only matched their
+	// directory on a case-insensitive filesystem to the directory as named on
+	// disk, relative to RootDir. Such imports fail to build on Linux.
+	CaseMismatches map[string]string
+
+	// AdditionalInternal lists import path prefixes outside RootPkgPath that are
+	// treated as internal. Their packages are looked up in the vendor directory.
+	AdditionalInternal []string
+
+	// ModuleDirs maps the paths of other modules of a go.work workspace to
+	// their directories. Their packages are looked up there rather than in
+	// the vendor directory.
+	ModuleDirs map[string]string
+
+	// Exclude, when set, reports internal packages left out of the tree. They
+	// are neither resolved nor linked as dependencies.
+	Exclude func(pkgName string) bool
+
+	// Include, when set, reports external packages tracked as internal. Like
+	// those under AdditionalInternal they are looked up in the vendor
+	// directory, and imports of them are only retained when found there.
+	Include func(pkgName string) bool
+
+	// ResolutionKey identifies the configuration Exclude and Include were
+	// built from, which cannot be compared themselves. Checkpoints are only
+	// resumed under the same key.
+	ResolutionKey string
+
+	mu              sync.RWMutex
+	frozen          bool
+	reverse         map[string][]*Pkg // Reverse dependency index, cached once frozen
+	digests         map[string]string // Digests of resolved packages, see trackDigests
+	caseInsensitive *bool             // Whether RootDir is on a case-insensitive filesystem, once known
 }
 
 // NewTree creates a new dependency tree for analysis
 func NewTree(rootDir, rootPkgPath string) *Tree {
 	return &Tree{
-		Packages:    make(map[string]*Pkg),
-		RootDir:     rootDir,
-		RootPkgPath: rootPkgPath,
+		Packages:       make(map[string]*Pkg),
+		Failed:         make(map[string]error),
+		LoadErrors:     make(map[string]error),
+		CaseMismatches: make(map[string]string),
+		RootDir:        rootDir,
+		RootPkgPath:    rootPkgPath,
+	}
+}
+
+// Freeze marks the tree read-only. Resolving a package that is not already in
+// a frozen tree fails.
+func (t *Tree) Freeze() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.frozen {
+		return
 	}
+	t.reverse = t.buildReverseIndex()
+	t.frozen = true
 }
 
-// Resolve builds the dependency tree for a given package
+// Frozen reports whether Freeze was called
+func (t *Tree) Frozen() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.frozen
+}
+
+// Resolve builds the dependency tree for a given package. Imports are resolved
+// with an explicit work queue rather than recursion, so arbitrarily deep import
+// chains are bounded by heap rather than stack.
 func (t *Tree) Resolve(pkgName string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
 	// Check if we've already resolved this package
 	if _, ok := t.Packages[pkgName]; ok {
 		return nil // Already resolved
 	}
+	if t.frozen {
+		return fmt.Errorf("failed to resolve %s: tree is frozen", pkgName)
+	}
+
+	var resolved []*Pkg
+	var rootErr error
+	queue := []string{pkgName}
+
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		if _, ok := t.Packages[name]; ok {
+			continue
+		}
+
+		pkg, err := t.resolvePackage(name)
+		resolved = append(resolved, pkg)
+		t.recordDigest(name)
+		if err != nil {
+			if name == pkgName {
+				rootErr = err
+			} else {
+				zap.S().Warnw("failed to resolve import, continuing", "import", name, "error", err)
+			}
+			continue
+		}
+
+		// Queue imports that have not been seen yet
+		for _, importPath := range pkg.Imports {
+			if _, ok := t.Packages[importPath]; !ok {
+				queue = append(queue, importPath)
+			}
+		}
+	}
+
+	// Link dependencies once every reachable package is registered
+	for _, pkg := range resolved {
+		for _, importPath := range pkg.Imports {
+			if _, failed := t.Failed[importPath]; failed {
+				continue
+			}
+			if depPkg, ok := t.Packages[importPath]; ok {
+				pkg.Dependencies = append(pkg.Dependencies, depPkg)
+			}
+		}
+	}
+
+	return rootErr
+}
 
+// resolvePackage registers a single package in the tree and collects its
+// internal imports, without resolving them.
+func (t *Tree) resolvePackage(pkgName string) (*Pkg, error) {
 	// Create new package
 	pkg := &Pkg{
 		Name:     pkgName,
-		Internal: strings.HasPrefix(pkgName, t.RootPkgPath),
+		Internal: t.IsInternal(pkgName),
 		Files:    make([]string, 0),
 		Imports:  make([]string, 0),
 	}
 	t.Packages[pkgName] = pkg
 
-	// Convert package path to filesystem path
-	relPath := strings.TrimPrefix(pkgName, t.RootPkgPath)
-	relPath = strings.TrimPrefix(relPath, "/")
-	pkgPath := filepath.Join(t.RootDir, relPath)
+	pkgPath := t.packageDir(pkgName)
+
+	// Import paths come from the analyzed code, so one such as
+	// "github.com/org/repo/../../etc" must not lead outside the repository
+	rootDir := t.RootDir
+	if modulePath, dir := t.workspaceModule(pkgName); modulePath != "" {
+		rootDir = dir
+	}
+	if !withinDir(rootDir, pkgPath) {
+		zap.S().Warnw("package directory is outside the repository root, refusing to resolve", "package", pkgName, "path", pkgPath)
+		t.Failed[pkgName] = fmt.Errorf("failed to resolve package %s: %s is outside the repository root", pkgName, pkgPath)
+		return pkg, t.Failed[pkgName]
+	}
 
 	// Check if directory exists
 	if _, err := os.Stat(pkgPath); os.IsNotExist(err) {
 		zap.S().Warnw("package directory not found, skipping", "package", pkgName, "path", pkgPath)
-		return nil
+		return pkg, nil
+	}
+
+	if t.TargetMarker != "" {
+		if info, err := os.Stat(filepath.Join(pkgPath, t.TargetMarker)); err == nil && !info.IsDir() {
+			pkg.Target = true
+		}
+	}
+
+	if t.onCaseInsensitiveFS() {
+		if rel, err := filepath.Rel(t.RootDir, pkgPath); err == nil {
+			if onDisk := onDiskPath(t.RootDir, rel); onDisk != filepath.ToSlash(rel) {
+				zap.S().Warnw("import path does not match the case of the package directory", "package", pkgName, "directory", onDisk)
+				t.CaseMismatches[pkgName] = onDisk
+			}
+		}
 	}
 
 	zap.S().Debugw("resolving dependencies for package", "package", pkgName, "path", pkgPath)
 
 	// Parse package files
 	fset := token.NewFileSet()
-	pkgs, err := parser.ParseDir(fset, pkgPath, nil, parser.ImportsOnly)
+	// Files excluded by their build constraint, such as generators marked with
+	// the ignore tag, are not part of the package
+	buildable := func(info os.FileInfo) bool {
+		return !excludedByBuildTags(filepath.Join(pkgPath, info.Name()), t.BuildTags)
+	}
+	pkgs, err := parser.ParseDir(fset, pkgPath, buildable, parser.ImportsOnly)
 	if err != nil {
-		return fmt.Errorf("failed to parse package %s at %s: %w", pkgName, pkgPath, err)
+		if len(pkgs) == 0 {
+			t.Failed[pkgName] = fmt.Errorf("failed to parse package %s at %s: %w", pkgName, pkgPath, err)
+			return pkg, t.Failed[pkgName]
+		}
+		// Keep the files that parsed rather than losing the whole package
+		zap.S().Warnw("failed to parse some files of package, continuing with the others", "package", pkgName, "path", pkgPath, "error", err)
+		t.LoadErrors[pkgName] = err
 	}
 
 	if len(pkgs) == 0 {
-		return fmt.Errorf("no Go packages found in directory %s", pkgPath)
+		// A directory without Go files, e.g. one holding only assets, is an
+		// empty package rather than a failure
+		zap.S().Debugw("no Go files in package directory, recording an empty package", "package", pkgName, "path", pkgPath)
+		return pkg, nil
+	}
+
+	// Select the production package of the directory and its external test
+	// package. Test files only contribute to Files and Imports when tests are
+	// part of the analysis, but their imports are always recorded separately.
+	prodName := selectPackage(pkgs, filepath.Base(pkgPath))
+	var selected []*ast.Package
+	if prodPkg, ok := pkgs[prodName]; ok {
+		selected = append(selected, prodPkg)
+		for file := range prodPkg.Files {
+			// Test files alone declare no production package
+			if !strings.HasSuffix(file, "_test.go") {
+				pkg.PackageName = prodName
+				break
+			}
+		}
+	}
+	if testPkg, ok := pkgs[prodName+"_test"]; ok {
+		selected = append(selected, testPkg)
 	}
 
 	// Track unique imports to avoid duplicates
 	importSet := make(map[string]bool)
+	testImportSet := make(map[string]bool)
 
-	// Collect all imports from all files in all packages
-	for _, parsedPkg := range pkgs {
-		for filename, file := range parsedPkg.Files {
-			// Skip test files
-			if strings.HasSuffix(filename, "_test.go") {
-				continue
-			}
+	// Collect all imports from the files of the selected packages
+	for _, parsedPkg := range selected {
+		filenames := make([]string, 0, len(parsedPkg.Files))
+		for filename := range parsedPkg.Files {
+			filenames = append(filenames, filename)
+		}
+		sort.Strings(filenames)
+
+		for _, filename := range filenames {
+			file := parsedPkg.Files[filename]
+			isTest := strings.HasSuffix(filename, "_test.go")
 
-			// Add the file to our list
-			pkg.Files = append(pkg.Files, filename)
+			// Add the file to our list unless it is a test file left out of the analysis
+			analyzed := !isTest || t.IncludeTests
+			if analyzed {
+				pkg.Files = append(pkg.Files, filename)
+			}
 
 			// Process imports
 			for _, imp := range file.Imports {
 				// Remove quotes from import path
 				importPath := strings.Trim(imp.Path.Value, "\"")
+				if importPath == cgoPseudoImport {
+					continue
+				}
 
-				// Only include internal imports and avoid duplicates
-				if strings.HasPrefix(importPath, t.RootPkgPath) && !importSet[importPath] {
+				// Only include internal imports and avoid duplicates. External test
+				// packages import the package under test, which is not a dependency.
+				if !t.IsInternal(importPath) || t.excluded(importPath) || importPath == pkgName {
+					continue
+				}
+				if isTest && !testImportSet[importPath] {
+					testImportSet[importPath] = true
+					pkg.TestImports = append(pkg.TestImports, importPath)
+				}
+				if analyzed && !importSet[importPath] {
 					importSet[importPath] = true
 					pkg.Imports = append(pkg.Imports, importPath)
-
-					// Recursively resolve the imported package
-					if err := t.Resolve(importPath); err != nil {
-						zap.S().Warnw("failed to resolve import, continuing", "import", importPath, "error", err)
-						continue
-					}
-
-					// Add to dependencies
-					if depPkg, ok := t.Packages[importPath]; ok {
-						pkg.Dependencies = append(pkg.Dependencies, depPkg)
-					}
 				}
 			}
 		}
@@ -118,11 +318,54 @@ func (t *Tree) Resolve(pkgName string) error {
 
 	zap.S().Debugw("package processed", "package", pkgName, "files", len(pkg.Files), "imports", len(pkg.Imports))
 
-	return nil
+	return pkg, nil
+}
+
+// selectPackage picks the production package among the packages parsed from a
+// single directory. External test packages (foo_test) are never selected. When
+// several candidates remain, the one named after the directory wins, then a
+// library package over main (typically a standalone generator), then the
+// lexically first name so the choice is deterministic.
+func selectPackage(pkgs map[string]*ast.Package, dirName string) string {
+	var candidates []string
+	for name := range pkgs {
+		if !strings.HasSuffix(name, "_test") {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+
+	switch len(candidates) {
+	case 0:
+		// Only an external test package exists; treat it as the package
+		for name := range pkgs {
+			return strings.TrimSuffix(name, "_test")
+		}
+		return ""
+	case 1:
+		return candidates[0]
+	}
+
+	expected := strings.ReplaceAll(dirName, "-", "_")
+	for _, name := range candidates {
+		if name == expected {
+			return name
+		}
+	}
+	for _, name := range candidates {
+		if name != "main" {
+			zap.S().Warnw("multiple packages in directory, selecting library package", "dir", dirName, "packages", candidates, "selected", name)
+			return name
+		}
+	}
+	return candidates[0]
 }
 
 // FindReverseDependencies returns all packages that depend on the given package
 func (t *Tree) FindReverseDependencies(pkgName string) []*Pkg {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
 	var deps []*Pkg
 	for _, pkg := range t.Packages {
 		// Skip the package itself
@@ -144,7 +387,165 @@ func (t *Tree) FindReverseDependencies(pkgName string) []*Pkg {
 	return deps
 }
 
-// IsInternal checks if a package is internal to the project
+// FindTransitiveReverseDependencies returns every package that depends on the
+// given package directly or transitively, mapped to the minimum number of
+// reverse-dependency hops separating them (1 = direct importer). A positive
+// maxDepth bounds the number of hops followed.
+func (t *Tree) FindTransitiveReverseDependencies(pkgName string, maxDepth int) map[string]int {
+	return reverseDepths(t.reverseDependencyIndex(), pkgName, maxDepth)
+}
+
+// reverseDepths runs a breadth-first search over the reverse dependency index
+// from pkgName, recording the depth at which each package is first reached.
+func reverseDepths(reverse map[string][]*Pkg, pkgName string, maxDepth int) map[string]int {
+	depths := make(map[string]int)
+	queue := []string{pkgName}
+	for depth := 1; len(queue) > 0 && (maxDepth <= 0 || depth <= maxDepth); depth++ {
+		var next []string
+		for _, current := range queue {
+			for _, importer := range reverse[current] {
+				if _, seen := depths[importer.Name]; seen || importer.Name == pkgName {
+					continue
+				}
+				depths[importer.Name] = depth
+				next = append(next, importer.Name)
+			}
+		}
+		queue = next
+	}
+	return depths
+}
+
+// IsInternal checks if a package is internal to the project, either under the
+// root package path or one of the additional internal prefixes, or included by
+// pattern and present in the vendor directory
 func (t *Tree) IsInternal(pkgName string) bool {
-	return strings.HasPrefix(pkgName, t.RootPkgPath)
-} 
\ No newline at end of file
+	if pkgName == t.RootPkgPath || strings.HasPrefix(pkgName, t.RootPkgPath+"/") {
+		return true
+	}
+	for _, prefix := range t.AdditionalInternal {
+		if pkgName == prefix || strings.HasPrefix(pkgName, prefix+"/") {
+			return true
+		}
+	}
+	if t.Include != nil && t.Include(pkgName) {
+		info, err := os.Stat(t.packageDir(pkgName))
+		return err == nil && info.IsDir()
+	}
+	return false
+}
+
+// onCaseInsensitiveFS reports whether the root directory is on a
+// case-insensitive filesystem, where os.Stat finds a directory whatever the
+// case of the path. The caller must hold the write lock.
+func (t *Tree) onCaseInsensitiveFS() bool {
+	if t.caseInsensitive == nil {
+		insensitive := caseInsensitiveDir(t.RootDir)
+		t.caseInsensitive = &insensitive
+	}
+	return *t.caseInsensitive
+}
+
+// caseInsensitiveDir reports whether dir, or its closest ancestor with a
+// letter in its name, can also be reached with the case of that name swapped.
+// When no such ancestor exists, the filesystem is assumed case-sensitive.
+func caseInsensitiveDir(dir string) bool {
+	for {
+		base := filepath.Base(dir)
+		swapped := strings.Map(func(r rune) rune {
+			if unicode.IsUpper(r) {
+				return unicode.ToLower(r)
+			}
+			return unicode.ToUpper(r)
+		}, base)
+		if swapped != base {
+			info, err := os.Stat(dir)
+			if err != nil {
+				return false
+			}
+			swappedInfo, err := os.Stat(filepath.Join(filepath.Dir(dir), swapped))
+			return err == nil && os.SameFile(info, swappedInfo)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
+// onDiskPath returns the slash-separated path rel below root with each element
+// named as on disk. Elements found only by a case-insensitive match take the
+// case of the directory entry.
+func onDiskPath(root, rel string) string {
+	current := root
+	var parts []string
+	for _, name := range strings.Split(filepath.ToSlash(rel), "/") {
+		if name == "" || name == "." {
+			continue
+		}
+		entries, err := os.ReadDir(current)
+		if err == nil && !hasEntry(entries, name) {
+			for _, entry := range entries {
+				if strings.EqualFold(entry.Name(), name) {
+					name = entry.Name()
+					break
+				}
+			}
+		}
+		parts = append(parts, name)
+		current = filepath.Join(current, name)
+	}
+	return strings.Join(parts, "/")
+}
+
+// hasEntry reports whether entries contain one named exactly name
+func hasEntry(entries []os.DirEntry, name string) bool {
+	for _, entry := range entries {
+		if entry.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// excluded reports whether a package is left out of the tree
+func (t *Tree) excluded(pkgName string) bool {
+	return t.Exclude != nil && t.Exclude(pkgName)
+}
+
+// packageDir converts an internal package path to its directory. Packages of
+// other workspace modules live in their module directory, and those under an
+// additional internal prefix or included by pattern in the vendor directory.
+func (t *Tree) packageDir(pkgName string) string {
+	if modulePath, dir := t.workspaceModule(pkgName); modulePath != "" {
+		relPath, _ := DirFor(modulePath, pkgName)
+		return filepath.Join(dir, filepath.FromSlash(relPath))
+	}
+	if relPath, ok := DirFor(t.RootPkgPath, pkgName); ok {
+		return filepath.Join(t.RootDir, filepath.FromSlash(relPath))
+	}
+	return filepath.Join(t.RootDir, "vendor", filepath.FromSlash(pkgName))
+}
+
+// workspaceModule returns the path and directory of the workspace module
+// holding a package, the one with the longest path when modules nest, or ""
+// when the package belongs to none
+func (t *Tree) workspaceModule(pkgName string) (modulePath, dir string) {
+	for path, moduleDir := range t.ModuleDirs {
+		if _, ok := DirFor(path, pkgName); ok && len(path) > len(modulePath) {
+			modulePath, dir = path, moduleDir
+		}
+	}
+	return modulePath, dir
+}
+
+// withinDir reports whether path is dir or lies below it
+func withinDir(dir, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
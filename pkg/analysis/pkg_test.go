@@ -0,0 +1,436 @@
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTreeResolve_ExternalTestPackage(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"foo/foo.go": fmt.Sprintf(`package foo
+
+import "%s/bar"
+
+func Foo() { bar.Bar() }`, rootPkg),
+		"foo/foo_ext_test.go": fmt.Sprintf(`package foo_test
+
+import (
+	"testing"
+
+	"%[1]s/baz"
+	"%[1]s/foo"
+)
+
+func TestFoo(t *testing.T) {
+	baz.Baz()
+	foo.Foo()
+}`, rootPkg),
+		"bar/bar.go": "package bar\n\nfunc Bar() {}",
+		"baz/baz.go": "package baz\n\nfunc Baz() {}",
+	})
+
+	t.Run("production imports only", func(t *testing.T) {
+		tree := NewTree(repoPath, rootPkg)
+		require.NoError(t, tree.Resolve(rootPkg+"/foo"))
+
+		foo := tree.Packages[rootPkg+"/foo"]
+		require.Equal(t, []string{rootPkg + "/bar"}, foo.Imports)
+		require.Len(t, foo.Files, 1)
+	})
+
+	t.Run("with tests", func(t *testing.T) {
+		tree := NewTree(repoPath, rootPkg)
+		tree.IncludeTests = true
+		require.NoError(t, tree.Resolve(rootPkg+"/foo"))
+
+		foo := tree.Packages[rootPkg+"/foo"]
+		require.ElementsMatch(t, []string{rootPkg + "/bar", rootPkg + "/baz"}, foo.Imports)
+		require.Len(t, foo.Files, 2)
+	})
+}
+
+func TestTreeResolve_PackageName(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"cmd/server/main.go":     fmt.Sprintf("package main\n\nimport \"%s/foo\"\n\nfunc main() { foo.Foo() }", rootPkg),
+		"foo/foo.go":             "package foo\n\nfunc Foo() {}",
+		"foo/foo_ext_test.go":    "package foo_test",
+		"go-bar/bar.go":          "package bar",
+		"assets/README":          "not Go",
+		"onlytests/only_test.go": "package onlytests_test",
+		"intests/in_test.go":     "package intests",
+	})
+
+	tree := NewTree(repoPath, rootPkg)
+	for _, dir := range []string{"cmd/server", "go-bar", "assets", "onlytests", "intests"} {
+		require.NoError(t, tree.Resolve(rootPkg+"/"+dir))
+	}
+
+	require.Equal(t, "main", tree.Packages[rootPkg+"/cmd/server"].PackageName)
+	require.Equal(t, "foo", tree.Packages[rootPkg+"/foo"].PackageName, "the external test package is not selected")
+	require.Equal(t, "bar", tree.Packages[rootPkg+"/go-bar"].PackageName)
+	require.Empty(t, tree.Packages[rootPkg+"/assets"].PackageName)
+	require.Empty(t, tree.Packages[rootPkg+"/onlytests"].PackageName, "no file declares package onlytests")
+	require.Empty(t, tree.Packages[rootPkg+"/intests"].PackageName, "test files declare no production package")
+}
+
+func TestTreeResolve_AliasedImportsAcrossFiles(t *testing.T) {
+	// app imports lib under two aliases in one file, and again under other
+	// names in two more files, one of which also imports util
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"app/a.go": fmt.Sprintf(`package app
+
+import (
+	l "%[1]s/lib"
+	lib2 "%[1]s/lib"
+)
+
+func A() { l.L(); lib2.L() }`, rootPkg),
+		"app/b.go": fmt.Sprintf(`package app
+
+import _ "%[1]s/lib"
+import "%[1]s/util"
+
+func B() { util.U() }`, rootPkg),
+		"app/c.go":       fmt.Sprintf("package app\n\nimport . \"%s/lib\"\n\nfunc C() { L() }", rootPkg),
+		"lib/lib.go":     "package lib\n\nfunc L() {}",
+		"util/util.go":   "package util\n\nfunc U() {}",
+		"other/other.go": fmt.Sprintf("package other\n\nimport lib \"%s/lib\"\n\nfunc O() { lib.L() }", rootPkg),
+	})
+
+	tree := NewTree(repoPath, rootPkg)
+	require.NoError(t, tree.Resolve(rootPkg+"/app"))
+	require.NoError(t, tree.Resolve(rootPkg+"/other"))
+
+	app := tree.Packages[rootPkg+"/app"]
+	require.Len(t, app.Files, 3)
+	require.Equal(t, []string{rootPkg + "/lib", rootPkg + "/util"}, app.Imports, "each path is imported once, whatever its names")
+	require.Len(t, app.Dependencies, 2)
+
+	var importers []string
+	for _, pkg := range tree.FindReverseDependencies(rootPkg + "/lib") {
+		importers = append(importers, pkg.Name)
+	}
+	require.ElementsMatch(t, []string{rootPkg + "/app", rootPkg + "/other"}, importers)
+}
+
+func TestTreeResolve_MainAlongsideLibrary(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"tools/tools.go": fmt.Sprintf(`package tools
+
+import "%s/bar"
+
+func Tool() { bar.Bar() }`, rootPkg),
+		"tools/gen.go": fmt.Sprintf(`package main
+
+import "%s/baz"
+
+func main() { baz.Baz() }`, rootPkg),
+		"bar/bar.go": "package bar\n\nfunc Bar() {}",
+		"baz/baz.go": "package baz\n\nfunc Baz() {}",
+		"cmd/app/main.go": fmt.Sprintf(`package main
+
+import "%s/bar"
+
+func main() { bar.Bar() }`, rootPkg),
+	})
+
+	tree := NewTree(repoPath, rootPkg)
+	require.NoError(t, tree.Resolve(rootPkg+"/tools"))
+	require.Equal(t, []string{rootPkg + "/bar"}, tree.Packages[rootPkg+"/tools"].Imports)
+
+	// A directory holding only a main package resolves to it
+	require.NoError(t, tree.Resolve(rootPkg+"/cmd/app"))
+	require.Equal(t, []string{rootPkg + "/bar"}, tree.Packages[rootPkg+"/cmd/app"].Imports)
+}
+
+func TestTreeResolve_OnlyExternalTestPackage(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"e2e/e2e_test.go": "package e2e_test\n\nimport \"testing\"\n\nfunc TestE2E(t *testing.T) {}",
+	})
+
+	tree := NewTree(repoPath, rootPkg)
+	require.NoError(t, tree.Resolve(rootPkg+"/e2e"))
+	require.Empty(t, tree.Packages[rootPkg+"/e2e"].Files)
+}
+
+func TestTreeResolve_NoProductionFiles(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"app/app.go":               "package app\n\nimport _ \"github.com/a/b/assets\"\n",
+		"assets/logo.svg":          "<svg/>",
+		"testonly/helpers_test.go": "package testonly\n\nimport _ \"github.com/a/b/app\"\n",
+	})
+
+	tree := NewTree(repoPath, rootPkg)
+	require.NoError(t, tree.Resolve(rootPkg+"/app"))
+	require.NoError(t, tree.Resolve(rootPkg+"/testonly"))
+
+	// Both directories yield empty packages without failures
+	require.Empty(t, tree.Failed)
+	require.Empty(t, tree.Packages[rootPkg+"/assets"].Files)
+	require.Empty(t, tree.Packages[rootPkg+"/testonly"].Files)
+	require.Empty(t, tree.Packages[rootPkg+"/testonly"].Imports)
+	require.Equal(t, []string{rootPkg + "/app"}, tree.Packages[rootPkg+"/testonly"].TestImports)
+}
+
+func TestTreeResolve_RefusesPathOutsideRoot(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	dir := writeRepo(t, map[string]string{
+		"repo/app/app.go":  "package app\n\nimport _ \"github.com/a/b/../secret\"\n",
+		"secret/secret.go": "package secret\n\nimport _ \"github.com/a/b/app\"\n",
+	})
+	repoPath := filepath.Join(dir, "repo")
+	escaping := rootPkg + "/../secret"
+
+	tree := NewTree(repoPath, rootPkg)
+	require.NoError(t, tree.Resolve(rootPkg+"/app"))
+
+	// The escaping import is refused rather than parsed
+	require.Contains(t, tree.Failed, escaping)
+	require.Empty(t, tree.Packages[escaping].Files)
+	require.Empty(t, tree.Packages[rootPkg+"/app"].Dependencies)
+
+	// Resolving it directly is an error
+	err := NewTree(repoPath, rootPkg).Resolve(escaping)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "outside the repository root")
+}
+
+func TestTreeResolve_SkipsIgnoreBuildTag(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"app/app.go":        "package app\n\nimport _ \"github.com/a/b/lib\"\n",
+		"app/gen.go":        "//go:build ignore\n\n// Command gen generates the tables\npackage main\n\nimport _ \"github.com/a/b/tools\"\n",
+		"app/legacy_gen.go": "// +build ignore\n\npackage main\n\nimport _ \"github.com/a/b/legacy\"\n",
+		"app/linux.go":      "//go:build linux && !ignore\n\npackage app\n\nimport _ \"github.com/a/b/sys\"\n",
+		"lib/lib.go":        "package lib\n",
+		"sys/sys.go":        "package sys\n",
+		"tools/tools.go":    "package tools\n",
+		"legacy/legacy.go":  "package legacy\n",
+	})
+
+	tree := NewTree(repoPath, rootPkg)
+	require.NoError(t, tree.Resolve(rootPkg+"/app"))
+
+	// The generators are left out, other constrained files are kept
+	app := tree.Packages[rootPkg+"/app"]
+	require.Equal(t, []string{rootPkg + "/lib", rootPkg + "/sys"}, app.Imports)
+	require.Equal(t, []string{filepath.Join(repoPath, "app", "app.go"), filepath.Join(repoPath, "app", "linux.go")}, app.Files)
+	require.NotContains(t, tree.Packages, rootPkg+"/tools")
+}
+
+func TestExcludedByBuildTags(t *testing.T) {
+	for constraint, ignored := range map[string]bool{
+		"":                                  false,
+		"//go:build ignore":                 true,
+		"//go:build ignore && linux":        true,
+		"//go:build ignore || linux":        false,
+		"//go:build !linux":                 false,
+		"//go:build !ignore":                false,
+		"// +build ignore":                  true,
+		"// +build linux\n// +build ignore": true,
+	} {
+		path := filepath.Join(t.TempDir(), "gen.go")
+		require.NoError(t, os.WriteFile(path, []byte(constraint+"\n\npackage main\n"), 0644))
+		require.Equal(t, ignored, excludedByBuildTags(path, nil), constraint)
+	}
+
+	// With build tags, other tags are set exactly when listed
+	for constraint, tags := range map[string]map[string]bool{
+		"//go:build foo":             {"foo": false, "bar": true},
+		"//go:build !foo":            {"foo": true, "bar": false},
+		"//go:build foo && linux":    {"foo": false, "bar": true},
+		"//go:build foo && !windows": {"foo": false, "bar": true},
+		"//go:build linux && !linux": {"foo": true, "bar": true},
+		"//go:build go1.21 && cgo":   {"foo": false, "bar": false},
+		"//go:build ignore":          {"foo": true, "ignore": false},
+	} {
+		path := filepath.Join(t.TempDir(), "tagged.go")
+		require.NoError(t, os.WriteFile(path, []byte(constraint+"\n\npackage main\n"), 0644))
+		for tag, excluded := range tags {
+			require.Equal(t, excluded, excludedByBuildTags(path, []string{tag}), "%s with tag %s", constraint, tag)
+		}
+	}
+}
+
+func TestTreeResolve_BuildTags(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"app/app.go":   "package app",
+		"app/foo.go":   fmt.Sprintf("//go:build foo\n\npackage app\n\nimport _ \"%s/extra\"\n", rootPkg),
+		"app/linux.go": fmt.Sprintf("//go:build linux\n\npackage app\n\nimport _ \"%s/platform\"\n", rootPkg),
+	})
+
+	for _, tt := range []struct {
+		name    string
+		tags    []string
+		imports []string
+	}{
+		{name: "any tag", tags: nil, imports: []string{rootPkg + "/extra", rootPkg + "/platform"}},
+		{name: "tag not configured", tags: []string{"bar"}, imports: []string{rootPkg + "/platform"}},
+		{name: "tag configured", tags: []string{"foo"}, imports: []string{rootPkg + "/extra", rootPkg + "/platform"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tree := NewTree(repoPath, rootPkg)
+			tree.BuildTags = tt.tags
+			require.NoError(t, tree.Resolve(rootPkg+"/app"))
+			require.ElementsMatch(t, tt.imports, tree.Packages[rootPkg+"/app"].Imports)
+		})
+	}
+}
+
+func TestTreeFindTransitiveReverseDependencies(t *testing.T) {
+	// d -> c -> b -> a, and d also imports b directly
+	tree := newTestTree(map[string][]string{
+		"example.com/m/a": nil,
+		"example.com/m/b": {"example.com/m/a"},
+		"example.com/m/c": {"example.com/m/b"},
+		"example.com/m/d": {"example.com/m/c", "example.com/m/b"},
+		"example.com/m/e": nil,
+	})
+
+	require.Equal(t, map[string]int{
+		"example.com/m/b": 1,
+		"example.com/m/c": 2,
+		"example.com/m/d": 2,
+	}, tree.FindTransitiveReverseDependencies("example.com/m/a", 0))
+
+	// The search is bounded by the maximum depth
+	require.Equal(t, map[string]int{
+		"example.com/m/b": 1,
+	}, tree.FindTransitiveReverseDependencies("example.com/m/a", 1))
+}
+
+func TestTreeResolve_DeepChain(t *testing.T) {
+	// p0 imports p1, which imports p2, and so on
+	const depth = 3000
+	rootPkg := "github.com/a/b"
+	files := make(map[string]string, depth)
+	for i := 0; i < depth-1; i++ {
+		files[fmt.Sprintf("p%d/p.go", i)] = fmt.Sprintf("package p%d\n\nimport _ \"%s/p%d\"\n", i, rootPkg, i+1)
+	}
+	files[fmt.Sprintf("p%d/p.go", depth-1)] = fmt.Sprintf("package p%d\n", depth-1)
+	repoPath := writeRepo(t, files)
+
+	tree := NewTree(repoPath, rootPkg)
+	require.NoError(t, tree.Resolve(rootPkg+"/p0"))
+	require.Len(t, tree.Packages, depth)
+
+	for i := 0; i < depth-1; i++ {
+		deps := tree.Packages[fmt.Sprintf("%s/p%d", rootPkg, i)].Dependencies
+		require.Len(t, deps, 1)
+		require.Equal(t, fmt.Sprintf("%s/p%d", rootPkg, i+1), deps[0].Name)
+	}
+
+	depths := tree.FindTransitiveReverseDependencies(fmt.Sprintf("%s/p%d", rootPkg, depth-1), 0)
+	require.Len(t, depths, depth-1)
+	require.Equal(t, depth-1, depths[rootPkg+"/p0"])
+}
+
+func TestTreeResolve_CgoAndEmbed(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod":       "module " + rootPkg,
+		"util/util.go": "package util\n\nfunc U() {}",
+		"native/native.go": fmt.Sprintf(`package native
+
+/*
+#include <stdlib.h>
+*/
+import "C"
+
+import (
+	_ "embed"
+
+	"%s/util"
+)
+
+//go:embed schema.sql
+var schema string
+
+func N() { util.U(); C.free(nil) }`, rootPkg),
+		"native/schema.sql": "CREATE TABLE t (id INT);",
+	})
+
+	tree := NewTree(repoPath, rootPkg)
+	require.NoError(t, tree.Resolve(rootPkg+"/native"))
+
+	pkg := tree.Packages[rootPkg+"/native"]
+	require.Equal(t, []string{rootPkg + "/util"}, pkg.Imports)
+	require.Equal(t, []string{filepath.Join(repoPath, "native", "native.go")}, pkg.Files, "embedded files are not package files")
+	for name, p := range tree.Packages {
+		require.NotEqual(t, "C", name)
+		require.NotContains(t, p.Imports, "C")
+	}
+}
+
+func TestTree_ConcurrentQueries(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"a/a.go": "package a",
+		"b/b.go": "package b\n\nimport _ \"github.com/a/b/a\"\n",
+		"c/c.go": "package c\n\nimport _ \"github.com/a/b/b\"\n",
+		"d/d.go": "package d\n\nimport _ \"github.com/a/b/a\"\n",
+	})
+	tree := NewTree(repoPath, rootPkg)
+
+	// Concurrent resolutions are serialized
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b", "c", "d"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, tree.Resolve(rootPkg+"/"+name))
+		}()
+	}
+	wg.Wait()
+	require.Len(t, tree.Packages, 4)
+
+	tree.Freeze()
+	require.True(t, tree.Frozen())
+	require.NoError(t, tree.Resolve(rootPkg+"/a"), "resolved packages are still accepted")
+	require.ErrorContains(t, tree.Resolve(rootPkg+"/e"), "tree is frozen")
+
+	want := map[string]int{rootPkg + "/b": 1, rootPkg + "/c": 2, rootPkg + "/d": 1}
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			require.Equal(t, want, tree.FindTransitiveReverseDependencies(rootPkg+"/a", 0))
+			require.Len(t, tree.FindReverseDependencies(rootPkg+"/a"), 2)
+			require.Len(t, tree.ImpactGraph([]string{rootPkg + "/a"}).Nodes, 4)
+			require.NotEmpty(t, tree.Chokepoints(0))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestTreeResolve_CaseMismatch(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"app/app.go":        "package app\n\nimport _ \"github.com/a/b/Util/Strings\"\n",
+		"util/strings/s.go": "package strings",
+	})
+	// Directory names are looked up as on disk whatever the filesystem
+	require.Equal(t, "util/strings", onDiskPath(repoPath, filepath.FromSlash("Util/Strings")))
+	require.Equal(t, "app", onDiskPath(repoPath, "app"))
+
+	if _, err := os.Stat(filepath.Join(repoPath, "UTIL")); err != nil {
+		t.Skip("the filesystem is case-sensitive")
+	}
+
+	tree := NewTree(repoPath, rootPkg)
+	require.NoError(t, tree.Resolve(rootPkg+"/app"))
+	require.Equal(t, map[string]string{rootPkg + "/Util/Strings": "util/strings"}, tree.CaseMismatches)
+	require.Empty(t, tree.CaseMismatches[rootPkg+"/app"])
+}
@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SetRenamedFiles records files that were moved by the change, mapping each new
+// path to its previous path. Packages whose directory was moved away entirely
+// are reported as renamed instead of as a removed and an added package.
+func (a *Analyzer) SetRenamedFiles(renames map[string]string) {
+	a.renamedFiles = renames
+}
+
+// packageRenames returns the packages moved to another directory, mapping the
+// previous import path to the new one. A package only counts as moved when its
+// previous directory no longer holds any Go files.
+func (a *Analyzer) packageRenames() map[string]string {
+	renames := make(map[string]string)
+	newPaths := make([]string, 0, len(a.renamedFiles))
+	for newPath := range a.renamedFiles {
+		newPaths = append(newPaths, newPath)
+	}
+	sort.Strings(newPaths)
+
+	for _, newPath := range newPaths {
+		oldPath := a.renamedFiles[newPath]
+		if !strings.HasSuffix(newPath, ".go") || !strings.HasSuffix(oldPath, ".go") {
+			continue
+		}
+		oldPath, oldInModule := a.moduleFile(oldPath)
+		newPath, newInModule := a.moduleFile(newPath)
+		if !oldInModule || !newInModule {
+			continue
+		}
+		oldDir, newDir := path.Dir(oldPath), path.Dir(newPath)
+		if oldDir == newDir || a.hasGoFiles(oldDir) {
+			continue
+		}
+		oldPkg, oldOK := a.packageForDir(oldDir)
+		newPkg, newOK := a.packageForDir(newDir)
+		if !oldOK || !newOK {
+			continue
+		}
+		if _, seen := renames[oldPkg]; !seen {
+			renames[oldPkg] = newPkg
+		}
+	}
+	return renames
+}
+
+// hasGoFiles reports whether the module directory dir contains Go files
+func (a *Analyzer) hasGoFiles(dir string) bool {
+	goFiles, _ := filepath.Glob(filepath.Join(a.cfg.ModuleRoot(a.repoPath), filepath.FromSlash(dir), "*.go"))
+	return len(goFiles) > 0
+}
+
+// renamedFrom inverts package renames, mapping each new import path to the
+// previous one
+func renamedFrom(renames map[string]string) map[string]string {
+	from := make(map[string]string, len(renames))
+	for oldPkg, newPkg := range renames {
+		from[newPkg] = oldPkg
+	}
+	return from
+}
@@ -0,0 +1,52 @@
+package analysis
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeChangedPackages_PackageRename(t *testing.T) {
+	// internal/old was moved to internal/new, one of its files was deleted on the
+	// way, and a helper moved from util into the still existing lib package
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod":            "module " + rootPkg,
+		"internal/new/a.go": "package new\n\nfunc A() {}",
+		"internal/new/b.go": "package new\n\nfunc B() {}",
+		"util/util.go":      "package util\n\nfunc U() {}",
+		"lib/lib.go":        "package lib\n\nfunc L() {}",
+		"lib/helper.go":     "package lib\n\nfunc H() {}",
+		"app/app.go":        fmt.Sprintf("package app\n\nimport \"%s/internal/new\"\n\nfunc App() { new.A() }", rootPkg),
+	})
+
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetRootPackage(rootPkg)
+	analyzer.SetRenamedFiles(map[string]string{
+		"internal/new/a.go": "internal/old/a.go",
+		"internal/new/b.go": "internal/old/b.go",
+		"lib/helper.go":     "util/helper.go",
+	})
+
+	changedFiles := []string{"internal/new/a.go", "internal/new/b.go", "internal/old/c.go", "lib/helper.go"}
+	require.Equal(t, []string{rootPkg + "/internal/new", rootPkg + "/lib"}, analyzer.ChangedPackages(changedFiles))
+
+	result, err := analyzer.AnalyzeChangedPackages(changedFiles)
+	require.NoError(t, err)
+	require.Len(t, result.Impacts, 2)
+
+	moved := result.Impacts[0]
+	require.Equal(t, rootPkg+"/internal/new", moved.ChangedPackage)
+	require.Equal(t, rootPkg+"/internal/old", moved.RenamedFrom)
+	require.Len(t, moved.AffectedPackages, 1)
+	require.Equal(t, rootPkg+"/app", moved.AffectedPackages[0].Name)
+
+	// A file moved into an existing package does not rename its old package
+	require.Empty(t, result.Impacts[1].RenamedFrom)
+
+	report := result.String()
+	require.Contains(t, report, "#### Package renamed: `"+rootPkg+"/internal/old` → `"+rootPkg+"/internal/new`")
+	require.NotContains(t, report, "Changed Package: `"+rootPkg+"/internal/old`")
+}
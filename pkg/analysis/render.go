@@ -0,0 +1,597 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// NoChangedPackages is the line of a report stating the change touches no
+// package
+const NoChangedPackages = "No changed packages found."
+
+// String returns a string representation of the analysis result
+func (r *AnalysisResult) String() string {
+	return r.StringWithContext(ResultContext{})
+}
+
+// StringWithContext returns a string representation of the analysis result
+// headed by the revisions it was computed for, so stale reports are obvious.
+func (r *AnalysisResult) StringWithContext(ctx ResultContext) string {
+	return r.markdown().render(ctx, false)
+}
+
+// SummaryWithContext is like StringWithContext but omits the per-package
+// breakdown, rendering only the analysis summary.
+func (r *AnalysisResult) SummaryWithContext(ctx ResultContext) string {
+	return r.markdown().render(ctx, true)
+}
+
+// reportSection identifies a section of the report, for its heading
+type reportSection int
+
+const (
+	sectionDelta reportSection = iota
+	sectionCritical
+	sectionImpacts
+	sectionNewDependencies
+	sectionDeprecatedImports
+	sectionConfigChanges
+	sectionSummary
+	sectionGoVersion
+)
+
+// reportFormat renders the elements of a report in one output format. What
+// each section holds and when it appears is decided by report, once for every
+// format.
+type reportFormat interface {
+	badge(emoji string) string // The emoji followed by a space, or its replacement
+	code(name string) string   // A package, module, revision or pattern
+	strong(text string) string
+
+	title() string
+	incomplete(resolved, total int) string
+	notice(text string) string
+	heading(section reportSection, n int) string
+	item(level int, text string) string // List item, nested from level 1
+	note(level int, text string) string // Paragraph at the nesting of list items of level
+	blockEnd() string                   // Ends a section or impact made of notes
+
+	criticalName(name, docsURL string) string
+	changedPackage(name, renamedFrom string) string
+	implementers(intro string, items []string) string
+	affectedName(pkg *AffectedPackage) string
+	labels(labels []string) string
+	affectedList(total int, entries []string, hidden int) string
+
+	count(label string, n int) string
+	areas(areas []*AreaCount) string
+	collapsed(summary string, items []string) string
+}
+
+// report renders the sections of an analysis result in a format
+type report struct {
+	r *AnalysisResult
+	f reportFormat
+}
+
+// markdown returns the Markdown report of the result, as posted on pull
+// requests
+func (r *AnalysisResult) markdown() *report {
+	return &report{r: r, f: markdownFormat{r: r}}
+}
+
+// render renders the whole report, leaving out the per-package breakdown
+// when summaryOnly is set
+func (p *report) render(ctx ResultContext, summaryOnly bool) string {
+	if p.r.changedTotal() == 0 {
+		return p.header(ctx) + p.delta() + NoChangedPackages + "\n" + p.newDependencies() + p.configChanges()
+	}
+
+	var b strings.Builder
+	b.WriteString(p.header(ctx) + p.delta())
+	if !summaryOnly {
+		b.WriteString(p.critical() + p.impacts())
+	}
+	b.WriteString(p.newDependencies() +
+		p.deprecatedImports() +
+		p.configChanges() +
+		p.summary() +
+		p.footer())
+	return b.String()
+}
+
+// header renders the title and analyzed revisions
+func (p *report) header(ctx ResultContext) string {
+	r, f := p.r, p.f
+	var b strings.Builder
+	b.WriteString(f.title())
+
+	if ctx.BaseSHA != "" || ctx.HeadSHA != "" {
+		b.WriteString(fmt.Sprintf("Analyzed base %s against head %s", f.code(ctx.BaseSHA), f.code(ctx.HeadSHA)))
+		if !ctx.AnalyzedAt.IsZero() {
+			b.WriteString(fmt.Sprintf(" at %s", ctx.AnalyzedAt.UTC().Format(time.RFC3339)))
+		}
+		b.WriteString(".\n\n")
+	}
+	if r.Incomplete != nil {
+		b.WriteString(f.incomplete(r.Incomplete.Resolved, r.Incomplete.Total))
+	}
+	if r.LoadErrors > 0 {
+		b.WriteString(f.notice(f.badge("⚠️") + f.strong(fmt.Sprintf("%d packages had load errors", r.LoadErrors)) + "; impact may be incomplete. See the warnings below."))
+	}
+	return b.String()
+}
+
+// delta renders the packages newly affected and no longer affected since the
+// previous analysis, if known
+func (p *report) delta() string {
+	r, f := p.r, p.f
+	if r.Delta == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(f.heading(sectionDelta, 0))
+	if r.Delta.Empty() {
+		b.WriteString(f.note(1, "The affected packages are unchanged.") + f.blockEnd())
+		return b.String()
+	}
+	for _, name := range r.Delta.NewlyAffected {
+		b.WriteString(f.item(1, f.badge("➕")+"Newly affected: "+f.code(r.DisplayName(name))+r.deltaNote(name)))
+	}
+	for _, name := range r.Delta.NoLongerAffected {
+		b.WriteString(f.item(1, f.badge("➖")+"No longer affected: "+f.code(r.DisplayName(name))+r.deltaNote(name)))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// deltaNote flags critical packages in the delta
+func (r *AnalysisResult) deltaNote(name string) string {
+	if r.Delta.Critical[name] {
+		return " (Critical)"
+	}
+	return ""
+}
+
+// critical lists the distinct critical packages affected, if any, so they
+// lead the report
+func (p *report) critical() string {
+	r, f := p.r, p.f
+	critical := r.CriticalPackageNames()
+	if len(critical) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(f.heading(sectionCritical, len(critical)))
+	docs := r.docsURLs()
+	for _, name := range critical {
+		b.WriteString(f.item(1, f.criticalName(name, docs[name])))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// impacts renders the per-changed-package breakdown of affected packages
+func (p *report) impacts() string {
+	r, f := p.r, p.f
+	var b strings.Builder
+	b.WriteString(f.heading(sectionImpacts, 0))
+	if len(r.Impacts) == 0 {
+		// Every impact was filtered out as non-critical
+		b.WriteString(f.note(1, "No critical packages are affected."))
+	}
+	for _, impact := range r.Impacts {
+		b.WriteString(f.changedPackage(impact.ChangedPackage, impact.RenamedFrom))
+		if len(impact.RecentAuthors) > 0 {
+			b.WriteString(f.note(2, "Recent authors: "+strings.Join(impact.RecentAuthors, ", ")))
+		}
+		if len(impact.InterfaceImplementers) > 0 {
+			var items []string
+			for _, impl := range impact.InterfaceImplementers {
+				items = append(items, fmt.Sprintf("%s: %s implements %s", f.code(r.DisplayName(impl.Package)), f.code(impl.Type), f.code(impl.Interface)))
+			}
+			b.WriteString(f.implementers(f.badge("⚠️")+"Potentially affected (interface change):", items))
+		}
+		if impact.Kind == ChangeKindInternalOnly {
+			b.WriteString(f.note(2, "The import set of this package is unchanged, so downstream impact is not reported."))
+			continue
+		}
+		if impact.Kind == ChangeKindImportsChanged {
+			var changes []string
+			for _, imp := range impact.AddedImports {
+				changes = append(changes, "+"+f.code(r.DisplayName(imp)))
+			}
+			for _, imp := range impact.RemovedImports {
+				changes = append(changes, "-"+f.code(r.DisplayName(imp)))
+			}
+			b.WriteString(f.note(2, "Import set changed: "+strings.Join(changes, ", ")))
+		}
+		if len(impact.AffectedPackages) == 0 {
+			b.WriteString(f.note(2, "This change does not affect any other packages."))
+			continue
+		}
+
+		shown := r.shownAffected(impact)
+		entries := make([]string, 0, len(shown))
+		for _, pkg := range shown {
+			entries = append(entries, f.affectedName(pkg)+f.labels(pkg.Labels)+depthNote(pkg.Depth)+releaseNote(pkg.LastRelease))
+		}
+		b.WriteString(f.affectedList(len(impact.AffectedPackages), entries, len(impact.AffectedPackages)-len(shown)))
+	}
+	b.WriteString(f.blockEnd())
+	return b.String()
+}
+
+// newDependencies lists the external modules newly required by the change
+func (p *report) newDependencies() string {
+	r, f := p.r, p.f
+	if len(r.NewDependencies) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(f.heading(sectionNewDependencies, len(r.NewDependencies)))
+	for _, req := range r.NewDependencies {
+		line := f.code(req.Path) + " " + req.Version
+		if req.Indirect {
+			line += " (indirect)"
+		}
+		b.WriteString(f.item(1, line))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// deprecatedImports lists the deprecated packages the changed packages import
+func (p *report) deprecatedImports() string {
+	r, f := p.r, p.f
+	if len(r.DeprecatedImports) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(f.heading(sectionDeprecatedImports, len(r.DeprecatedImports)))
+	for _, imp := range r.DeprecatedImports {
+		b.WriteString(f.item(1, f.code(r.DisplayName(imp.Importer))+" imports "+f.code(r.DisplayName(imp.Imported))))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// configChanges lists the patterns the change adds to and removes from the
+// configuration the analysis ran under
+func (p *report) configChanges() string {
+	r, f := p.r, p.f
+	if len(r.ConfigChanges) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(f.heading(sectionConfigChanges, len(r.ConfigChanges)))
+	for _, change := range r.ConfigChanges {
+		b.WriteString(f.item(1, f.code(change.Setting)))
+		for _, entry := range change.Added {
+			b.WriteString(f.item(2, f.badge("➕")+"added "+f.code(entry)))
+		}
+		for _, entry := range change.Removed {
+			b.WriteString(f.item(2, f.badge("➖")+"removed "+f.code(entry)))
+		}
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// summary renders the aggregate counts, escalation, policy and layering
+// violations and any warnings
+func (p *report) summary() string {
+	r, f := p.r, p.f
+	var b strings.Builder
+	b.WriteString(f.heading(sectionSummary, 0))
+	b.WriteString(f.count("Changed packages", r.changedTotal()))
+	b.WriteString(f.count("Affected packages", r.affectedTotal()))
+	b.WriteString(f.count("Critical packages affected", len(r.CriticalPackageNames())))
+	b.WriteString(f.count("Direct dependencies of changed packages", len(r.DirectDependencies)))
+	b.WriteString(f.count("Indirectly affected packages", len(r.IndirectDependencies)))
+
+	if len(r.ChangedAreas) > 0 {
+		b.WriteString(f.areas(r.ChangedAreas))
+	}
+
+	switch r.Severity {
+	case SeverityBlock:
+		b.WriteString(fmt.Sprintf("\n%s%s. This change is blocking.\n", f.badge("🛑"), f.strong(fmt.Sprintf("%d critical packages affected (threshold: %d)", r.CriticalCount, r.BlockThreshold))))
+	case SeverityWarn:
+		b.WriteString(fmt.Sprintf("\n%s%d critical packages affected (threshold: %d).\n", f.badge("⚠️"), r.CriticalCount, r.WarnThreshold))
+	}
+
+	if len(r.PolicyViolations) > 0 {
+		b.WriteString("\n" + f.note(0, f.badge("🚫")+f.strong(fmt.Sprintf("%d critical source policy violations", len(r.PolicyViolations)))+". Only allowed packages may affect critical packages:"))
+		for _, v := range r.PolicyViolations {
+			b.WriteString(f.item(1, f.code(r.DisplayName(v.ChangedPackage))+" affects critical package "+f.code(r.DisplayName(v.CriticalPackage))))
+		}
+	}
+
+	introduced, existing := r.splitLayerViolations()
+	if len(introduced) > 0 {
+		b.WriteString("\n" + f.note(0, f.badge("🧱")+f.strong(fmt.Sprintf("%d layering violations introduced by this change", len(introduced)))+":"))
+		for _, v := range introduced {
+			b.WriteString(f.item(1, p.layerViolation(v)))
+		}
+	}
+	if len(existing) > 0 {
+		var items []string
+		for _, v := range existing {
+			items = append(items, p.layerViolation(v))
+		}
+		b.WriteString(f.collapsed(fmt.Sprintf("%d pre-existing layering violations", len(existing)), items))
+	}
+
+	if len(r.Warnings) > 0 {
+		b.WriteString(f.collapsed(fmt.Sprintf("%sWarnings (%d)", f.badge("⚠️"), len(r.Warnings)), r.Warnings))
+	}
+	return b.String()
+}
+
+// layerViolation describes a layering violation
+func (p *report) layerViolation(v *LayerViolation) string {
+	return fmt.Sprintf("%s (%s) imports %s (%s)", p.f.code(p.r.DisplayName(v.Importer)), v.ImporterLayer, p.f.code(p.r.DisplayName(v.Imported)), v.ImportedLayer)
+}
+
+// footer renders the Go version compatibility problems, if any
+func (p *report) footer() string {
+	r, f := p.r, p.f
+	if len(r.GoVersionIssues) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(f.heading(sectionGoVersion, len(r.GoVersionIssues)))
+	for _, issue := range r.GoVersionIssues {
+		b.WriteString(f.item(1, f.badge("⚠️")+issue))
+	}
+	return b.String()
+}
+
+// markdownFormat renders reports in GitHub-flavored Markdown, as posted on
+// pull requests
+type markdownFormat struct {
+	r *AnalysisResult
+}
+
+func (m markdownFormat) badge(emoji string) string { return m.r.badge(emoji) }
+func (m markdownFormat) code(name string) string   { return "`" + name + "`" }
+func (m markdownFormat) strong(text string) string { return "**" + text + "**" }
+
+func (m markdownFormat) title() string {
+	return "<!-- dependency-guardian -->\n## " + m.badge("🔍") + "Dependency Impact Analysis\n\n"
+}
+
+func (m markdownFormat) incomplete(resolved, total int) string {
+	return m.notice(fmt.Sprintf("%s**Analysis incomplete** (deadline reached after resolving %d of %d packages). Impacts on the remaining packages are not reported.", m.badge("⚠️"), resolved, total))
+}
+
+func (m markdownFormat) notice(text string) string { return "> " + text + "\n\n" }
+
+func (m markdownFormat) heading(section reportSection, n int) string {
+	switch section {
+	case sectionDelta:
+		return "### " + m.badge("🔄") + "Changes Since Last Analysis\n\n"
+	case sectionCritical:
+		return fmt.Sprintf("### %sCritical Packages Affected (%d)\n\n", m.badge("🚨"), n)
+	case sectionImpacts:
+		return "### Changed Packages and Their Impacts\n\n"
+	case sectionNewDependencies:
+		return fmt.Sprintf("### %sNew External Dependencies (%d)\n\n", m.badge("📦"), n)
+	case sectionDeprecatedImports:
+		return fmt.Sprintf("### %sDeprecated Imports (%d)\n\n", m.badge("📛"), n) +
+			"These packages are deprecated; consider migrating the changed packages off them.\n\n"
+	case sectionConfigChanges:
+		return fmt.Sprintf("### %sConfig changes in this PR\n\n", m.badge("⚙️")) +
+			"This analysis ran under the configuration of the PR head.\n\n"
+	case sectionSummary:
+		return "### Analysis Summary:\n\n"
+	case sectionGoVersion:
+		return "\n### Go Version Compatibility\n\n"
+	}
+	return ""
+}
+
+func (m markdownFormat) item(level int, text string) string {
+	return strings.Repeat("  ", level-1) + "- " + text + "\n"
+}
+
+func (m markdownFormat) note(level int, text string) string { return text + "\n\n" }
+func (m markdownFormat) blockEnd() string                   { return "" }
+
+func (m markdownFormat) criticalName(name, docsURL string) string {
+	return m.r.criticalName(name, docsURL)
+}
+
+func (m markdownFormat) changedPackage(name, renamedFrom string) string {
+	if renamedFrom != "" {
+		return fmt.Sprintf("#### Package renamed: `%s` → `%s`\n\n", m.r.DisplayName(renamedFrom), m.r.DisplayName(name))
+	}
+	return fmt.Sprintf("#### Changed Package: `%s`\n\n", m.r.DisplayName(name))
+}
+
+func (m markdownFormat) implementers(intro string, items []string) string {
+	var b strings.Builder
+	b.WriteString(intro + "\n\n")
+	for _, item := range items {
+		b.WriteString(m.item(1, item))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+func (m markdownFormat) affectedName(pkg *AffectedPackage) string {
+	if pkg.IsCritical {
+		return m.r.criticalBadge(pkg) + m.r.criticalName(pkg.Name, pkg.DocsURL) + " (Critical)"
+	}
+	return m.code(m.r.DisplayName(pkg.Name))
+}
+
+func (m markdownFormat) labels(labels []string) string { return labelNote(labels) }
+
+func (m markdownFormat) affectedList(total int, entries []string, hidden int) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("<details><summary>Affected Packages (%d)</summary>\n\n", total))
+	for _, entry := range entries {
+		b.WriteString(m.item(1, entry))
+	}
+	if hidden > 0 {
+		b.WriteString(m.item(1, fmt.Sprintf("… and %d more (see JSON artifact)", hidden)))
+	}
+	b.WriteString("\n</details>\n\n")
+	return b.String()
+}
+
+func (m markdownFormat) count(label string, n int) string {
+	return fmt.Sprintf("- **%s**: %d\n", label, n)
+}
+
+func (m markdownFormat) areas(areas []*AreaCount) string {
+	var b strings.Builder
+	b.WriteString("\n| Area | Changed packages |\n|------|-----------------:|\n")
+	for _, area := range areas {
+		b.WriteString(fmt.Sprintf("| `%s` | %d |\n", area.Area, area.Packages))
+	}
+	return b.String()
+}
+
+func (m markdownFormat) collapsed(summary string, items []string) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("\n<details><summary>%s</summary>\n\n", summary))
+	for _, item := range items {
+		b.WriteString(m.item(1, item))
+	}
+	b.WriteString("\n</details>\n")
+	return b.String()
+}
+
+// textBadges are the text markers replacing emoji when emoji are disabled.
+// Decorative emoji have no marker.
+var textBadges = map[string]string{
+	"🚨":  "[CRITICAL]",
+	"🛑":  "[BLOCKING]",
+	"⚠️": "[WARNING]",
+	"🚫":  "[POLICY]",
+	"🧱":  "[LAYERING]",
+	"📛":  "[DEPRECATED]",
+	"➕":  "[+]",
+	"➖":  "[-]",
+}
+
+// badge renders an emoji followed by a space, or its text marker when emoji
+// are disabled
+func (r *AnalysisResult) badge(emoji string) string {
+	if !r.NoEmoji {
+		return emoji + " "
+	}
+	if marker := textBadges[emoji]; marker != "" {
+		return marker + " "
+	}
+	return ""
+}
+
+// criticalBadge returns the badge of a critical affected package: its
+// configured badge, or the default one when it has none or emoji are disabled
+func (r *AnalysisResult) criticalBadge(pkg *AffectedPackage) string {
+	if pkg.Badge != "" && !r.NoEmoji {
+		return pkg.Badge + " "
+	}
+	return r.badge("🚨")
+}
+
+// shownAffected returns the affected packages of impact to list in the report:
+// every critical package, plus non-critical packages in order while the cap
+// allows
+func (r *AnalysisResult) shownAffected(impact *PackageImpact) []*AffectedPackage {
+	if r.MaxAffectedShown <= 0 || len(impact.AffectedPackages) <= r.MaxAffectedShown {
+		return impact.AffectedPackages
+	}
+
+	budget := r.MaxAffectedShown
+	for _, pkg := range impact.AffectedPackages {
+		if pkg.IsCritical {
+			budget--
+		}
+	}
+
+	var shown []*AffectedPackage
+	for _, pkg := range impact.AffectedPackages {
+		if pkg.IsCritical {
+			shown = append(shown, pkg)
+		} else if budget > 0 {
+			shown = append(shown, pkg)
+			budget--
+		}
+	}
+	return shown
+}
+
+// DisplayName returns how a package is named in reports: relative to the
+// module path when RelativePaths is set, except for the module root itself
+func (r *AnalysisResult) DisplayName(pkgPath string) string {
+	if !r.RelativePaths || r.ModulePath == "" {
+		return pkgPath
+	}
+	if rel, ok := strings.CutPrefix(pkgPath, r.ModulePath+"/"); ok {
+		return rel
+	}
+	return pkgPath
+}
+
+// criticalName renders the emphasized name of a critical package, linking to
+// its documentation when a URL is configured
+func (r *AnalysisResult) criticalName(pkgPath, docsURL string) string {
+	if docsURL == "" {
+		return fmt.Sprintf("**`%s`**", r.DisplayName(pkgPath))
+	}
+	return fmt.Sprintf("**[`%s`](%s)**", r.DisplayName(pkgPath), docsURL)
+}
+
+// docsURLs maps the affected packages with a documentation link to it
+func (r *AnalysisResult) docsURLs() map[string]string {
+	docs := make(map[string]string)
+	for _, impact := range r.Impacts {
+		for _, pkg := range impact.AffectedPackages {
+			if pkg.DocsURL != "" {
+				docs[pkg.Name] = pkg.DocsURL
+			}
+		}
+	}
+	return docs
+}
+
+// labelNote renders the custom labels of an affected package
+func labelNote(labels []string) string {
+	custom := customLabels(labels)
+	if len(custom) == 0 {
+		return ""
+	}
+	return " `" + strings.Join(custom, "` `") + "`"
+}
+
+// releaseNote names the release an affected package last changed in, if known
+func releaseNote(release string) string {
+	if release == "" {
+		return ""
+	}
+	return " — last changed in " + release
+}
+
+// depthNote describes how far an affected package is from the change
+func depthNote(depth int) string {
+	switch {
+	case depth <= 0:
+		return ""
+	case depth == 1:
+		return " — directly affected"
+	default:
+		return fmt.Sprintf(" — affected at depth %d", depth)
+	}
+}
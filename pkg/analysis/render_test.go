@@ -0,0 +1,173 @@
+package analysis
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// renderTestResult has a critical and a non-critical affected package, a
+// change without impact and a warning
+func renderTestResult() *AnalysisResult {
+	return &AnalysisResult{
+		Impacts: []*PackageImpact{
+			{
+				ChangedPackage: "example.com/m/a",
+				AffectedPackages: []*AffectedPackage{
+					{Name: "example.com/m/auth", IsCritical: true, Depth: 1},
+					{Name: "example.com/m/leaf", Depth: 2},
+				},
+			},
+			{ChangedPackage: "example.com/m/b"},
+		},
+		DirectDependencies: []string{"example.com/m/util"},
+		Warnings:           []string{"failed to resolve dependencies for example.com/m/broken: boom"},
+		CriticalCount:      1,
+		Severity:           SeverityWarn,
+		WarnThreshold:      1,
+		BlockThreshold:     2,
+	}
+}
+
+func TestRenderHeader(t *testing.T) {
+	require.Equal(t, "<!-- dependency-guardian -->\n## 🔍 Dependency Impact Analysis\n\n", (&AnalysisResult{}).markdown().header(ResultContext{}))
+	require.Equal(t,
+		"<!-- dependency-guardian -->\n## 🔍 Dependency Impact Analysis\n\nAnalyzed base `b` against head `h` at 1970-01-01T00:00:00Z.\n\n",
+		(&AnalysisResult{}).markdown().header(ResultContext{BaseSHA: "b", HeadSHA: "h", AnalyzedAt: time.Unix(0, 0)}))
+}
+
+func TestAnalysisResult_RenderCritical(t *testing.T) {
+	result := renderTestResult()
+	require.Equal(t, "### 🚨 Critical Packages Affected (1)\n\n- **`example.com/m/auth`**\n\n", result.markdown().critical())
+
+	result.Impacts[0].AffectedPackages[0].IsCritical = false
+	require.Empty(t, result.markdown().critical())
+}
+
+func TestAnalysisResult_RenderImpacts(t *testing.T) {
+	require.Equal(t, `### Changed Packages and Their Impacts
+
+#### Changed Package: `+"`example.com/m/a`"+`
+
+<details><summary>Affected Packages (2)</summary>
+
+- 🚨 **`+"`example.com/m/auth`"+`** (Critical) — directly affected
+- `+"`example.com/m/leaf`"+` — affected at depth 2
+
+</details>
+
+#### Changed Package: `+"`example.com/m/b`"+`
+
+This change does not affect any other packages.
+
+`, renderTestResult().markdown().impacts())
+}
+
+func TestAnalysisResult_RenderRecentAuthors(t *testing.T) {
+	result := renderTestResult()
+	result.Impacts[1].RecentAuthors = []string{"Bob", "Alice"}
+	require.Contains(t, result.markdown().impacts(), "#### Changed Package: `example.com/m/b`\n\nRecent authors: Bob, Alice\n\n")
+	require.Contains(t, result.Text(), "  example.com/m/b\n    Recent authors: Bob, Alice\n")
+}
+
+func TestAnalysisResult_RenderSummary(t *testing.T) {
+	require.Equal(t, `### Analysis Summary:
+
+- **Changed packages**: 2
+- **Affected packages**: 2
+- **Critical packages affected**: 1
+- **Direct dependencies of changed packages**: 1
+- **Indirectly affected packages**: 0
+
+⚠️ 1 critical packages affected (threshold: 1).
+
+<details><summary>⚠️ Warnings (1)</summary>
+
+- failed to resolve dependencies for example.com/m/broken: boom
+
+</details>
+`, renderTestResult().markdown().summary())
+}
+
+func TestAnalysisResult_RenderFooter(t *testing.T) {
+	result := renderTestResult()
+	require.Empty(t, result.markdown().footer())
+
+	result.GoVersionIssues = []string{"go.mod declares go 1.19, below the policy minimum of 1.21"}
+	require.Equal(t, "\n### Go Version Compatibility\n\n- ⚠️ go.mod declares go 1.19, below the policy minimum of 1.21\n", result.markdown().footer())
+}
+
+func TestAnalysisResult_StringComposesSections(t *testing.T) {
+	result := renderTestResult()
+	ctx := ResultContext{BaseSHA: "b", HeadSHA: "h"}
+
+	require.Equal(t, result.markdown().header(ctx)+result.markdown().critical()+result.markdown().impacts()+result.markdown().newDependencies()+result.markdown().summary()+result.markdown().footer(), result.StringWithContext(ctx))
+	require.Equal(t, result.markdown().header(ctx)+result.markdown().newDependencies()+result.markdown().summary()+result.markdown().footer(), result.SummaryWithContext(ctx))
+}
+
+func TestAnalysisResult_Text(t *testing.T) {
+	result := renderTestResult()
+	result.Impacts[0].AffectedPackages[0].Labels = []string{LabelCritical, "consensus"}
+	result.Impacts = append(result.Impacts, &PackageImpact{
+		ChangedPackage: "example.com/m/c",
+		Kind:           ChangeKindImportsChanged,
+		AddedImports:   []string{"example.com/m/util"},
+	})
+	result.NewDependencies = []*ModRequire{{Path: "github.com/x/y", Version: "v1.0.0"}}
+	result.PolicyViolations = []*PolicyViolation{{ChangedPackage: "example.com/m/a", CriticalPackage: "example.com/m/auth"}}
+	result.GoVersionIssues = []string{"example.com/m/a requires go1.23"}
+
+	text := result.TextWithContext(ResultContext{BaseSHA: "b", HeadSHA: "h"})
+	require.Contains(t, text, "    ├── 🚨 example.com/m/auth (critical) [consensus] — directly affected\n    └── example.com/m/leaf — affected at depth 2\n")
+	require.Contains(t, text, "  example.com/m/c\n    Import set changed: +example.com/m/util\n")
+	for _, markdown := range []string{"`", "<", ">", "**", "#", "]("} {
+		require.NotContains(t, text, markdown)
+	}
+
+	result.NoEmoji = true
+	plain := result.TextWithContext(ResultContext{})
+	require.True(t, strings.HasPrefix(plain, "Dependency Impact Analysis\n\n"))
+	for _, emoji := range []string{"🔍", "🚨", "📦", "⚠️", "🚫"} {
+		require.NotContains(t, plain, emoji)
+	}
+	require.Contains(t, plain, "    ├── [CRITICAL] example.com/m/auth (critical) [consensus] — directly affected\n")
+	require.Contains(t, plain, "[POLICY] 1 critical source policy violations.")
+}
+
+func TestAnalysisResult_RenderDelta(t *testing.T) {
+	result := renderTestResult()
+	require.Empty(t, result.markdown().delta())
+
+	result.Delta = result.DeltaSince(&Snapshot{
+		Affected: []string{"example.com/m/leaf", "example.com/m/old"},
+		Critical: []string{"example.com/m/old"},
+	})
+	require.Equal(t, []string{"example.com/m/auth"}, result.Delta.NewlyAffected)
+	require.Equal(t, []string{"example.com/m/old"}, result.Delta.NoLongerAffected)
+	require.Equal(t, "### 🔄 Changes Since Last Analysis\n\n- ➕ Newly affected: `example.com/m/auth` (Critical)\n- ➖ No longer affected: `example.com/m/old` (Critical)\n\n", result.markdown().delta())
+}
+
+func TestAnalysisResult_NoEmoji(t *testing.T) {
+	result := renderTestResult()
+	result.Severity = SeverityBlock
+	result.NewDependencies = []*ModRequire{{Path: "github.com/x/y", Version: "v1.0.0"}}
+	result.PolicyViolations = []*PolicyViolation{{ChangedPackage: "example.com/m/a", CriticalPackage: "example.com/m/auth"}}
+	result.GoVersionIssues = []string{"example.com/m/a requires go1.23"}
+	result.Delta = result.DeltaSince(&Snapshot{})
+	result.NoEmoji = true
+
+	report := result.String()
+	for _, emoji := range []string{"🔍", "🚨", "📦", "🛑", "⚠️", "🚫", "🔄", "➕", "➖"} {
+		require.NotContains(t, report, emoji)
+	}
+	require.Contains(t, report, "## Dependency Impact Analysis\n")
+	require.Contains(t, report, "- [CRITICAL] **`example.com/m/auth`** (Critical) — directly affected\n")
+	require.Contains(t, report, "\n[BLOCKING] **1 critical packages affected (threshold: 2)**. This change is blocking.\n")
+	require.Contains(t, report, "- [+] Newly affected: `example.com/m/auth` (Critical)\n")
+
+	text := result.TextWithContext(ResultContext{})
+	require.NotContains(t, text, "🚨")
+	require.Contains(t, text, "[CRITICAL] example.com/m/auth (critical)")
+}
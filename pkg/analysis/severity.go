@@ -0,0 +1,29 @@
+package analysis
+
+// Severity is the escalation level of an analysis, driven by the number of
+// distinct critical packages it affects.
+type Severity string
+
+const (
+	// SeverityNone means the critical impact is below every threshold
+	SeverityNone Severity = "none"
+	// SeverityWarn means the critical impact reached the warn threshold
+	SeverityWarn Severity = "warn"
+	// SeverityBlock means the critical impact reached the block threshold
+	SeverityBlock Severity = "block"
+)
+
+// severityFor returns the severity of affecting count distinct critical
+// packages. A threshold of zero disables that level.
+func severityFor(count, warnThreshold, blockThreshold int) Severity {
+	switch {
+	case count == 0:
+		return SeverityNone
+	case blockThreshold > 0 && count >= blockThreshold:
+		return SeverityBlock
+	case warnThreshold > 0 && count >= warnThreshold:
+		return SeverityWarn
+	default:
+		return SeverityNone
+	}
+}
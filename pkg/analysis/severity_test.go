@@ -0,0 +1,19 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeverityFor(t *testing.T) {
+	require.Equal(t, SeverityNone, severityFor(0, 1, 1))
+	require.Equal(t, SeverityBlock, severityFor(1, 1, 1))
+	require.Equal(t, SeverityWarn, severityFor(2, 1, 3))
+	require.Equal(t, SeverityBlock, severityFor(5, 1, 3))
+	require.Equal(t, SeverityNone, severityFor(1, 2, 3))
+
+	// A zero threshold disables its level
+	require.Equal(t, SeverityWarn, severityFor(10, 1, 0))
+	require.Equal(t, SeverityNone, severityFor(10, 0, 0))
+}
@@ -0,0 +1,34 @@
+package analysis
+
+import "fmt"
+
+// Shield is a shields.io endpoint badge, see https://shields.io/badges/endpoint-badge
+type Shield struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
+}
+
+// Shield returns the badge summarizing the result: the number of affected
+// packages, in red when any is critical, yellow when some are affected and
+// green otherwise
+func (r *AnalysisResult) Shield() *Shield {
+	affected := r.affectedTotal()
+	shield := &Shield{
+		SchemaVersion: 1,
+		Label:         "impact",
+		Message:       fmt.Sprintf("%d packages", affected),
+		Color:         "brightgreen",
+	}
+	if affected == 1 {
+		shield.Message = "1 package"
+	}
+	switch {
+	case len(r.CriticalPackageNames()) > 0:
+		shield.Color = "red"
+	case affected > 0:
+		shield.Color = "yellow"
+	}
+	return shield
+}
@@ -0,0 +1,32 @@
+package analysis
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalysisResult_Shield(t *testing.T) {
+	result := &AnalysisResult{
+		Impacts: []*PackageImpact{
+			{
+				ChangedPackage: "example.com/m/d",
+				AffectedPackages: []*AffectedPackage{
+					{Name: "example.com/m/c"},
+					{Name: "example.com/m/auth", IsCritical: true},
+				},
+			},
+		},
+	}
+
+	data, err := json.Marshal(result.Shield())
+	require.NoError(t, err)
+	require.JSONEq(t, `{"schemaVersion": 1, "label": "impact", "message": "2 packages", "color": "red"}`, string(data))
+
+	result.Impacts[0].AffectedPackages = result.Impacts[0].AffectedPackages[:1]
+	require.Equal(t, &Shield{SchemaVersion: 1, Label: "impact", Message: "1 package", Color: "yellow"}, result.Shield())
+
+	result.Impacts[0].AffectedPackages = nil
+	require.Equal(t, &Shield{SchemaVersion: 1, Label: "impact", Message: "0 packages", Color: "brightgreen"}, result.Shield())
+}
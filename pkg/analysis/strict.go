@@ -0,0 +1,70 @@
+package analysis
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// SetStrictImports makes the analysis fail when an internal package imports a
+// package of the module that has no directory, instead of dropping the edge
+func (a *Analyzer) SetStrictImports(strict bool) {
+	a.strictImports = strict
+}
+
+// unresolvableImports maps the packages of the module imported in the tree but
+// without a directory to their sorted importers. Imports of modules replaced
+// in go.mod, such as nested modules, and those matching the configured
+// exceptions are left out.
+func (a *Analyzer) unresolvableImports() map[string][]string {
+	var replaces []string
+	if mod, err := ReadModFile(a.cfg.ModuleRoot(a.repoPath)); err == nil {
+		replaces = mod.Replaces
+	}
+	replaced := func(pkgName string) bool {
+		for _, r := range replaces {
+			if pkgName == r || strings.HasPrefix(pkgName, r+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	missing := make(map[string][]string)
+	for name, pkg := range a.tree.Packages {
+		if name != a.rootPkgPath && !strings.HasPrefix(name, a.rootPkgPath+"/") {
+			continue
+		}
+		if len(pkg.Files) > 0 || replaced(name) || a.cfg.IsStrictImportException(name) {
+			continue
+		}
+		if _, err := os.Stat(a.tree.packageDir(name)); !os.IsNotExist(err) {
+			continue
+		}
+		for _, importer := range a.tree.FindReverseDependencies(name) {
+			missing[name] = append(missing[name], importer.Name)
+		}
+		sort.Strings(missing[name])
+	}
+	return missing
+}
+
+// checkStrictImports fails when the tree has unresolvable internal imports
+func (a *Analyzer) checkStrictImports() error {
+	missing := a.unresolvableImports()
+	if len(missing) == 0 {
+		return nil
+	}
+
+	var names []string
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var details []string
+	for _, name := range names {
+		details = append(details, fmt.Sprintf("%s (imported by %s)", name, strings.Join(missing[name], ", ")))
+	}
+	return fmt.Errorf("unresolvable internal imports in strict imports mode: %s", strings.Join(details, "; "))
+}
@@ -0,0 +1,33 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzeChangedPackages_StrictImports(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod": "module " + rootPkg + "\n\nreplace " + rootPkg + "/nested => ./nested\n",
+		"a/a.go": "package a\n\nimport (\n\t_ \"github.com/a/b/gone\"\n\t_ \"github.com/a/b/gen/api\"\n\t_ \"github.com/a/b/nested/pkg\"\n)\n",
+		"c/c.go": "package c\n\nimport _ \"github.com/a/b/gone\"\n",
+	})
+
+	cfg := config.DefaultConfig()
+	cfg.Analysis.StrictImportExceptions = []string{"**/gen/**"}
+
+	// Without strict imports the dangling edge is dropped
+	analyzer := NewAnalyzer(cfg, repoPath)
+	analyzer.SetRootPackage(rootPkg)
+	_, err := analyzer.AnalyzeChangedPackages([]string{"a/a.go"})
+	require.NoError(t, err)
+
+	// In strict mode it fails, leaving out generated and replaced packages
+	analyzer = NewAnalyzer(cfg, repoPath)
+	analyzer.SetStrictImports(true)
+	analyzer.SetRootPackage(rootPkg)
+	_, err = analyzer.AnalyzeChangedPackages([]string{"a/a.go"})
+	require.EqualError(t, err, "unresolvable internal imports in strict imports mode: github.com/a/b/gone (imported by github.com/a/b/a, github.com/a/b/c)")
+}
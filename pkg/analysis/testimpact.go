@@ -0,0 +1,65 @@
+package analysis
+
+import (
+	"sort"
+)
+
+// TestImpact lists the packages whose tests use a changed package
+type TestImpact struct {
+	ChangedPackage string
+	TestDependents []string // Packages whose test files use it, sorted
+}
+
+// TestDependents returns, sorted, the packages whose test files import pkgName
+// or a package that depends on it, directly or transitively. It answers how
+// many test suites a change to a shared test helper reaches.
+func (t *Tree) TestDependents(pkgName string) []string {
+	reverse := t.reverseDependencyIndex()
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	used := map[string]bool{pkgName: true}
+	for name := range reverseDepths(reverse, pkgName, 0) {
+		used[name] = true
+	}
+
+	var dependents []string
+	for name, pkg := range t.Packages {
+		if name == pkgName {
+			continue
+		}
+		for _, imp := range pkg.TestImports {
+			if used[imp] {
+				dependents = append(dependents, name)
+				break
+			}
+		}
+	}
+	sort.Strings(dependents)
+	return dependents
+}
+
+// TestImpact resolves the whole repository and returns, for each package
+// containing changed files, the packages whose tests use it, leaving out
+// ignored packages
+func (a *Analyzer) TestImpact(changedFiles []string) ([]*TestImpact, error) {
+	if a.tree == nil {
+		return nil, ErrNoRootPackage
+	}
+	if err := a.resolveAll(); err != nil {
+		return nil, err
+	}
+
+	var impacts []*TestImpact
+	for _, changed := range a.ChangedPackages(changedFiles) {
+		impact := &TestImpact{ChangedPackage: changed}
+		for _, name := range a.tree.TestDependents(changed) {
+			if !a.cfg.ShouldIgnorePackage(name) {
+				impact.TestDependents = append(impact.TestDependents, name)
+			}
+		}
+		impacts = append(impacts, impact)
+	}
+	return impacts, nil
+}
@@ -0,0 +1,49 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalyzerTestImpact(t *testing.T) {
+	// a and b use testutil from their tests, c through the fixtures helper it
+	// imports from an external test package, d only from production code
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"go.mod":                        "module " + rootPkg,
+		"internal/testutil/testutil.go": "package testutil",
+		"internal/fixtures/fixtures.go": "package fixtures\n\nimport _ \"github.com/a/b/internal/testutil\"\n",
+		"a/a.go":                        "package a",
+		"a/a_test.go":                   "package a\n\nimport _ \"github.com/a/b/internal/testutil\"\n",
+		"b/b.go":                        "package b",
+		"b/b_test.go":                   "package b_test\n\nimport _ \"github.com/a/b/internal/testutil\"\n",
+		"c/c.go":                        "package c",
+		"c/c_test.go":                   "package c_test\n\nimport _ \"github.com/a/b/internal/fixtures\"\n",
+		"d/d.go":                        "package d\n\nimport _ \"github.com/a/b/internal/testutil\"\n",
+	})
+
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	impacts, err := analyzer.TestImpact([]string{"internal/testutil/testutil.go"})
+	require.NoError(t, err)
+	require.Equal(t, []*TestImpact{{
+		ChangedPackage: rootPkg + "/internal/testutil",
+		TestDependents: []string{rootPkg + "/a", rootPkg + "/b", rootPkg + "/c"},
+	}}, impacts)
+
+	// Test imports are recorded without making tests part of the analysis
+	a := analyzer.tree.Packages[rootPkg+"/a"]
+	require.Empty(t, a.Imports)
+	require.Equal(t, []string{rootPkg + "/internal/testutil"}, a.TestImports)
+	require.Len(t, a.Files, 1)
+
+	impacts, err = analyzer.TestImpact([]string{"README.md"})
+	require.NoError(t, err)
+	require.Empty(t, impacts)
+
+	_, err = NewAnalyzer(config.DefaultConfig(), repoPath).TestImpact(nil)
+	require.ErrorIs(t, err, ErrNoRootPackage)
+}
@@ -0,0 +1,145 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Text returns a plain text representation of the analysis result for
+// terminals: an indented tree without Markdown. With NoEmoji set, emoji are
+// replaced by text markers as in the Markdown report.
+func (r *AnalysisResult) Text() string {
+	return r.TextWithContext(ResultContext{})
+}
+
+// TextWithContext is like Text but headed by the revisions the result was
+// computed for
+func (r *AnalysisResult) TextWithContext(ctx ResultContext) string {
+	return (&report{r: r, f: textFormat{r: r}}).render(ctx, false)
+}
+
+// textFormat renders reports as plain text for terminals, nesting lists by
+// indentation
+type textFormat struct {
+	r *AnalysisResult
+}
+
+func (t textFormat) badge(emoji string) string { return t.r.badge(emoji) }
+
+func (t textFormat) code(name string) string   { return name }
+func (t textFormat) strong(text string) string { return text }
+
+func (t textFormat) title() string {
+	return t.badge("🔍") + "Dependency Impact Analysis\n\n"
+}
+
+func (t textFormat) incomplete(resolved, total int) string {
+	return t.notice(fmt.Sprintf("%sAnalysis incomplete: deadline reached after resolving %d of %d packages.", t.badge("⚠️"), resolved, total))
+}
+
+func (t textFormat) notice(text string) string { return text + "\n\n" }
+
+func (t textFormat) heading(section reportSection, n int) string {
+	switch section {
+	case sectionDelta:
+		return t.badge("🔄") + "Changes since last analysis:\n"
+	case sectionCritical:
+		return fmt.Sprintf("%sCritical packages affected (%d):\n", t.badge("🚨"), n)
+	case sectionImpacts:
+		return "Changed packages and their impacts:\n"
+	case sectionNewDependencies:
+		return fmt.Sprintf("%sNew external dependencies (%d):\n", t.badge("📦"), n)
+	case sectionDeprecatedImports:
+		return fmt.Sprintf("%sDeprecated imports (%d):\n", t.badge("📛"), n)
+	case sectionConfigChanges:
+		return t.badge("⚙️") + "Config changes in this PR (the analysis ran under the head configuration):\n"
+	case sectionSummary:
+		return "Summary:\n"
+	case sectionGoVersion:
+		return "\nGo version compatibility:\n"
+	}
+	return ""
+}
+
+func (t textFormat) item(level int, text string) string {
+	return strings.Repeat("  ", level) + text + "\n"
+}
+
+func (t textFormat) note(level int, text string) string { return t.item(level, text) }
+func (t textFormat) blockEnd() string                   { return "\n" }
+
+func (t textFormat) criticalName(name, docsURL string) string {
+	if docsURL == "" {
+		return t.r.DisplayName(name)
+	}
+	return fmt.Sprintf("%s (docs: %s)", t.r.DisplayName(name), docsURL)
+}
+
+func (t textFormat) changedPackage(name, renamedFrom string) string {
+	if renamedFrom != "" {
+		return t.item(1, fmt.Sprintf("%s (renamed from %s)", t.r.DisplayName(name), t.r.DisplayName(renamedFrom)))
+	}
+	return t.item(1, t.r.DisplayName(name))
+}
+
+func (t textFormat) implementers(intro string, items []string) string {
+	var b strings.Builder
+	b.WriteString(t.item(2, intro))
+	for _, item := range items {
+		b.WriteString(t.item(3, item))
+	}
+	return b.String()
+}
+
+func (t textFormat) affectedName(pkg *AffectedPackage) string {
+	if !pkg.IsCritical {
+		return t.r.DisplayName(pkg.Name)
+	}
+	return t.r.criticalBadge(pkg) + t.r.DisplayName(pkg.Name) + " (critical)"
+}
+
+func (t textFormat) labels(labels []string) string {
+	custom := customLabels(labels)
+	if len(custom) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(custom, ", ") + "]"
+}
+
+// affectedList renders the affected packages as the branches of a tree
+func (t textFormat) affectedList(total int, entries []string, hidden int) string {
+	var b strings.Builder
+	for i, entry := range entries {
+		branch := "├── "
+		if i == len(entries)-1 && hidden == 0 {
+			branch = "└── "
+		}
+		b.WriteString("    " + branch + entry + "\n")
+	}
+	if hidden > 0 {
+		b.WriteString(fmt.Sprintf("    └── … and %d more\n", hidden))
+	}
+	return b.String()
+}
+
+func (t textFormat) count(label string, n int) string {
+	return fmt.Sprintf("  %-43s%d\n", label+":", n)
+}
+
+func (t textFormat) areas(areas []*AreaCount) string {
+	var b strings.Builder
+	b.WriteString("\nChanged packages by area:\n")
+	for _, area := range areas {
+		b.WriteString(fmt.Sprintf("  %-40s  %d\n", area.Area, area.Packages))
+	}
+	return b.String()
+}
+
+func (t textFormat) collapsed(summary string, items []string) string {
+	var b strings.Builder
+	b.WriteString("\n" + summary + ":\n")
+	for _, item := range items {
+		b.WriteString(t.item(1, item))
+	}
+	return b.String()
+}
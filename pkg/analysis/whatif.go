@@ -0,0 +1,229 @@
+package analysis
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Edge is an import edge: From imports To
+type Edge struct {
+	From string
+	To   string
+}
+
+// ParseEdge parses an edge written as "from->to"
+func ParseEdge(s string) (Edge, error) {
+	from, to, ok := strings.Cut(s, "->")
+	from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+	if !ok || from == "" || to == "" {
+		return Edge{}, fmt.Errorf("invalid edge %q, expected from->to", s)
+	}
+	return Edge{From: from, To: to}, nil
+}
+
+// String returns the edge as "from->to"
+func (e Edge) String() string {
+	return e.From + "->" + e.To
+}
+
+// ReverseDependencyChange is how the transitive reverse dependencies of a
+// package differ between two trees
+type ReverseDependencyChange struct {
+	Package string
+	Added   []string // Packages that newly depend on it
+	Removed []string // Packages that no longer depend on it
+}
+
+// WithEdges returns an in-memory copy of the tree with the import edges of add
+// added and those of remove removed. The original tree and the disk are left
+// untouched. Every edge must connect packages of the tree, added edges must
+// not exist yet and removed edges must exist.
+func (t *Tree) WithEdges(add, remove []Edge) (*Tree, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	mutated := NewTree(t.RootDir, t.RootPkgPath)
+	mutated.IncludeTests = t.IncludeTests
+	mutated.BuildTags = t.BuildTags
+	mutated.TargetMarker = t.TargetMarker
+	mutated.AdditionalInternal = t.AdditionalInternal
+	mutated.ModuleDirs = t.ModuleDirs
+	mutated.Include = t.Include
+	for name, err := range t.Failed {
+		mutated.Failed[name] = err
+	}
+	for name, err := range t.LoadErrors {
+		mutated.LoadErrors[name] = err
+	}
+	for name, dir := range t.CaseMismatches {
+		mutated.CaseMismatches[name] = dir
+	}
+	for name, pkg := range t.Packages {
+		mutated.Packages[name] = &Pkg{
+			Name:        pkg.Name,
+			PackageName: pkg.PackageName,
+			Files:       pkg.Files,
+			Imports:     append([]string(nil), pkg.Imports...),
+			TestImports: pkg.TestImports,
+			Internal:    pkg.Internal,
+			Target:      pkg.Target,
+		}
+	}
+	for name, pkg := range t.Packages {
+		for _, dep := range pkg.Dependencies {
+			mutated.Packages[name].Dependencies = append(mutated.Packages[name].Dependencies, mutated.Packages[dep.Name])
+		}
+	}
+
+	for _, edge := range remove {
+		from, _, err := mutated.edgeEnds(edge)
+		if err != nil {
+			return nil, err
+		}
+		i := dependencyIndex(from, edge.To)
+		if i < 0 {
+			return nil, fmt.Errorf("failed to remove edge %s: %s does not import %s", edge, edge.From, edge.To)
+		}
+		from.Dependencies = append(from.Dependencies[:i], from.Dependencies[i+1:]...)
+		from.Imports = removeString(from.Imports, edge.To)
+	}
+	for _, edge := range add {
+		from, to, err := mutated.edgeEnds(edge)
+		if err != nil {
+			return nil, err
+		}
+		if dependencyIndex(from, edge.To) >= 0 {
+			return nil, fmt.Errorf("failed to add edge %s: %s already imports %s", edge, edge.From, edge.To)
+		}
+		from.Dependencies = append(from.Dependencies, to)
+		from.Imports = append(from.Imports, edge.To)
+	}
+	return mutated, nil
+}
+
+// WhatIf applies the edge mutations to a copy of the tree and returns, sorted
+// by package, the packages whose transitive reverse dependencies change
+func (t *Tree) WhatIf(add, remove []Edge) ([]*ReverseDependencyChange, error) {
+	mutated, err := t.WithEdges(add, remove)
+	if err != nil {
+		return nil, err
+	}
+
+	before := t.reverseDependencyIndex()
+	after := mutated.reverseDependencyIndex()
+
+	var changes []*ReverseDependencyChange
+	for name := range mutated.Packages {
+		old := reverseDepths(before, name, 0)
+		cur := reverseDepths(after, name, 0)
+
+		change := &ReverseDependencyChange{Package: name}
+		for dep := range cur {
+			if _, ok := old[dep]; !ok {
+				change.Added = append(change.Added, dep)
+			}
+		}
+		for dep := range old {
+			if _, ok := cur[dep]; !ok {
+				change.Removed = append(change.Removed, dep)
+			}
+		}
+		if len(change.Added) == 0 && len(change.Removed) == 0 {
+			continue
+		}
+		sort.Strings(change.Added)
+		sort.Strings(change.Removed)
+		changes = append(changes, change)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Package < changes[j].Package
+	})
+	return changes, nil
+}
+
+// edgeEnds looks up both packages of an edge
+func (t *Tree) edgeEnds(edge Edge) (*Pkg, *Pkg, error) {
+	from, ok := t.Packages[edge.From]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown package %s in edge %s", edge.From, edge)
+	}
+	to, ok := t.Packages[edge.To]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown package %s in edge %s", edge.To, edge)
+	}
+	if from == to {
+		return nil, nil, fmt.Errorf("invalid edge %s: a package cannot import itself", edge)
+	}
+	return from, to, nil
+}
+
+// dependencyIndex returns the position of name among the dependencies of pkg,
+// or -1
+func dependencyIndex(pkg *Pkg, name string) int {
+	for i, dep := range pkg.Dependencies {
+		if dep.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeString returns list without the occurrences of s
+func removeString(list []string, s string) []string {
+	var kept []string
+	for _, item := range list {
+		if item != s {
+			kept = append(kept, item)
+		}
+	}
+	return kept
+}
+
+// WhatIf resolves the whole repository and returns how the transitive reverse
+// dependencies of its packages change under the edge mutations, leaving out
+// ignored packages. Package names may be given relative to the root package.
+func (a *Analyzer) WhatIf(add, remove []Edge) ([]*ReverseDependencyChange, error) {
+	if a.tree == nil {
+		return nil, ErrNoRootPackage
+	}
+	if err := a.resolveAll(); err != nil {
+		return nil, err
+	}
+
+	changes, err := a.tree.WhatIf(a.qualifyEdges(add), a.qualifyEdges(remove))
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []*ReverseDependencyChange
+	for _, c := range changes {
+		if !a.cfg.ShouldIgnorePackage(c.Package) {
+			kept = append(kept, c)
+		}
+	}
+	return kept, nil
+}
+
+// qualifyEdges returns the edges with package names that are not in the tree
+// interpreted relative to the root package
+func (a *Analyzer) qualifyEdges(edges []Edge) []Edge {
+	qualified := make([]Edge, 0, len(edges))
+	for _, edge := range edges {
+		qualified = append(qualified, Edge{From: a.qualifyPackage(edge.From), To: a.qualifyPackage(edge.To)})
+	}
+	return qualified
+}
+
+// qualifyPackage returns the import path of a package given by import path or
+// relative to the root package
+func (a *Analyzer) qualifyPackage(name string) string {
+	if _, ok := a.tree.Packages[name]; ok {
+		return name
+	}
+	if rel := ImportPathFor(a.rootPkgPath, name); a.tree.Packages[rel] != nil {
+		return rel
+	}
+	return name
+}
@@ -0,0 +1,67 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEdge(t *testing.T) {
+	edge, err := ParseEdge(" a -> b ")
+	require.NoError(t, err)
+	require.Equal(t, Edge{From: "a", To: "b"}, edge)
+
+	for _, invalid := range []string{"a", "a->", "->b", "a-b"} {
+		_, err := ParseEdge(invalid)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestTreeWhatIf_RemoveEdge(t *testing.T) {
+	// a -> b -> d, c -> d
+	tree := newTestTree(map[string][]string{
+		"example.com/m/a": {"example.com/m/b"},
+		"example.com/m/b": {"example.com/m/d"},
+		"example.com/m/c": {"example.com/m/d"},
+		"example.com/m/d": nil,
+	})
+
+	changes, err := tree.WhatIf(nil, []Edge{{From: "example.com/m/b", To: "example.com/m/d"}})
+	require.NoError(t, err)
+	require.Equal(t, []*ReverseDependencyChange{
+		{Package: "example.com/m/d", Removed: []string{"example.com/m/a", "example.com/m/b"}},
+	}, changes)
+
+	// The reverse dependencies of d shrink in the copy only
+	mutated, err := tree.WithEdges(nil, []Edge{{From: "example.com/m/b", To: "example.com/m/d"}})
+	require.NoError(t, err)
+	require.Len(t, mutated.FindTransitiveReverseDependencies("example.com/m/d", 0), 1)
+	require.Len(t, tree.FindTransitiveReverseDependencies("example.com/m/d", 0), 3)
+	require.Equal(t, []string{"example.com/m/d"}, tree.Packages["example.com/m/b"].Imports)
+
+	_, err = tree.WhatIf(nil, []Edge{{From: "example.com/m/a", To: "example.com/m/c"}})
+	require.ErrorContains(t, err, "does not import")
+	_, err = tree.WhatIf([]Edge{{From: "example.com/m/a", To: "example.com/m/x"}}, nil)
+	require.ErrorContains(t, err, "unknown package example.com/m/x")
+}
+
+func TestAnalyzerWhatIf(t *testing.T) {
+	rootPkg := "github.com/a/b"
+	repoPath := writeRepo(t, map[string]string{
+		"a/a.go": "package a\n\nimport _ \"github.com/a/b/b\"\n",
+		"b/b.go": "package b",
+		"c/c.go": "package c",
+	})
+
+	analyzer := NewAnalyzer(config.DefaultConfig(), repoPath)
+	analyzer.SetRootPackage(rootPkg)
+
+	// Names relative to the module path are accepted
+	changes, err := analyzer.WhatIf([]Edge{{From: "a", To: "c"}}, []Edge{{From: "a", To: "b"}})
+	require.NoError(t, err)
+	require.Equal(t, []*ReverseDependencyChange{
+		{Package: rootPkg + "/b", Removed: []string{rootPkg + "/a"}},
+		{Package: rootPkg + "/c", Added: []string{rootPkg + "/a"}},
+	}, changes)
+}
@@ -0,0 +1,277 @@
+package analysis
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+)
+
+// WorkspaceModule is a module of a go.work workspace
+type WorkspaceModule struct {
+	Dir  string // Directory relative to the repository root, "" for the root
+	Path string // Module path declared by its go.mod
+}
+
+// ReadWorkFile returns the module directories listed by the use directives of
+// the go.work file in dir, slash-separated and relative to dir
+func ReadWorkFile(dir string) ([]string, error) {
+	content, err := os.ReadFile(filepath.Join(dir, "go.work"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read go.work: %w", err)
+	}
+
+	var dirs []string
+	inUse := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "//"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		switch {
+		case len(fields) == 0:
+			continue
+		case inUse && fields[0] == ")":
+			inUse = false
+			continue
+		case inUse:
+		case fields[0] == "use" && len(fields) >= 2 && fields[1] == "(":
+			inUse = true
+			continue
+		case fields[0] == "use" && len(fields) >= 2:
+			fields = fields[1:]
+		default:
+			continue
+		}
+		dir := path.Clean(filepath.ToSlash(unquoteModField(fields[0])))
+		if dir == "." {
+			dir = ""
+		}
+		dirs = append(dirs, dir)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to parse go.work: %w", err)
+	}
+	return dirs, nil
+}
+
+// ReadWorkspace returns the modules of the go.work workspace at the root of
+// the repository at repoPath, in the order go.work lists them
+func ReadWorkspace(repoPath string) ([]*WorkspaceModule, error) {
+	dirs, err := ReadWorkFile(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	modules := make([]*WorkspaceModule, 0, len(dirs))
+	for _, dir := range dirs {
+		mod, err := ReadModFile(filepath.Join(repoPath, filepath.FromSlash(dir)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read module %s of the workspace: %w", dir, err)
+		}
+		modules = append(modules, &WorkspaceModule{Dir: dir, Path: mod.Module})
+	}
+	return modules, nil
+}
+
+// Workspace analyzes the modules of a go.work workspace together, each with
+// its own Analyzer. Each module resolves the packages of the others it imports
+// from their directories, so changes reach the modules depending on them.
+type Workspace struct {
+	modules     []*WorkspaceModule
+	analyzers   []*Analyzer
+	parallelism int
+}
+
+// NewWorkspace creates an analyzer for every module of a workspace in the
+// repository at repoPath, each with a copy of cfg pointing to its directory.
+// setup, if not nil, is called on each analyzer before its root package is
+// set, in module order, to apply the settings shared by the modules.
+func NewWorkspace(cfg *config.Config, repoPath string, modules []*WorkspaceModule, setup func(a *Analyzer, module *WorkspaceModule)) *Workspace {
+	w := &Workspace{modules: modules}
+	for _, module := range modules {
+		moduleCfg := *cfg
+		moduleCfg.Analysis.ModuleDir = module.Dir
+		analyzer := NewAnalyzer(&moduleCfg, repoPath)
+		if setup != nil {
+			setup(analyzer, module)
+		}
+		analyzer.SetWorkspace(modules)
+		analyzer.SetRootPackage(module.Path)
+		w.analyzers = append(w.analyzers, analyzer)
+	}
+	return w
+}
+
+// SetParallelism bounds the number of modules analyzed concurrently; 0 or
+// less analyzes them all at once
+func (w *Workspace) SetParallelism(n int) {
+	w.parallelism = n
+}
+
+// AnalyzeChangedPackages analyzes the changed files, relative to the
+// repository root, in every module concurrently and merges the results in
+// module order, so the result does not depend on scheduling
+func (w *Workspace) AnalyzeChangedPackages(changedFiles []string) (*AnalysisResult, error) {
+	if len(w.analyzers) == 0 {
+		return nil, ErrNoRootPackage
+	}
+	parallelism := w.parallelism
+	if parallelism <= 0 || parallelism > len(w.analyzers) {
+		parallelism = len(w.analyzers)
+	}
+
+	results := make([]*AnalysisResult, len(w.analyzers))
+	errs := make([]error, len(w.analyzers))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, analyzer := range w.analyzers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i], errs[i] = analyzer.AnalyzeChangedPackages(changedFiles)
+		}()
+	}
+	wg.Wait()
+
+	for i, module := range w.modules {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("failed to analyze module %s: %w", module.Path, errs[i])
+		}
+	}
+	return mergeResults(results, w.analyzers[0].cfg), nil
+}
+
+// mergeResults merges the results of the modules of a workspace, in order,
+// and recomputes the severity with the thresholds of cfg
+func mergeResults(results []*AnalysisResult, cfg *config.Config) *AnalysisResult {
+	merged := results[0]
+	for _, result := range results[1:] {
+		merged.Merge(result)
+	}
+	merged.Severity = severityFor(merged.CriticalCount, cfg.Critical.WarnThreshold, cfg.Critical.BlockThreshold)
+	return merged
+}
+
+// ChangedPackages maps changed files to the sorted, deduplicated import paths
+// of the packages containing them in any module, as Analyzer.ChangedPackages
+func (w *Workspace) ChangedPackages(changedFiles []string) []string {
+	var changed []string
+	for _, analyzer := range w.analyzers {
+		changed = unionStrings(changed, analyzer.ChangedPackages(changedFiles))
+	}
+	return changed
+}
+
+// PackageDir returns the directory of a package of the workspace, as
+// Analyzer.PackageDir, looked up by the module holding it
+func (w *Workspace) PackageDir(pkgName string) (string, bool) {
+	owner := 0
+	for i, module := range w.modules {
+		if _, ok := DirFor(module.Path, pkgName); ok && len(module.Path) > len(w.modules[owner].Path) {
+			owner = i
+		}
+	}
+	if len(w.analyzers) == 0 {
+		return "", false
+	}
+	return w.analyzers[owner].PackageDir(pkgName)
+}
+
+// AnalyzeWorkspace analyzes the changed files, relative to the repository
+// root, in every module of a workspace and merges the results. The modules
+// are analyzed concurrently, at most parallelism at a time (0 or less for no
+// limit).
+func AnalyzeWorkspace(cfg *config.Config, repoPath string, modules []*WorkspaceModule, changedFiles []string, parallelism int) (*AnalysisResult, error) {
+	w := NewWorkspace(cfg, repoPath, modules, nil)
+	w.SetParallelism(parallelism)
+	return w.AnalyzeChangedPackages(changedFiles)
+}
+
+// SetWorkspace sets the modules of the workspace the analyzed module belongs
+// to. Imports of the other modules are resolved from their directories, and
+// changed files in them map to their packages once resolved. It must be called
+// before SetRootPackage.
+func (a *Analyzer) SetWorkspace(modules []*WorkspaceModule) {
+	a.workspace = modules
+}
+
+// otherModules returns the modules of the workspace besides the analyzed one
+func (a *Analyzer) otherModules() []*WorkspaceModule {
+	var others []*WorkspaceModule
+	for _, module := range a.workspace {
+		if module.Path != a.rootPkgPath {
+			others = append(others, module)
+		}
+	}
+	return others
+}
+
+// linkWorkspace lets a tree of the checkout at repoPath resolve the packages
+// of the other modules of the workspace from their directories
+func (a *Analyzer) linkWorkspace(t *Tree, repoPath string) {
+	others := a.otherModules()
+	if len(others) == 0 {
+		return
+	}
+	t.AdditionalInternal = slices.Clip(t.AdditionalInternal)
+	t.ModuleDirs = make(map[string]string, len(others))
+	for _, module := range others {
+		t.AdditionalInternal = append(t.AdditionalInternal, module.Path)
+		t.ModuleDirs[module.Path] = filepath.Join(repoPath, filepath.FromSlash(module.Dir))
+	}
+}
+
+// packageModule returns the other workspace module holding a package, the one
+// with the longest path when modules nest, or nil when it belongs to none
+func (a *Analyzer) packageModule(pkgName string) *WorkspaceModule {
+	var owner *WorkspaceModule
+	for _, module := range a.otherModules() {
+		if _, ok := DirFor(module.Path, pkgName); !ok {
+			continue
+		}
+		if owner == nil || len(module.Path) > len(owner.Path) {
+			owner = module
+		}
+	}
+	return owner
+}
+
+// fileModule returns the other workspace module holding a file relative to
+// the repository root, the one with the deepest directory containing it, or
+// nil when the file belongs to the analyzed module or to none
+func (a *Analyzer) fileModule(file string) *WorkspaceModule {
+	var owner *WorkspaceModule
+	for _, module := range a.workspace {
+		if !withinModuleDir(module.Dir, file) {
+			continue
+		}
+		if owner == nil || len(module.Dir) > len(owner.Dir) {
+			owner = module
+		}
+	}
+	if owner == nil || owner.Path == a.rootPkgPath {
+		return nil
+	}
+	return owner
+}
+
+// withinModuleDir reports whether a slash-separated path relative to the
+// repository root lies in the module directory dir
+func withinModuleDir(dir, file string) bool {
+	if dir == "" {
+		return true
+	}
+	return strings.HasPrefix(path.Clean("/"+file)+"/", "/"+dir+"/")
+}
@@ -0,0 +1,109 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadWorkspace(t *testing.T) {
+	repoPath := writeRepo(t, map[string]string{
+		"go.work": `go 1.24
+
+use ./tools // build tooling
+
+use (
+	.
+	"./services/api"
+)`,
+		"go.mod":              "module example.com/root",
+		"tools/go.mod":        "module example.com/tools",
+		"services/api/go.mod": "module example.com/api",
+	})
+
+	modules, err := ReadWorkspace(repoPath)
+	require.NoError(t, err)
+	require.Equal(t, []*WorkspaceModule{
+		{Dir: "tools", Path: "example.com/tools"},
+		{Dir: "", Path: "example.com/root"},
+		{Dir: "services/api", Path: "example.com/api"},
+	}, modules)
+}
+
+func TestAnalyzeWorkspace(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Critical.Packages = config.CriticalPatterns("**/app")
+
+	t.Run("independent modules", func(t *testing.T) {
+		repoPath := writeRepo(t, map[string]string{
+			"a/go.mod": "module example.com/a",
+			"a/app/app.go": `package app
+
+import "example.com/a/lib"
+
+var _ = lib.L`,
+			"a/lib/lib.go": "package lib\n\nvar L = 1",
+			"b/go.mod":     "module example.com/b",
+			"b/srv/srv.go": `package srv
+
+import "example.com/b/util"
+
+var _ = util.U`,
+			"b/util/util.go": "package util\n\nvar U = 1",
+		})
+		modules := []*WorkspaceModule{{Dir: "a", Path: "example.com/a"}, {Dir: "b", Path: "example.com/b"}}
+		changed := []string{"a/lib/lib.go", "b/util/util.go"}
+
+		parallel, err := AnalyzeWorkspace(cfg, repoPath, modules, changed, 2)
+		require.NoError(t, err)
+
+		// Without imports between them, the workspace analysis is the merge
+		// of analyzing each module on its own
+		var expected *AnalysisResult
+		for _, module := range modules {
+			moduleCfg := *cfg
+			moduleCfg.Analysis.ModuleDir = module.Dir
+			analyzer := NewAnalyzer(&moduleCfg, repoPath)
+			analyzer.SetRootPackage(module.Path)
+			result, err := analyzer.AnalyzeChangedPackages(changed)
+			require.NoError(t, err)
+			require.Len(t, result.Impacts, 1)
+			if expected == nil {
+				expected = result
+			} else {
+				expected.Merge(result)
+			}
+		}
+		expected.Severity = severityFor(expected.CriticalCount, cfg.Critical.WarnThreshold, cfg.Critical.BlockThreshold)
+		require.Equal(t, expected, parallel)
+
+		require.Len(t, parallel.Impacts, 2)
+		require.Equal(t, "example.com/a/lib", parallel.Impacts[0].ChangedPackage)
+		require.Equal(t, "example.com/b/util", parallel.Impacts[1].ChangedPackage)
+		require.Equal(t, []string{"example.com/a/app", "example.com/b/srv"}, parallel.AffectedPackageNames())
+		require.Equal(t, 1, parallel.CriticalCount)
+		require.Equal(t, "example.com", parallel.ModulePath)
+	})
+
+	t.Run("cross-module import", func(t *testing.T) {
+		repoPath := writeRepo(t, map[string]string{
+			"a/go.mod": "module example.com/a",
+			"a/app/app.go": `package app
+
+import "example.com/b/util"
+
+var _ = util.U`,
+			"b/go.mod":       "module example.com/b",
+			"b/util/util.go": "package util\n\nvar U = 1",
+		})
+		modules := []*WorkspaceModule{{Dir: "a", Path: "example.com/a"}, {Dir: "b", Path: "example.com/b"}}
+
+		result, err := AnalyzeWorkspace(cfg, repoPath, modules, []string{"b/util/util.go"}, 0)
+		require.NoError(t, err)
+		require.Len(t, result.Impacts, 1)
+		require.Equal(t, "example.com/b/util", result.Impacts[0].ChangedPackage)
+		require.Equal(t, []string{"example.com/a/app"}, result.CriticalPackageNames())
+		require.Empty(t, result.Warnings)
+	})
+}
@@ -0,0 +1,48 @@
+package backoff
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff computes retry delays with exponential growth, a cap and full
+// jitter: the delay before retry n is drawn uniformly from
+// [0, min(Max, Base*2^(n-1))]. Randomizing the whole interval keeps clients
+// that failed together from retrying in lockstep.
+type Backoff struct {
+	Base time.Duration // Upper bound of the first delay
+	Max  time.Duration // Upper bound of every delay; zero means uncapped
+}
+
+// Delay returns the delay to wait before the given retry, counted from 1
+func (b Backoff) Delay(retry int) time.Duration {
+	limit := b.Limit(retry)
+	if limit <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int64N(int64(limit) + 1))
+}
+
+// Limit returns the upper bound of the delay before the given retry
+func (b Backoff) Limit(retry int) time.Duration {
+	if b.Base <= 0 {
+		return 0
+	}
+
+	limit := b.Base
+	for i := 1; i < retry; i++ {
+		if b.Max > 0 && limit >= b.Max {
+			break
+		}
+		if limit > math.MaxInt64/2 {
+			// Doubling would overflow
+			break
+		}
+		limit *= 2
+	}
+	if b.Max > 0 && limit > b.Max {
+		limit = b.Max
+	}
+	return limit
+}
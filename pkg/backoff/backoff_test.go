@@ -0,0 +1,35 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackoffLimit(t *testing.T) {
+	b := Backoff{Base: time.Second, Max: 5 * time.Second}
+	require.Equal(t, time.Second, b.Limit(1))
+	require.Equal(t, 2*time.Second, b.Limit(2))
+	require.Equal(t, 4*time.Second, b.Limit(3))
+	require.Equal(t, 5*time.Second, b.Limit(4))
+	require.Equal(t, 5*time.Second, b.Limit(100))
+
+	require.Zero(t, Backoff{}.Limit(3))
+	require.Positive(t, Backoff{Base: time.Second}.Limit(1000), "uncapped limits do not overflow")
+}
+
+func TestBackoffDelay_JitteredWithinCap(t *testing.T) {
+	b := Backoff{Base: time.Second, Max: 3 * time.Second}
+
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		delay := b.Delay(5)
+		require.GreaterOrEqual(t, delay, time.Duration(0))
+		require.LessOrEqual(t, delay, 3*time.Second)
+		seen[delay] = true
+	}
+	require.Greater(t, len(seen), 1, "delays vary across invocations")
+
+	require.Zero(t, Backoff{}.Delay(1))
+}
@@ -1,9 +1,17 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/bmatcuk/doublestar/v4"
 	"go.uber.org/zap"
@@ -13,6 +21,24 @@ import (
 // DefaultConfigName is the default name of the config file
 const DefaultConfigName = ".dependency-guardian.yml"
 
+// DefaultTargetMarker is the default name of the file marking a package as a
+// high-level target
+const DefaultTargetMarker = ".dep-guardian-target"
+
+// ErrConfigNotFound is returned when a config file that must exist is missing.
+// It matches fs.ErrNotExist.
+type ErrConfigNotFound struct {
+	Path string
+}
+
+func (e *ErrConfigNotFound) Error() string {
+	return fmt.Sprintf("config file not found: %s", e.Path)
+}
+
+func (e *ErrConfigNotFound) Unwrap() error {
+	return fs.ErrNotExist
+}
+
 // DefaultConfig returns a default configuration
 func DefaultConfig() *Config {
 	return &Config{
@@ -21,35 +47,53 @@ func DefaultConfig() *Config {
 			HighLevelPackages: []string{
 				"**",
 			},
+			MarkerFile: DefaultTargetMarker,
 		},
 		Patterns: PatternConfig{
 			// Only ignore test files by default
-			IgnorePatterns: []string{
+			IgnoreFilePatterns: []string{
 				"*_test.go",
 			},
-			IncludePatterns: []string{},
+			IgnorePackagePatterns: []string{},
+			IncludePatterns:       []string{},
 		},
 		Analysis: AnalysisConfig{
-			MaxDepth:           10,  // Increased depth
-			MinImpactThreshold: 0,   // Show all impacts
+			MaxDepth:           10, // Increased depth
+			MinImpactThreshold: 0,  // Show all impacts
+			IncludeTests:       false,
 		},
 		Critical: CriticalConfig{
-			Packages: []string{},
+			Packages: []CriticalPattern{},
+			// Any critical impact warns; blocking takes several unless configured otherwise
+			WarnThreshold:  1,
+			BlockThreshold: 3,
+		},
+		Output: OutputConfig{
+			// Critical packages are listed even beyond the cap
+			MaxAffectedPerPackage: 25,
+			Emoji:                 true,
 		},
 	}
 }
 
 // LoadConfig loads the configuration.
-// If a specific configFilePath is provided, it is used.
-// If configFilePath is empty, it looks for the default config file in repoPath.
-func LoadConfig(repoPath, configFilePath string) (*Config, error) {
+// If configFilePaths are provided, they are used, later files being merged
+// over earlier ones (see MergeConfigFiles).
+// If none is provided, it looks for the default config file in repoPath.
+// When required is true, a missing default config file is an error instead of
+// falling back to the default configuration.
+func LoadConfig(repoPath string, configFilePaths []string, required bool) (*Config, error) {
+	if len(configFilePaths) > 1 {
+		return MergeConfigFiles(configFilePaths)
+	}
+
 	config := DefaultConfig()
 
 	var loadPath string
-	explicitPathProvided := configFilePath != ""
+	explicitPathProvided := len(configFilePaths) == 1
 
 	if explicitPathProvided {
-		loadPath = configFilePath
+		loadPath = configFilePaths[0]
 	} else {
 		loadPath = filepath.Join(repoPath, DefaultConfigName)
 	}
@@ -59,7 +103,11 @@ func LoadConfig(repoPath, configFilePath string) (*Config, error) {
 		if os.IsNotExist(err) {
 			if explicitPathProvided {
 				// User specified a file that doesn't exist. This is an error.
-				return nil, fmt.Errorf("config file not found at specified path: %s", loadPath)
+				return nil, &ErrConfigNotFound{Path: loadPath}
+			}
+			if required {
+				// The caller wants a missing config to be treated as misconfiguration.
+				return nil, &ErrConfigNotFound{Path: loadPath}
 			}
 			// Default file doesn't exist. This is fine, use defaults.
 			zap.S().Infow("no default config file found, using default configuration", "path", loadPath)
@@ -70,13 +118,251 @@ func LoadConfig(repoPath, configFilePath string) (*Config, error) {
 	}
 
 	// Parse config file
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	config, err = LoadConfigFromReader(bytes.NewReader(data))
+	if err != nil {
 		return nil, fmt.Errorf("failed to parse config file %s: %w", loadPath, err)
 	}
 
 	return config, nil
 }
 
+// appendedLists are the list settings that accumulate across merged config
+// files, so that a repository adds to the critical packages and ignore
+// patterns of a shared base policy. Every other list, such as those under
+// analysis, is replaced by the last file setting it.
+var appendedLists = map[string]bool{
+	"critical.packages":                true,
+	"patterns.ignore_file_patterns":    true,
+	"patterns.ignore_package_patterns": true,
+	"patterns.ignore_patterns":         true,
+	"overrides":                        true,
+	"layering.rules":                   true,
+}
+
+// MergeConfigFiles loads the config files at paths, merging each over the
+// previous ones: scalar settings are overridden, mappings such as docs are
+// merged key by key, and lists are appended or replaced as described by
+// appendedLists.
+func MergeConfigFiles(paths []string) (*Config, error) {
+	var merged *yaml.Node
+	for _, loadPath := range paths {
+		data, err := os.ReadFile(loadPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil, &ErrConfigNotFound{Path: loadPath}
+			}
+			return nil, fmt.Errorf("failed to read config file %s: %w", loadPath, err)
+		}
+
+		var doc yaml.Node
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", loadPath, err)
+		}
+		if len(doc.Content) == 0 {
+			// An empty file sets nothing
+			continue
+		}
+		root := doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("failed to parse config file %s: the configuration must be a mapping", loadPath)
+		}
+		if merged == nil {
+			merged = root
+			continue
+		}
+		mergeMappings(merged, root, "")
+	}
+
+	if merged == nil {
+		return DefaultConfig(), nil
+	}
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge config files: %w", err)
+	}
+	config, err := LoadConfigFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config files %s: %w", strings.Join(paths, ", "), err)
+	}
+	return config, nil
+}
+
+// mergeMappings merges the YAML mapping src over dst in place. prefix is the
+// dotted path of the mapping within the configuration.
+func mergeMappings(dst, src *yaml.Node, prefix string) {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], src.Content[i+1]
+		keyPath := key.Value
+		if prefix != "" {
+			keyPath = prefix + "." + key.Value
+		}
+
+		existing := mappingValue(dst, key.Value)
+		switch {
+		case existing == nil:
+			dst.Content = append(dst.Content, key, value)
+		case existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode:
+			mergeMappings(existing, value, keyPath)
+		case existing.Kind == yaml.SequenceNode && value.Kind == yaml.SequenceNode && appendedLists[keyPath]:
+			existing.Content = append(existing.Content, value.Content...)
+		default:
+			*existing = *value
+		}
+	}
+}
+
+// mappingValue returns the value of key in the YAML mapping node, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// LoadConfigFromReader parses a configuration from r, applied over the defaults.
+// It lets callers supply config from any source, such as an HTTP response or a
+// file fetched from another repository.
+func LoadConfigFromReader(r io.Reader) (*Config, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	config := DefaultConfig()
+	if err := yaml.Unmarshal(data, config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+
+	if len(config.Patterns.IgnorePatterns) > 0 {
+		zap.S().Warnw("patterns.ignore_patterns is deprecated, use patterns.ignore_file_patterns instead", "patterns", config.Patterns.IgnorePatterns)
+
+		// Like before the split, ignore_patterns alone replaces the default
+		// file patterns
+		var set struct {
+			Patterns struct {
+				IgnoreFilePatterns *[]string `yaml:"ignore_file_patterns"`
+			} `yaml:"patterns"`
+		}
+		if err := yaml.Unmarshal(data, &set); err == nil && set.Patterns.IgnoreFilePatterns == nil {
+			config.Patterns.IgnoreFilePatterns = nil
+		}
+	}
+
+	if err := config.validateLayering(); err != nil {
+		return nil, err
+	}
+
+	if err := config.mergeOverrides(); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// mergeOverrides resolves the configuration of every override. Overrides are
+// merged over the override of the closest enclosing prefix, or over the base
+// configuration.
+func (c *Config) mergeOverrides() error {
+	sort.SliceStable(c.Overrides, func(i, j int) bool {
+		return len(normalizePrefix(c.Overrides[i].PathPrefix)) < len(normalizePrefix(c.Overrides[j].PathPrefix))
+	})
+
+	for i, override := range c.Overrides {
+		prefix := normalizePrefix(override.PathPrefix)
+		if prefix == "" {
+			return fmt.Errorf("override %d: path_prefix is required", i)
+		}
+		if override.Config.Kind != 0 && override.Config.Kind != yaml.MappingNode {
+			return fmt.Errorf("override %s: config must be a mapping", prefix)
+		}
+
+		merged, err := c.ForPackageDir(prefix).clone()
+		if err != nil {
+			return fmt.Errorf("failed to merge override %s: %w", prefix, err)
+		}
+		if override.Config.Kind != 0 {
+			if err := override.Config.Decode(merged); err != nil {
+				return fmt.Errorf("failed to parse override %s: %w", prefix, err)
+			}
+		}
+		if len(merged.Overrides) > 0 {
+			return fmt.Errorf("override %s: overrides cannot be nested", prefix)
+		}
+		merged.Overrides = nil
+		override.merged = merged
+	}
+	return nil
+}
+
+// clone returns a deep copy of the configuration without its overrides
+func (c *Config) clone() (*Config, error) {
+	base := *c
+	base.Overrides = nil
+	data, err := yaml.Marshal(&base)
+	if err != nil {
+		return nil, err
+	}
+	clone := &Config{}
+	if err := yaml.Unmarshal(data, clone); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// ForPackageDir returns the configuration that applies to the package in dir,
+// relative to the repository root: the override with the longest path prefix
+// containing dir, or c itself when none does
+func (c *Config) ForPackageDir(dir string) *Config {
+	dir = normalizePrefix(dir)
+	best := c
+	bestLen := -1
+	for _, override := range c.Overrides {
+		prefix := normalizePrefix(override.PathPrefix)
+		if override.merged == nil || len(prefix) <= bestLen {
+			continue
+		}
+		if dir == prefix || strings.HasPrefix(dir, prefix+"/") {
+			best = override.merged
+			bestLen = len(prefix)
+		}
+	}
+	return best
+}
+
+// normalizePrefix cleans a directory prefix, dropping a trailing "/**"
+func normalizePrefix(prefix string) string {
+	prefix = strings.TrimSuffix(strings.TrimSpace(prefix), "/**")
+	return strings.Trim(path.Clean("/"+prefix), "/")
+}
+
+// LoadConfigFS loads the configuration at path within fsys, such as an
+// embed.FS. Unlike the default repository config, a missing file is an error.
+func LoadConfigFS(fsys fs.FS, path string) (*Config, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, &ErrConfigNotFound{Path: path}
+		}
+		return nil, fmt.Errorf("failed to open config file %s: %w", path, err)
+	}
+	defer f.Close()
+
+	config, err := LoadConfigFromReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config file %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// ModuleRoot returns the directory of the module within the repository at
+// repoPath
+func (c *Config) ModuleRoot(repoPath string) string {
+	return filepath.Join(repoPath, filepath.FromSlash(c.Analysis.ModuleDir))
+}
+
 // IsHighLevelPackage checks if a package matches any of the high-level package patterns
 func (c *Config) IsHighLevelPackage(pkgPath string) bool {
 	// If no high-level packages are defined, consider everything a target.
@@ -94,7 +380,64 @@ func (c *Config) IsHighLevelPackage(pkgPath string) bool {
 
 // IsCriticalPackage checks if a package matches any of the critical package patterns
 func (c *Config) IsCriticalPackage(pkgPath string) bool {
-	for _, pattern := range c.Critical.Packages {
+	for _, critical := range c.Critical.Packages {
+		if matched, _ := doublestar.Match(critical.Pattern, pkgPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// CriticalBadge returns the badge configured for the most specific critical
+// pattern with a badge matching the package, or "" for the default badge
+func (c *Config) CriticalBadge(pkgPath string) string {
+	badges := make(map[string]string)
+	for _, critical := range c.Critical.Packages {
+		if critical.Badge != "" {
+			badges[critical.Pattern] = critical.Badge
+		}
+	}
+	return mostSpecificMatch(badges, pkgPath)
+}
+
+// CriticalPatterns returns critical patterns without badges, as listed in a
+// config file of plain strings
+func CriticalPatterns(patterns ...string) []CriticalPattern {
+	critical := make([]CriticalPattern, 0, len(patterns))
+	for _, pattern := range patterns {
+		critical = append(critical, CriticalPattern{Pattern: pattern})
+	}
+	return critical
+}
+
+// UnmarshalYAML accepts either a plain pattern or a mapping with a badge
+func (p *CriticalPattern) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*p = CriticalPattern{Pattern: value.Value}
+		return nil
+	}
+	type plain CriticalPattern
+	if err := value.Decode((*plain)(p)); err != nil {
+		return err
+	}
+	if p.Pattern == "" {
+		return fmt.Errorf("line %d: critical package without a pattern", value.Line)
+	}
+	return nil
+}
+
+// MarshalYAML writes a pattern without a badge as a plain string
+func (p CriticalPattern) MarshalYAML() (interface{}, error) {
+	if p.Badge == "" {
+		return p.Pattern, nil
+	}
+	type plain CriticalPattern
+	return plain(p), nil
+}
+
+// IsEntrypoint checks if a package matches any of the entrypoint patterns
+func (c *Config) IsEntrypoint(pkgPath string) bool {
+	for _, pattern := range c.Analysis.Entrypoints {
 		if matched, _ := doublestar.Match(pattern, pkgPath); matched {
 			return true
 		}
@@ -102,13 +445,208 @@ func (c *Config) IsCriticalPackage(pkgPath string) bool {
 	return false
 }
 
-// ShouldIgnorePackage checks if a package should be ignored based on ignore patterns
+// IsCriticalSource checks if a changed package is allowed to affect critical
+// packages. Every package is allowed when no critical sources are defined.
+func (c *Config) IsCriticalSource(pkgPath string) bool {
+	if len(c.Policy.CriticalSources) == 0 {
+		return true
+	}
+
+	for _, pattern := range c.Policy.CriticalSources {
+		if matched, _ := doublestar.Match(pattern, pkgPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDeprecatedPackage checks if a package matches any of the deprecated
+// package patterns
+func (c *Config) IsDeprecatedPackage(pkgPath string) bool {
+	for _, pattern := range c.Policy.DeprecatedPackages {
+		if matched, _ := doublestar.Match(pattern, pkgPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// DocsURL returns the documentation URL mapped to a package, or "" when no
+// pattern matches. When several patterns match, the most specific one wins:
+// the one with the most literal characters, then the fewest wildcards, then
+// the lexically first.
+func (c *Config) DocsURL(pkgPath string) string {
+	return mostSpecificMatch(c.Docs, pkgPath)
+}
+
+// ServiceName returns the service name mapped to a package, or "" when no
+// pattern matches. Like DocsURL, the most specific pattern wins.
+func (c *Config) ServiceName(pkgPath string) string {
+	return mostSpecificMatch(c.Services, pkgPath)
+}
+
+// mostSpecificMatch returns the value of the most specific pattern of
+// patterns matching pkgPath, or ""
+func mostSpecificMatch(patterns map[string]string, pkgPath string) string {
+	best := ""
+	for pattern := range patterns {
+		if matched, _ := doublestar.Match(pattern, pkgPath); !matched {
+			continue
+		}
+		if best == "" || moreSpecific(pattern, best) {
+			best = pattern
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return patterns[best]
+}
+
+// moreSpecific reports whether pattern a is more specific than pattern b
+func moreSpecific(a, b string) bool {
+	literalA, wildA := patternWeight(a)
+	literalB, wildB := patternWeight(b)
+	if literalA != literalB {
+		return literalA > literalB
+	}
+	if wildA != wildB {
+		return wildA < wildB
+	}
+	return a < b
+}
+
+// patternWeight counts the literal characters of a pattern, ignoring path
+// separators, and its wildcard characters
+func patternWeight(pattern string) (literal, wildcards int) {
+	for _, r := range pattern {
+		switch {
+		case strings.ContainsRune("*?[]{}", r):
+			wildcards++
+		case r != '/':
+			literal++
+		}
+	}
+	return literal, wildcards
+}
+
+// LayerOf returns the layer of a package, or "" when it belongs to none. When
+// patterns of several layers match, the most specific pattern wins, as for
+// DocsURL.
+func (c *Config) LayerOf(pkgPath string) string {
+	patterns := make(map[string]string)
+	for layer, layerPatterns := range c.Layering.Layers {
+		for _, pattern := range layerPatterns {
+			if current, ok := patterns[pattern]; !ok || layer < current {
+				patterns[pattern] = layer
+			}
+		}
+	}
+	return mostSpecificMatch(patterns, pkgPath)
+}
+
+// LayerImportAllowed checks if the layering rules let a package of layer from
+// import a package of layer to. Imports within a layer, and from or to
+// packages outside every layer, are always allowed.
+func (c *Config) LayerImportAllowed(from, to string) bool {
+	if from == "" || to == "" || from == to {
+		return true
+	}
+	for _, rule := range c.Layering.Rules {
+		if rule.From != from {
+			continue
+		}
+		if slices.Contains(rule.Deny, to) {
+			return false
+		}
+		if len(rule.Allow) > 0 && !slices.Contains(rule.Allow, to) {
+			return false
+		}
+	}
+	return true
+}
+
+// validateLayering checks that the layering rules only refer to defined layers
+func (c *Config) validateLayering() error {
+	for _, rule := range c.Layering.Rules {
+		for _, layer := range append(append([]string{rule.From}, rule.Allow...), rule.Deny...) {
+			if _, ok := c.Layering.Layers[layer]; !ok {
+				return fmt.Errorf("layering rule from %q refers to undefined layer %q", rule.From, layer)
+			}
+		}
+	}
+	return nil
+}
+
+// ShouldIgnorePackage checks if a package path matches any of the package ignore patterns
 func (c *Config) ShouldIgnorePackage(pkgPath string) bool {
-	// Only ignore test files and explicitly ignored patterns
-	for _, pattern := range c.Patterns.IgnorePatterns {
+	for _, pattern := range c.Patterns.IgnorePackagePatterns {
+		if matched, _ := doublestar.Match(pattern, pkgPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// IsStrictImportException checks if an internal import path may be
+// unresolvable in strict imports mode
+func (c *Config) IsStrictImportException(pkgPath string) bool {
+	for _, pattern := range c.Analysis.StrictImportExceptions {
+		if matched, _ := doublestar.Match(pattern, pkgPath); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// ShouldAnalyzeFile checks if a changed file passes the file ignore and include
+// patterns. Files matching an ignore pattern, including the deprecated
+// ignore_patterns, are excluded; when include patterns other than import path
+// globs are defined, a file must also match one of them.
+func (c *Config) ShouldAnalyzeFile(filePath string) bool {
+	ignorePatterns := append(append([]string{}, c.Patterns.IgnoreFilePatterns...), c.Patterns.IgnorePatterns...)
+	for _, pattern := range ignorePatterns {
+		if matched, _ := doublestar.Match(pattern, filePath); matched {
+			return false
+		}
+	}
+
+	restricted := false
+	for _, pattern := range c.Patterns.IncludePatterns {
+		if isImportPathPattern(pattern) {
+			continue
+		}
+		restricted = true
+		if matched, _ := doublestar.Match(pattern, filePath); matched {
+			return true
+		}
+	}
+	return !restricted
+}
+
+// IsIncludedPackage reports whether a package matches an include pattern
+// written as an import path glob, such as "github.com/org/shared/**". External
+// imports matching one are tracked like internal packages when their
+// directory is vendored.
+func (c *Config) IsIncludedPackage(pkgPath string) bool {
+	for _, pattern := range c.Patterns.IncludePatterns {
+		if !isImportPathPattern(pattern) {
+			continue
+		}
 		if matched, _ := doublestar.Match(pattern, pkgPath); matched {
 			return true
 		}
 	}
 	return false
-} 
\ No newline at end of file
+}
+
+// isImportPathPattern reports whether an include pattern is an import path
+// glob rather than a file glob: its first element is a domain name, such as
+// github.com, and it does not match Go files
+func isImportPathPattern(pattern string) bool {
+	first, rest, ok := strings.Cut(pattern, "/")
+	return ok && rest != "" &&
+		strings.Contains(first, ".") &&
+		!strings.ContainsAny(first, "*?[{") &&
+		!strings.HasSuffix(pattern, ".go")
+}
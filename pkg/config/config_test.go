@@ -0,0 +1,376 @@
+package config
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_MissingDefaultFile(t *testing.T) {
+	repoPath := t.TempDir()
+
+	// Without the requirement, defaults are used
+	cfg, err := LoadConfig(repoPath, nil, false)
+	require.NoError(t, err)
+	require.Equal(t, DefaultConfig(), cfg)
+
+	// With the requirement, a missing default file is an error
+	cfg, err = LoadConfig(repoPath, nil, true)
+	require.Error(t, err)
+	require.Nil(t, cfg)
+	require.Contains(t, err.Error(), filepath.Join(repoPath, DefaultConfigName))
+}
+
+func TestLoadConfig_RequiredDefaultFilePresent(t *testing.T) {
+	repoPath := t.TempDir()
+	content := `critical:
+  packages:
+    - "**/auth"
+`
+	err := os.WriteFile(filepath.Join(repoPath, DefaultConfigName), []byte(content), 0644)
+	require.NoError(t, err)
+
+	cfg, err := LoadConfig(repoPath, nil, true)
+	require.NoError(t, err)
+	require.Equal(t, CriticalPatterns("**/auth"), cfg.Critical.Packages)
+}
+
+func TestShouldAnalyzeFile(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Patterns.IgnoreFilePatterns = []string{"**/mocks/**"}
+
+	require.True(t, cfg.ShouldAnalyzeFile("internal/db/db.go"))
+	require.False(t, cfg.ShouldAnalyzeFile("internal/db/mocks/db.go"))
+
+	// Include patterns restrict the analyzed files, ignore patterns still apply
+	cfg.Patterns.IncludePatterns = []string{"internal/**"}
+	require.True(t, cfg.ShouldAnalyzeFile("internal/db/db.go"))
+	require.False(t, cfg.ShouldAnalyzeFile("api/handler.go"))
+	require.False(t, cfg.ShouldAnalyzeFile("internal/db/mocks/db.go"))
+
+	// Import path globs select packages, not files
+	cfg.Patterns.IncludePatterns = []string{"github.com/org/shared/**"}
+	require.True(t, cfg.ShouldAnalyzeFile("api/handler.go"))
+	require.True(t, cfg.IsIncludedPackage("github.com/org/shared/log"))
+	require.False(t, cfg.IsIncludedPackage("github.com/other/lib"))
+
+	cfg.Patterns.IncludePatterns = []string{"github.com/org/shared/**", "internal/**", "main.go"}
+	require.False(t, cfg.ShouldAnalyzeFile("api/handler.go"))
+	require.False(t, cfg.IsIncludedPackage("internal/db"))
+	require.True(t, cfg.ShouldAnalyzeFile("main.go"))
+}
+
+func TestShouldIgnorePackage(t *testing.T) {
+	cfg := DefaultConfig()
+
+	// File patterns never apply to package paths
+	require.False(t, cfg.ShouldIgnorePackage("github.com/org/repo/pkg/foo_test.go"))
+
+	cfg.Patterns.IgnorePackagePatterns = []string{"**/internal/testutil/**", "**/internal/testutil"}
+	require.True(t, cfg.ShouldIgnorePackage("github.com/org/repo/internal/testutil"))
+	require.True(t, cfg.ShouldIgnorePackage("github.com/org/repo/internal/testutil/fixtures"))
+	require.False(t, cfg.ShouldIgnorePackage("github.com/org/repo/internal/db"))
+
+	// Package patterns never apply to changed files
+	require.True(t, cfg.ShouldAnalyzeFile("internal/testutil/util.go"))
+}
+
+func TestLoadConfigFromReader_PatternKinds(t *testing.T) {
+	cfg, err := LoadConfigFromReader(strings.NewReader(`patterns:
+  ignore_file_patterns:
+    - "gen/**"
+  ignore_package_patterns:
+    - "**/mocks"
+  ignore_patterns:
+    - "legacy/**"
+`))
+	require.NoError(t, err)
+	require.Equal(t, []string{"gen/**"}, cfg.Patterns.IgnoreFilePatterns)
+	require.Equal(t, []string{"**/mocks"}, cfg.Patterns.IgnorePackagePatterns)
+
+	// The deprecated ignore_patterns still exclude changed files
+	require.False(t, cfg.ShouldAnalyzeFile("gen/api.go"))
+	require.False(t, cfg.ShouldAnalyzeFile("legacy/old.go"))
+	require.True(t, cfg.ShouldAnalyzeFile("pkg/mocks/mock.go"))
+
+	require.True(t, cfg.ShouldIgnorePackage("github.com/org/repo/pkg/mocks"))
+	require.False(t, cfg.ShouldIgnorePackage("github.com/org/repo/legacy"))
+
+	// On its own, ignore_patterns replaces the default file patterns
+	cfg, err = LoadConfigFromReader(strings.NewReader(`patterns:
+  ignore_patterns:
+    - "legacy/**"
+`))
+	require.NoError(t, err)
+	require.Empty(t, cfg.Patterns.IgnoreFilePatterns)
+	require.False(t, cfg.ShouldAnalyzeFile("legacy/old.go"))
+	require.True(t, cfg.ShouldAnalyzeFile("a_test.go"))
+}
+
+func TestLoadConfigFromReader(t *testing.T) {
+	t.Run("valid", func(t *testing.T) {
+		cfg, err := LoadConfigFromReader(strings.NewReader(`targets:
+  high_level_packages:
+    - "**/cmd/**"
+critical:
+  packages:
+    - "**/auth"
+`))
+		require.NoError(t, err)
+		require.Equal(t, []string{"**/cmd/**"}, cfg.Targets.HighLevelPackages)
+		require.Equal(t, CriticalPatterns("**/auth"), cfg.Critical.Packages)
+		// Unset fields keep their defaults
+		require.Equal(t, DefaultConfig().Patterns, cfg.Patterns)
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		cfg, err := LoadConfigFromReader(strings.NewReader(""))
+		require.NoError(t, err)
+		require.Equal(t, DefaultConfig(), cfg)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		cfg, err := LoadConfigFromReader(strings.NewReader("targets: [unclosed"))
+		require.Error(t, err)
+		require.Nil(t, cfg)
+	})
+}
+
+func TestLoadConfigFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"policy/dependency-guardian.yml": {Data: []byte("critical:\n  packages: [\"**/billing\"]\n")},
+	}
+
+	cfg, err := LoadConfigFS(fsys, "policy/dependency-guardian.yml")
+	require.NoError(t, err)
+	require.Equal(t, CriticalPatterns("**/billing"), cfg.Critical.Packages)
+
+	_, err = LoadConfigFS(fsys, "missing.yml")
+	require.EqualError(t, err, "config file not found: missing.yml")
+	var notFound *ErrConfigNotFound
+	require.ErrorAs(t, err, &notFound)
+	require.Equal(t, "missing.yml", notFound.Path)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestDocsURL(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Docs = map[string]string{
+		"**/services/**":         "https://runbook/services",
+		"**/services/billing/**": "https://runbook/billing",
+		"**/services/billing":    "https://runbook/billing-root",
+	}
+
+	require.Equal(t, "https://runbook/billing", cfg.DocsURL("github.com/org/repo/services/billing/invoices"))
+	require.Equal(t, "https://runbook/billing-root", cfg.DocsURL("github.com/org/repo/services/billing"))
+	require.Equal(t, "https://runbook/services", cfg.DocsURL("github.com/org/repo/services/search"))
+	require.Empty(t, cfg.DocsURL("github.com/org/repo/lib"))
+}
+
+func TestLoadConfigFromReader_CriticalBadges(t *testing.T) {
+	yamlConfig := `
+critical:
+  packages:
+    - "**/auth"
+    - pattern: "**/payments/**"
+      badge: "💳 payments"
+    - pattern: "**/payments/ledger"
+      badge: "📒 ledger"
+`
+	cfg, err := LoadConfigFromReader(strings.NewReader(yamlConfig))
+	require.NoError(t, err)
+	require.Equal(t, []CriticalPattern{
+		{Pattern: "**/auth"},
+		{Pattern: "**/payments/**", Badge: "💳 payments"},
+		{Pattern: "**/payments/ledger", Badge: "📒 ledger"},
+	}, cfg.Critical.Packages)
+
+	require.Equal(t, "💳 payments", cfg.CriticalBadge("github.com/org/repo/payments/cards"))
+	require.Equal(t, "📒 ledger", cfg.CriticalBadge("github.com/org/repo/payments/ledger"))
+	require.Empty(t, cfg.CriticalBadge("github.com/org/repo/auth"))
+	require.True(t, cfg.IsCriticalPackage("github.com/org/repo/auth"))
+
+	_, err = LoadConfigFromReader(strings.NewReader("critical:\n  packages:\n    - badge: \"x\"\n"))
+	require.Error(t, err)
+}
+
+func TestLoadConfigFromReader_Layering(t *testing.T) {
+	yamlConfig := `
+layering:
+  layers:
+    api: ["**/api/**"]
+    service: ["**/service/**"]
+    repo: ["**/repo/**", "**/service/store/**"]
+  rules:
+    - from: api
+      allow: [service]
+    - from: repo
+      deny: [api, service]
+`
+	cfg, err := LoadConfigFromReader(strings.NewReader(yamlConfig))
+	require.NoError(t, err)
+
+	require.Equal(t, "api", cfg.LayerOf("example.com/m/api/http"))
+	require.Equal(t, "repo", cfg.LayerOf("example.com/m/service/store/sql"), "the most specific pattern wins")
+	require.Empty(t, cfg.LayerOf("example.com/m/lib"))
+
+	require.True(t, cfg.LayerImportAllowed("api", "service"))
+	require.False(t, cfg.LayerImportAllowed("api", "repo"))
+	require.False(t, cfg.LayerImportAllowed("repo", "service"))
+	require.True(t, cfg.LayerImportAllowed("service", "api"), "layers without rules are unconstrained")
+	require.True(t, cfg.LayerImportAllowed("repo", "repo"))
+	require.True(t, cfg.LayerImportAllowed("api", ""))
+
+	_, err = LoadConfigFromReader(strings.NewReader("layering:\n  layers:\n    api: [\"**/api\"]\n  rules:\n    - from: api\n      deny: [db]\n"))
+	require.EqualError(t, err, `layering rule from "api" refers to undefined layer "db"`)
+}
+
+func TestLoadConfigFromReader_Overrides(t *testing.T) {
+	cfg, err := LoadConfigFromReader(strings.NewReader(`
+critical:
+  packages: ["**/auth"]
+analysis:
+  max_depth: 3
+overrides:
+  - path_prefix: internal/payments/**
+    config:
+      critical:
+        packages: ["**/ledger"]
+  - path_prefix: internal/payments/refunds
+    config:
+      analysis:
+        max_depth: 1
+`))
+	require.NoError(t, err)
+
+	require.Same(t, cfg, cfg.ForPackageDir("internal/billing"))
+	require.Same(t, cfg, cfg.ForPackageDir("internal/paymentsx"))
+
+	payments := cfg.ForPackageDir("internal/payments/cards")
+	require.Equal(t, CriticalPatterns("**/ledger"), payments.Critical.Packages)
+	require.Equal(t, 3, payments.Analysis.MaxDepth, "unset settings are inherited")
+	require.Equal(t, 3, payments.Critical.BlockThreshold)
+
+	// Nested overrides are merged over the enclosing one
+	refunds := cfg.ForPackageDir("internal/payments/refunds")
+	require.Equal(t, CriticalPatterns("**/ledger"), refunds.Critical.Packages)
+	require.Equal(t, 1, refunds.Analysis.MaxDepth)
+
+	// The base configuration is left untouched
+	require.Equal(t, CriticalPatterns("**/auth"), cfg.Critical.Packages)
+
+	_, err = LoadConfigFromReader(strings.NewReader("overrides:\n  - config: {}\n"))
+	require.ErrorContains(t, err, "path_prefix is required")
+}
+
+func TestLoadConfigFromReader_Emoji(t *testing.T) {
+	require.True(t, DefaultConfig().Output.Emoji)
+
+	cfg, err := LoadConfigFromReader(strings.NewReader("output:\n  emoji: false\n"))
+	require.NoError(t, err)
+	require.False(t, cfg.Output.Emoji)
+}
+
+func TestLoadConfig_MergesFiles(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yml")
+	repo := filepath.Join(dir, "repo.yml")
+	require.NoError(t, os.WriteFile(base, []byte(`analysis:
+  max_depth: 3
+  include_tests: true
+  entrypoints:
+    - "**/cmd/*"
+critical:
+  packages:
+    - "**/auth"
+  block_threshold: 5
+patterns:
+  ignore_file_patterns:
+    - "**/*.pb.go"
+docs:
+  "**/auth": https://docs.example.com/auth
+`), 0644))
+	require.NoError(t, os.WriteFile(repo, []byte(`analysis:
+  max_depth: 5
+  entrypoints:
+    - "**/server"
+critical:
+  packages:
+    - "**/payments"
+patterns:
+  ignore_file_patterns:
+    - "**/mock_*.go"
+docs:
+  "**/payments": https://docs.example.com/payments
+`), 0644))
+
+	cfg, err := LoadConfig("", []string{base, repo}, false)
+	require.NoError(t, err)
+
+	// Scalars are overridden, unless the later file leaves them unset
+	require.Equal(t, 5, cfg.Analysis.MaxDepth)
+	require.True(t, cfg.Analysis.IncludeTests)
+	require.Equal(t, 5, cfg.Critical.BlockThreshold)
+
+	// Critical packages and ignore patterns accumulate
+	require.Equal(t, CriticalPatterns("**/auth", "**/payments"), cfg.Critical.Packages)
+	require.Equal(t, []string{"**/*.pb.go", "**/mock_*.go"}, cfg.Patterns.IgnoreFilePatterns)
+
+	// Analysis lists are replaced
+	require.Equal(t, []string{"**/server"}, cfg.Analysis.Entrypoints)
+
+	// Maps are merged by key
+	require.Equal(t, map[string]string{
+		"**/auth":     "https://docs.example.com/auth",
+		"**/payments": "https://docs.example.com/payments",
+	}, cfg.Docs)
+
+	// Settings neither file sets keep their defaults
+	require.Equal(t, DefaultConfig().Analysis.MinImpactThreshold, cfg.Analysis.MinImpactThreshold)
+}
+
+func TestLoadConfig_MergeMissingFile(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yml")
+	require.NoError(t, os.WriteFile(base, []byte("analysis:\n  max_depth: 3\n"), 0644))
+
+	_, err := LoadConfig("", []string{base, filepath.Join(dir, "missing.yml")}, false)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "missing.yml")
+}
+
+func TestDiff(t *testing.T) {
+	base, err := LoadConfigFromReader(strings.NewReader(`
+critical:
+  packages: ["**/billing", "**/auth"]
+patterns:
+  ignore_package_patterns: ["**/mocks"]
+layering:
+  layers:
+    domain: ["**/domain/**"]
+`))
+	require.NoError(t, err)
+	head, err := LoadConfigFromReader(strings.NewReader(`
+critical:
+  packages: ["**/billing", {pattern: "**/ledger", badge: "📒"}]
+patterns:
+  ignore_package_patterns: ["**/mocks"]
+layering:
+  layers:
+    domain: ["**/domain/**", "**/model/**"]
+    infra: ["**/infra/**"]
+`))
+	require.NoError(t, err)
+
+	require.Equal(t, []*Change{
+		{Setting: "critical.packages", Added: []string{"**/ledger (📒)"}, Removed: []string{"**/auth"}},
+		{Setting: "layering.layers.domain", Added: []string{"**/model/**"}},
+		{Setting: "layering.layers.infra", Added: []string{"**/infra/**"}},
+	}, Diff(base, head))
+	require.Empty(t, Diff(head, head))
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Change lists the patterns a configuration change adds to and removes from
+// one of the list settings
+type Change struct {
+	Setting string // YAML path of the setting, e.g. "critical.packages"
+	Added   []string
+	Removed []string
+}
+
+// Diff returns the changes of the pattern lists from base to head, in the
+// order the settings are documented. Settings left unchanged are omitted.
+func Diff(base, head *Config) []*Change {
+	var changes []*Change
+	add := func(setting string, before, after []string) {
+		if change := diffList(setting, before, after); change != nil {
+			changes = append(changes, change)
+		}
+	}
+
+	add("targets.high_level_packages", base.Targets.HighLevelPackages, head.Targets.HighLevelPackages)
+	add("patterns.ignore_file_patterns", base.Patterns.IgnoreFilePatterns, head.Patterns.IgnoreFilePatterns)
+	add("patterns.ignore_package_patterns", base.Patterns.IgnorePackagePatterns, head.Patterns.IgnorePackagePatterns)
+	add("patterns.include_patterns", base.Patterns.IncludePatterns, head.Patterns.IncludePatterns)
+	add("patterns.ignore_patterns", base.Patterns.IgnorePatterns, head.Patterns.IgnorePatterns)
+	add("analysis.additional_internal_prefixes", base.Analysis.AdditionalInternalPrefixes, head.Analysis.AdditionalInternalPrefixes)
+	add("analysis.entrypoints", base.Analysis.Entrypoints, head.Analysis.Entrypoints)
+	add("analysis.strict_import_exceptions", base.Analysis.StrictImportExceptions, head.Analysis.StrictImportExceptions)
+	add("analysis.build_tags", base.Analysis.BuildTags, head.Analysis.BuildTags)
+	add("critical.packages", criticalEntries(base.Critical.Packages), criticalEntries(head.Critical.Packages))
+	add("policy.critical_sources", base.Policy.CriticalSources, head.Policy.CriticalSources)
+	add("policy.deprecated_packages", base.Policy.DeprecatedPackages, head.Policy.DeprecatedPackages)
+
+	layers := make(map[string]bool)
+	for name := range base.Layering.Layers {
+		layers[name] = true
+	}
+	for name := range head.Layering.Layers {
+		layers[name] = true
+	}
+	names := make([]string, 0, len(layers))
+	for name := range layers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		add("layering.layers."+name, base.Layering.Layers[name], head.Layering.Layers[name])
+	}
+
+	return changes
+}
+
+// diffList returns the entries of after missing from before and the other
+// way around, or nil when both hold the same entries
+func diffList(setting string, before, after []string) *Change {
+	inBefore := make(map[string]bool, len(before))
+	for _, entry := range before {
+		inBefore[entry] = true
+	}
+	inAfter := make(map[string]bool, len(after))
+	for _, entry := range after {
+		inAfter[entry] = true
+	}
+
+	change := &Change{Setting: setting}
+	for _, entry := range after {
+		if !inBefore[entry] {
+			change.Added = append(change.Added, entry)
+		}
+	}
+	for _, entry := range before {
+		if !inAfter[entry] {
+			change.Removed = append(change.Removed, entry)
+		}
+	}
+	if len(change.Added) == 0 && len(change.Removed) == 0 {
+		return nil
+	}
+	return change
+}
+
+// criticalEntries renders critical patterns as the entries compared by Diff,
+// so a changed badge shows as a replaced pattern
+func criticalEntries(patterns []CriticalPattern) []string {
+	entries := make([]string, 0, len(patterns))
+	for _, p := range patterns {
+		if p.Badge != "" {
+			entries = append(entries, fmt.Sprintf("%s (%s)", p.Pattern, p.Badge))
+		} else {
+			entries = append(entries, p.Pattern)
+		}
+	}
+	return entries
+}
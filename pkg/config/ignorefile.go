@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// DefaultIgnoreFileName is the name of the gitignore-style file listing paths
+// excluded from the analysis
+const DefaultIgnoreFileName = ".dependency-guardian-ignore"
+
+// IgnoreFile is a set of gitignore-style rules. Later rules take precedence,
+// "!" negates a rule, a trailing "/" restricts it to directories and a rule
+// containing a "/" other than a trailing one is anchored at the repository
+// root. Everything below an ignored directory is ignored.
+type IgnoreFile struct {
+	rules []ignoreRule
+}
+
+// ignoreRule is a single line of an ignore file
+type ignoreRule struct {
+	pattern  string
+	negate   bool
+	dirOnly  bool
+	anchored bool
+}
+
+// LoadIgnoreFile reads the ignore file at the root of repoPath. It returns nil
+// without error when the repository has none.
+func LoadIgnoreFile(repoPath string) (*IgnoreFile, error) {
+	ignorePath := filepath.Join(repoPath, DefaultIgnoreFileName)
+	data, err := os.ReadFile(ignorePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read ignore file %s: %w", ignorePath, err)
+	}
+
+	ignore, err := ParseIgnoreFile(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse ignore file %s: %w", ignorePath, err)
+	}
+	return ignore, nil
+}
+
+// ParseIgnoreFile parses gitignore-style rules from r
+func ParseIgnoreFile(r io.Reader) (*IgnoreFile, error) {
+	ignore := &IgnoreFile{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\#`) || strings.HasPrefix(line, `\!`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if strings.Contains(line, "/") {
+			rule.anchored = true
+			line = strings.TrimPrefix(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+		if strings.HasSuffix(line, "/**") {
+			// Match everything inside the directory but not the directory itself
+			line += "/*"
+		}
+		if !doublestar.ValidatePattern(line) {
+			return nil, fmt.Errorf("invalid pattern %q", scanner.Text())
+		}
+		rule.pattern = line
+		ignore.rules = append(ignore.rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return ignore, nil
+}
+
+// Ignored reports whether the slash-separated path, relative to the repository
+// root, is excluded. A nil IgnoreFile excludes nothing.
+func (f *IgnoreFile) Ignored(name string, isDir bool) bool {
+	if f == nil {
+		return false
+	}
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		return false
+	}
+
+	// A path below an ignored directory cannot be re-included
+	parts := strings.Split(name, "/")
+	for i := 1; i < len(parts); i++ {
+		if f.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+	return f.match(name, isDir)
+}
+
+// match applies the rules to a single path, the last matching rule winning
+func (f *IgnoreFile) match(name string, isDir bool) bool {
+	ignored := false
+	for _, rule := range f.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		pattern := rule.pattern
+		if !rule.anchored {
+			pattern = "**/" + pattern
+		}
+		if matched, _ := doublestar.Match(pattern, name); matched {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// String renders the rules in their normalized form, one per line, so that
+// two files ignoring the same paths the same way render alike. A nil
+// IgnoreFile renders empty.
+func (f *IgnoreFile) String() string {
+	if f == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, rule := range f.rules {
+		if rule.negate {
+			b.WriteString("!")
+		}
+		if rule.anchored {
+			b.WriteString("/")
+		}
+		b.WriteString(rule.pattern)
+		if rule.dirOnly {
+			b.WriteString("/")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
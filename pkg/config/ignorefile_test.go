@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreFile_Ignored(t *testing.T) {
+	ignore, err := ParseIgnoreFile(strings.NewReader(`
+# Generated code
+generated/
+/tools
+*.pb.go
+internal/legacy/**
+!internal/legacy/keep
+\#notes
+`))
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"generated", true, true},
+		{"api/generated", true, true},
+		{"api/generated/types.go", false, true},
+		{"generated", false, false}, // only directories match "generated/"
+		{"tools", true, true},
+		{"cmd/tools", true, false}, // anchored at the root
+		{"api/v1/types.pb.go", false, true},
+		{"internal/legacy/old", true, true},
+		{"internal/legacy/keep", true, false},
+		{"#notes", false, true},
+		{"pkg/app/app.go", false, false},
+		{".", true, false},
+	} {
+		require.Equal(t, tc.ignored, ignore.Ignored(tc.path, tc.isDir), tc.path)
+	}
+
+	var none *IgnoreFile
+	require.False(t, none.Ignored("generated", true))
+}
+
+func TestLoadIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	ignore, err := LoadIgnoreFile(dir)
+	require.NoError(t, err)
+	require.Nil(t, ignore)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, DefaultIgnoreFileName), []byte("gen/\n"), 0644))
+	ignore, err = LoadIgnoreFile(dir)
+	require.NoError(t, err)
+	require.True(t, ignore.Ignored("gen", true))
+}
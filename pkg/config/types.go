@@ -1,31 +1,141 @@
 package config
 
+import "gopkg.in/yaml.v3"
+
 // Config represents the root configuration structure
 type Config struct {
-	Targets    TargetConfig    `yaml:"targets"`
-	Patterns   PatternConfig   `yaml:"patterns"`
-	Analysis   AnalysisConfig  `yaml:"analysis"`
-	Critical   CriticalConfig  `yaml:"critical"`
+	Targets  TargetConfig      `yaml:"targets"`
+	Patterns PatternConfig     `yaml:"patterns"`
+	Analysis AnalysisConfig    `yaml:"analysis"`
+	Critical CriticalConfig    `yaml:"critical"`
+	Output   OutputConfig      `yaml:"output"`
+	Policy   PolicyConfig      `yaml:"policy"`
+	Layering LayeringConfig    `yaml:"layering"`
+	Docs     map[string]string `yaml:"docs"`     // Package pattern to documentation or runbook URL
+	Services map[string]string `yaml:"services"` // Package pattern to the name of the service it deploys as
+
+	// Overrides are merged over this configuration for the changed packages
+	// under their path prefix
+	Overrides []*OverrideConfig `yaml:"overrides"`
+}
+
+// OverrideConfig is a partial configuration applied to the changed packages in
+// a directory subtree of a monorepo. Only the settings it sets are replaced;
+// lists are replaced as a whole. It governs how the impact of those packages
+// is reported: critical and high-level packages, docs, ignored packages,
+// max_depth and the critical source policy. File filters, thresholds and
+// output settings stay global.
+type OverrideConfig struct {
+	PathPrefix string    `yaml:"path_prefix"` // Directory relative to the repository root, e.g. "internal/payments"
+	Config     yaml.Node `yaml:"config"`
+
+	merged *Config // This configuration merged over its parent, set on load
 }
 
 // TargetConfig defines which high-level packages to analyze
 type TargetConfig struct {
 	HighLevelPackages []string `yaml:"high_level_packages"`
+
+	// Name of a file marking the package directory holding it as high-level,
+	// in addition to high_level_packages ("" disables markers)
+	MarkerFile string `yaml:"marker_file"`
 }
 
 // PatternConfig defines include/exclude patterns for analysis
 type PatternConfig struct {
-	IgnorePatterns  []string `yaml:"ignore_patterns"`
-	IncludePatterns []string `yaml:"include_patterns"`
+	IgnoreFilePatterns    []string `yaml:"ignore_file_patterns"`    // Changed files to leave out of the analysis
+	IgnorePackagePatterns []string `yaml:"ignore_package_patterns"` // Affected package paths to leave out of the report
+	IncludePatterns       []string `yaml:"include_patterns"`        // Changed files to analyze, when set, and external packages to track (see IsIncludedPackage)
+
+	// Deprecated: IgnorePatterns is an alias of IgnoreFilePatterns. Set
+	// without ignore_file_patterns, it replaces the default file patterns.
+	IgnorePatterns []string `yaml:"ignore_patterns"`
 }
 
 // AnalysisConfig defines analysis behavior settings
 type AnalysisConfig struct {
-	MaxDepth           int `yaml:"max_depth"`
-	MinImpactThreshold int `yaml:"min_impact_threshold"`
+	MaxDepth           int    `yaml:"max_depth"`
+	MinImpactThreshold int    `yaml:"min_impact_threshold"`
+	IncludeTests       bool   `yaml:"include_tests"`  // Treat _test.go files as part of their package
+	ImportDiff         bool   `yaml:"import_diff"`    // Only propagate impact of packages whose import set changed
+	MinGoVersion       string `yaml:"min_go_version"` // Policy minimum for the go directive of go.mod (e.g., "1.21")
+
+	// Import path prefixes of other modules treated as internal, such as a
+	// vendored shared library
+	AdditionalInternalPrefixes []string `yaml:"additional_internal_prefixes"`
+
+	// Directory of the module relative to the repository root, for
+	// repositories keeping their code under e.g. "src" (defaults to the root)
+	ModuleDir string `yaml:"module_dir"`
+
+	// Patterns of entrypoint packages, such as "**/cmd/*". When set, only
+	// affected packages reachable from an entrypoint are reported.
+	Entrypoints []string `yaml:"entrypoints"`
+
+	// Flag the types of other packages implementing an exported interface that
+	// the change alters, found by type-checking the base revision. Like
+	// import_diff, it compares against a checkout of the base revision.
+	InterfaceChanges bool `yaml:"interface_changes"`
+
+	// Analyze changes to every vendored package, reporting the first-party
+	// packages importing them, directly or through other vendored packages
+	AnalyzeVendor bool `yaml:"analyze_vendor"`
+
+	// Patterns of internal import paths allowed to have no directory in strict
+	// imports mode, such as packages produced by code generation in CI
+	StrictImportExceptions []string `yaml:"strict_import_exceptions"`
+
+	// Tags the module is built with, as with go build -tags. When set, files
+	// whose build constraint needs other tags are left out; platform tags
+	// such as linux or cgo are never pinned, so every platform is analyzed.
+	BuildTags []string `yaml:"build_tags"`
+
+	// Classify every main package as high-level, in addition to those
+	// matching targets.high_level_packages
+	MainsAreHighLevel bool `yaml:"mains_are_high_level"`
 }
 
 // CriticalConfig defines critical packages that require special attention
 type CriticalConfig struct {
-	Packages []string `yaml:"packages"`
-} 
\ No newline at end of file
+	Packages       []CriticalPattern `yaml:"packages"`
+	WarnThreshold  int               `yaml:"warn_threshold"`  // Distinct critical packages affected to warn (0 disables)
+	BlockThreshold int               `yaml:"block_threshold"` // Distinct critical packages affected to block (0 disables)
+}
+
+// CriticalPattern is a pattern of critical packages. In YAML it is either the
+// pattern alone or a mapping also giving the badge rendered next to the
+// packages it matches, such as "💳 payments", instead of the generic one.
+type CriticalPattern struct {
+	Pattern string `yaml:"pattern"`
+	Badge   string `yaml:"badge,omitempty"`
+}
+
+// OutputConfig defines how the report is rendered
+type OutputConfig struct {
+	MaxAffectedPerPackage int    `yaml:"max_affected_per_package"` // Affected packages listed per changed package (0 lists all)
+	RelativePaths         bool   `yaml:"relative_paths"`           // Render packages relative to the module path
+	Emoji                 bool   `yaml:"emoji"`                    // Decorate the report with emoji rather than text markers
+	Footer                string `yaml:"footer"`                   // Appended to the posted comment; a template over {{.Repo}}, {{.PR}} and {{.Version}}
+}
+
+// PolicyConfig defines governance rules enforced on the analysis
+type PolicyConfig struct {
+	CriticalSources    []string `yaml:"critical_sources"`    // Changed packages allowed to affect critical packages (empty allows all)
+	DeprecatedPackages []string `yaml:"deprecated_packages"` // Internal packages that changed packages should migrate off
+}
+
+// LayeringConfig defines the architectural layers of the module and the
+// import directions allowed between them
+type LayeringConfig struct {
+	Layers map[string][]string `yaml:"layers"` // Layer name to the patterns of its packages
+	Rules  []*LayerRule        `yaml:"rules"`
+}
+
+// LayerRule constrains the imports of the packages of a layer. They may not
+// import the layers in Deny and, when Allow is set, may only import their own
+// layer and those in Allow. Packages outside every layer are unconstrained.
+type LayerRule struct {
+	From  string   `yaml:"from"`
+	Allow []string `yaml:"allow"`
+	Deny  []string `yaml:"deny"`
+}
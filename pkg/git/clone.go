@@ -0,0 +1,84 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cosmos/dependency-guardian/pkg/backoff"
+	"go.uber.org/zap"
+)
+
+// CloneAttempts is the number of times the clone and checkout sequence is
+// attempted before giving up on transient failures.
+const CloneAttempts = 3
+
+// cloneBackoff bounds the jittered delay between clone attempts
+var cloneBackoff = backoff.Backoff{Base: 2 * time.Second, Max: 30 * time.Second}
+
+// Cloner performs the git operations needed to clone a revision
+type Cloner interface {
+	// Clone shallow-clones ref of the repository at url into dir
+	Clone(url, ref, dir string) error
+	// Checkout checks out sha in the repository at dir
+	Checkout(dir, sha string) error
+}
+
+// ErrCloneFailed is returned when the repository could not be cloned and
+// checked out, carrying the output of the failed git invocation
+type ErrCloneFailed struct {
+	Output string
+	err    error
+}
+
+func (e *ErrCloneFailed) Error() string {
+	return fmt.Sprintf("failed to clone repository: %v", e.err)
+}
+
+func (e *ErrCloneFailed) Unwrap() error {
+	return e.err
+}
+
+// Clone clones branchRef of repoURL into dir and checks out headRef, retrying
+// the whole sequence with jittered exponential backoff on transient failures.
+// Failures are reported as an *ErrCloneFailed.
+func Clone(cloner Cloner, repoURL, branchRef, headRef, dir string) error {
+	for attempt := 1; ; attempt++ {
+		err := cloneAndCheckout(cloner, repoURL, branchRef, headRef, dir)
+		if err == nil {
+			return nil
+		}
+
+		var gitErr *Error
+		if !errors.As(err, &gitErr) {
+			return &ErrCloneFailed{err: err}
+		}
+		if !gitErr.Transient() || attempt == CloneAttempts {
+			return &ErrCloneFailed{Output: gitErr.Output, err: err}
+		}
+
+		delay := cloneBackoff.Delay(attempt)
+		zap.S().Warnw("git clone failed with a transient error, retrying", "attempt", attempt, "delay", delay, "error", gitErr.Err)
+		time.Sleep(delay)
+
+		// Start the next attempt from an empty directory
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("failed to clean clone directory: %w", err)
+		}
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to recreate clone directory: %w", err)
+		}
+	}
+}
+
+// cloneAndCheckout performs a single shallow clone followed by a checkout
+func cloneAndCheckout(cloner Cloner, repoURL, branchRef, headRef, dir string) error {
+	// Clone with depth 1 to target branch/ref
+	if err := cloner.Clone(repoURL, branchRef, dir); err != nil {
+		return err
+	}
+
+	// Ensure we are at the exact head SHA (in case branch moved)
+	return cloner.Checkout(dir, headRef)
+}
@@ -0,0 +1,132 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/backoff"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCloner is a Cloner whose operations are provided by the test
+type fakeCloner struct {
+	clone    func(url, ref, dir string) error
+	checkout func(dir, sha string) error
+
+	clones    int
+	checkouts []string
+}
+
+func (f *fakeCloner) Clone(url, ref, dir string) error {
+	f.clones++
+	if f.clone == nil {
+		return nil
+	}
+	return f.clone(url, ref, dir)
+}
+
+func (f *fakeCloner) Checkout(dir, sha string) error {
+	f.checkouts = append(f.checkouts, sha)
+	if f.checkout == nil {
+		return nil
+	}
+	return f.checkout(dir, sha)
+}
+
+// noBackoff disables the clone retry delay for the duration of the test
+func noBackoff(t *testing.T) {
+	t.Helper()
+	orig := cloneBackoff
+	cloneBackoff = backoff.Backoff{}
+	t.Cleanup(func() { cloneBackoff = orig })
+}
+
+// transientErr and permanentErr are clone failures as reported by git
+var (
+	transientErr = &Error{Op: "clone", Err: errors.New("exit status 128"), Output: "fatal: unable to access: Connection reset by peer"}
+	permanentErr = &Error{Op: "clone", Err: errors.New("exit status 128"), Output: "remote: Repository not found."}
+)
+
+func TestClone_HappyPath(t *testing.T) {
+	runner := &fakeCloner{
+		clone: func(url, ref, dir string) error {
+			require.Equal(t, "https://example.com/repo.git", url)
+			require.Equal(t, "feature/branch", ref)
+			return os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/repo"), 0644)
+		},
+	}
+
+	dir := t.TempDir()
+	err := Clone(runner, "https://example.com/repo.git", "feature/branch", "abc123", dir)
+	require.NoError(t, err)
+	require.Equal(t, 1, runner.clones)
+	require.Equal(t, []string{"abc123"}, runner.checkouts)
+	require.FileExists(t, filepath.Join(dir, "go.mod"))
+}
+
+func TestClone_CheckoutFailure(t *testing.T) {
+	noBackoff(t)
+	runner := &fakeCloner{
+		checkout: func(dir, sha string) error {
+			return &Error{Op: "checkout", Err: errors.New("exit status 128"), Output: "fatal: reference is not a tree: " + sha}
+		},
+	}
+
+	err := Clone(runner, "https://example.com/repo.git", "main", "abc123", t.TempDir())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "git checkout failed")
+	require.Contains(t, err.Error(), "reference is not a tree: abc123")
+	require.Equal(t, 1, runner.clones, "permanent checkout failures should not be retried")
+}
+
+func TestClone_RetriesTransientFailures(t *testing.T) {
+	noBackoff(t)
+	runner := &fakeCloner{}
+	runner.clone = func(url, ref, dir string) error {
+		if runner.clones < 3 {
+			return transientErr
+		}
+		return nil
+	}
+
+	err := Clone(runner, "https://example.com/repo.git", "main", "abc123", t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, 3, runner.clones)
+	require.Equal(t, []string{"abc123"}, runner.checkouts)
+}
+
+func TestClone_GivesUpAfterMaxAttempts(t *testing.T) {
+	noBackoff(t)
+	runner := &fakeCloner{
+		clone: func(url, ref, dir string) error { return transientErr },
+	}
+
+	err := Clone(runner, "https://example.com/repo.git", "main", "abc123", t.TempDir())
+	require.ErrorIs(t, err, transientErr)
+	require.Equal(t, CloneAttempts, runner.clones)
+}
+
+func TestClone_DoesNotRetryPermanentFailures(t *testing.T) {
+	noBackoff(t)
+	runner := &fakeCloner{
+		clone: func(url, ref, dir string) error { return permanentErr },
+	}
+
+	err := Clone(runner, "https://example.com/repo.git", "main", "abc123", t.TempDir())
+	require.ErrorIs(t, err, permanentErr)
+	require.Equal(t, 1, runner.clones)
+}
+
+func TestClone_ExposesOutput(t *testing.T) {
+	noBackoff(t)
+	runner := &fakeCloner{
+		clone: func(url, ref, dir string) error { return permanentErr },
+	}
+
+	err := Clone(runner, "https://example.com/repo.git", "main", "abc123", t.TempDir())
+	var cloneErr *ErrCloneFailed
+	require.ErrorAs(t, err, &cloneErr)
+	require.Equal(t, "remote: Repository not found.", cloneErr.Output)
+}
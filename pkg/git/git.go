@@ -0,0 +1,52 @@
+// Package git clones repositories through git and classifies git failures
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// transientErrors are output fragments of git failures that are worth retrying
+var transientErrors = []string{
+	"connection reset",
+	"connection timed out",
+	"operation timed out",
+	"could not resolve host",
+	"the remote end hung up unexpectedly",
+	"early eof",
+	"rpc failed",
+	"unexpected disconnect",
+	"gnutls_handshake() failed",
+	"the requested url returned error: 429",
+	"the requested url returned error: 500",
+	"the requested url returned error: 502",
+	"the requested url returned error: 503",
+	"the requested url returned error: 504",
+}
+
+// Error is a failed git invocation along with its output
+type Error struct {
+	Op     string // Git subcommand, such as clone
+	Err    error
+	Output string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("git %s failed: %v\n%s", e.Op, e.Err, e.Output)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Transient reports whether the failure looks like a network or server hiccup
+// rather than a permanent problem such as a missing repository or branch.
+func (e *Error) Transient() bool {
+	output := strings.ToLower(e.Output)
+	for _, fragment := range transientErrors {
+		if strings.Contains(output, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,37 +2,115 @@ package github
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
+	"time"
 
+	"github.com/cosmos/dependency-guardian/pkg/backoff"
 	"github.com/google/go-github/v60/github"
 	"golang.org/x/oauth2"
 )
 
+// Provider is the set of GitHub operations used by the commands. It is
+// implemented by Client and can be substituted with a fake in tests.
+type Provider interface {
+	GetPullRequest(owner, repo string, number int) (*github.PullRequest, error)
+	GetPullRequestFiles(owner, repo string, number int) ([]*github.CommitFile, error)
+	GetFileContent(owner, repo, path, ref string) ([]byte, error)
+	ListComments(owner, repo string, number int) ([]*github.IssueComment, error)
+	ListReviews(owner, repo string, number int) ([]*github.PullRequestReview, error)
+	UpdateComment(owner, repo string, commentID int64, body string) error
+	UpdateReview(owner, repo string, number int, reviewID int64, body string) error
+	CreateComment(owner, repo string, number int, body string) error
+	DeleteComment(owner, repo string, commentID int64) error
+	AddLabels(owner, repo string, number int, labels []string) error
+	RemoveLabel(owner, repo string, number int, label string) error
+	AuthenticatedLogin() (string, error)
+}
+
+var _ Provider = (*Client)(nil)
+
+// ErrNotFound is wrapped by the errors of lookups for missing resources
+var ErrNotFound = errors.New("not found")
+
+// ErrForbidden is wrapped by the errors of writes the token lacks the
+// permission for, such as commenting with a read-only token
+var ErrForbidden = errors.New("forbidden")
+
+// forbidden wraps ErrForbidden into err when the response is a 403. GitHub
+// also refuses requests over the primary or secondary rate limit with a 403,
+// which says nothing about the permissions of the token.
+func forbidden(resp *github.Response, err error) error {
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	if errors.As(err, &rateErr) || errors.As(err, &abuseErr) {
+		return err
+	}
+	if resp != nil && resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("%w: %w", ErrForbidden, err)
+	}
+	return err
+}
+
 // Client wraps the GitHub API client with our custom functionality
 type Client struct {
-	client *github.Client
-	ctx    context.Context
+	client     *github.Client
+	ctx        context.Context
+	backoff    backoff.Backoff // Jitter added to rate limit waits, see waitForRateLimit
+	maxRetries int             // Retries of a rate-limited request
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithContext bounds the requests of the client, and the waits between
+// retries, by ctx
+func WithContext(ctx context.Context) Option {
+	return func(c *Client) {
+		c.ctx = ctx
+	}
+}
+
+// WithBackoff sets the jittered delay added to each wait for a rate limit to
+// reset, so that clients refused together do not retry in lockstep
+func WithBackoff(b backoff.Backoff) Option {
+	return func(c *Client) {
+		c.backoff = b
+	}
+}
+
+// WithMaxRetries sets how many times a rate-limited request is retried; 0
+// disables retries
+func WithMaxRetries(n int) Option {
+	return func(c *Client) {
+		c.maxRetries = n
+	}
 }
 
 // NewClient creates a new GitHub client using the GITHUB_TOKEN environment variable
-func NewClient() (*Client, error) {
+func NewClient(opts ...Option) (*Client, error) {
 	token := os.Getenv("GITHUB_TOKEN")
 	if token == "" {
 		return nil, fmt.Errorf("GITHUB_TOKEN environment variable is required")
 	}
 
-	ctx := context.Background()
+	c := &Client{
+		ctx:        context.Background(),
+		backoff:    backoff.Backoff{Base: time.Second, Max: 10 * time.Second},
+		maxRetries: 1,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
-	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
-
-	return &Client{
-		client: client,
-		ctx:    ctx,
-	}, nil
+	tc := oauth2.NewClient(c.ctx, ts)
+	c.client = github.NewClient(tc)
+	return c, nil
 }
 
 // GetPullRequest fetches a pull request by number
@@ -68,21 +146,138 @@ func (c *Client) GetPullRequestFiles(owner, repo string, number int) ([]*github.
 	return allFiles, nil
 }
 
-// ListComments lists all comments on a pull request
-func (c *Client) ListComments(owner, repo string, number int) ([]*github.IssueComment, error) {
-	comments, _, err := c.client.Issues.ListComments(c.ctx, owner, repo, number, nil)
+// GetFileContent returns the content of the file at path in the repository at
+// ref. The error wraps ErrNotFound when there is no such file.
+func (c *Client) GetFileContent(owner, repo, path, ref string) ([]byte, error) {
+	file, _, resp, err := c.client.Repositories.GetContents(c.ctx, owner, repo, path, &github.RepositoryContentGetOptions{Ref: ref})
 	if err != nil {
-		return nil, fmt.Errorf("failed to list comments on PR #%d: %w", number, err)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, fmt.Errorf("failed to get %s at %s: %w", path, ref, ErrNotFound)
+		}
+		return nil, fmt.Errorf("failed to get %s at %s: %w", path, ref, err)
+	}
+	if file == nil {
+		return nil, fmt.Errorf("failed to get %s at %s: not a file", path, ref)
+	}
+	content, err := file.GetContent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s at %s: %w", path, ref, err)
+	}
+	return []byte(content), nil
+}
+
+// maxRateLimitWait bounds how long a listing waits for the rate limit to
+// reset before giving up
+const maxRateLimitWait = time.Minute
+
+// rateLimitWait returns how long to wait before the given retry, counted
+// from 1, of a request refused by the rate limit: until the limit resets, plus
+// a jittered backoff delay. It reports false for errors other than rate
+// limiting and for waits longer than maxRateLimitWait.
+func (c *Client) rateLimitWait(err error, retry int) (time.Duration, bool) {
+	var wait time.Duration
+	var rateErr *github.RateLimitError
+	var abuseErr *github.AbuseRateLimitError
+	switch {
+	case errors.As(err, &rateErr):
+		wait = time.Until(rateErr.Rate.Reset.Time)
+	case errors.As(err, &abuseErr):
+		wait = abuseErr.GetRetryAfter()
+	default:
+		return 0, false
+	}
+	wait = max(wait, 0) + c.backoff.Delay(retry)
+	if wait > maxRateLimitWait {
+		return 0, false
+	}
+	return wait, true
+}
+
+// waitForRateLimit waits before the given retry of a request refused by err,
+// as rateLimitWait, and reports whether the request is worth retrying. Retries
+// past maxRetries, and a context done before the wait ends, are not.
+func (c *Client) waitForRateLimit(err error, retry int) bool {
+	if retry > c.maxRetries {
+		return false
+	}
+	wait, ok := c.rateLimitWait(err, retry)
+	if !ok {
+		return false
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-c.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// ListComments lists all comments on a pull request, across every page. A
+// page refused by the rate limit is retried once the limit resets, if it does
+// so shortly, up to the configured number of retries.
+func (c *Client) ListComments(owner, repo string, number int) ([]*github.IssueComment, error) {
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	var all []*github.IssueComment
+	retries := 0
+	for {
+		comments, resp, err := c.client.Issues.ListComments(c.ctx, owner, repo, number, opts)
+		if err != nil {
+			if c.waitForRateLimit(err, retries+1) {
+				retries++
+				continue
+			}
+			return nil, fmt.Errorf("failed to list comments on PR #%d: %w", number, err)
+		}
+		retries = 0
+		all = append(all, comments...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// ListReviews lists all reviews of a pull request, across every page, retrying
+// rate-limited pages like ListComments
+func (c *Client) ListReviews(owner, repo string, number int) ([]*github.PullRequestReview, error) {
+	opts := &github.ListOptions{PerPage: 100}
+	var all []*github.PullRequestReview
+	retries := 0
+	for {
+		reviews, resp, err := c.client.PullRequests.ListReviews(c.ctx, owner, repo, number, opts)
+		if err != nil {
+			if c.waitForRateLimit(err, retries+1) {
+				retries++
+				continue
+			}
+			return nil, fmt.Errorf("failed to list reviews on PR #%d: %w", number, err)
+		}
+		retries = 0
+		all = append(all, reviews...)
+		if resp.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.NextPage
 	}
-	return comments, nil
 }
 
 // UpdateComment updates an existing comment on a pull request
 func (c *Client) UpdateComment(owner, repo string, commentID int64, body string) error {
 	comment := &github.IssueComment{Body: &body}
-	_, _, err := c.client.Issues.EditComment(c.ctx, owner, repo, commentID, comment)
+	_, resp, err := c.client.Issues.EditComment(c.ctx, owner, repo, commentID, comment)
+	if err != nil {
+		return fmt.Errorf("failed to update comment #%d: %w", commentID, forbidden(resp, err))
+	}
+	return nil
+}
+
+// UpdateReview replaces the body of a review of a pull request
+func (c *Client) UpdateReview(owner, repo string, number int, reviewID int64, body string) error {
+	_, resp, err := c.client.PullRequests.UpdateReview(c.ctx, owner, repo, number, reviewID, body)
 	if err != nil {
-		return fmt.Errorf("failed to update comment #%d: %w", commentID, err)
+		return fmt.Errorf("failed to update review #%d: %w", reviewID, forbidden(resp, err))
 	}
 	return nil
 }
@@ -90,9 +285,53 @@ func (c *Client) UpdateComment(owner, repo string, commentID int64, body string)
 // CreateComment creates a new comment on a pull request
 func (c *Client) CreateComment(owner, repo string, number int, body string) error {
 	comment := &github.IssueComment{Body: &body}
-	_, _, err := c.client.Issues.CreateComment(c.ctx, owner, repo, number, comment)
+	_, resp, err := c.client.Issues.CreateComment(c.ctx, owner, repo, number, comment)
+	if err != nil {
+		return fmt.Errorf("failed to create comment on PR #%d: %w", number, forbidden(resp, err))
+	}
+	return nil
+}
+
+// DeleteComment deletes a comment on a pull request. Deleting a comment that no
+// longer exists is not an error.
+func (c *Client) DeleteComment(owner, repo string, commentID int64) error {
+	resp, err := c.client.Issues.DeleteComment(c.ctx, owner, repo, commentID)
 	if err != nil {
-		return fmt.Errorf("failed to create comment on PR #%d: %w", number, err)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return fmt.Errorf("failed to delete comment #%d: %w", commentID, forbidden(resp, err))
 	}
 	return nil
-} 
\ No newline at end of file
+}
+
+// AddLabels adds labels to a pull request. Labels already present are left as is.
+func (c *Client) AddLabels(owner, repo string, number int, labels []string) error {
+	_, _, err := c.client.Issues.AddLabelsToIssue(c.ctx, owner, repo, number, labels)
+	if err != nil {
+		return fmt.Errorf("failed to add labels %v to PR #%d: %w", labels, number, err)
+	}
+	return nil
+}
+
+// RemoveLabel removes a label from a pull request. Removing a label that is not
+// present is not an error.
+func (c *Client) RemoveLabel(owner, repo string, number int, label string) error {
+	resp, err := c.client.Issues.RemoveLabelForIssue(c.ctx, owner, repo, number, label)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return fmt.Errorf("failed to remove label %q from PR #%d: %w", label, number, err)
+	}
+	return nil
+}
+
+// AuthenticatedLogin returns the login of the user the token belongs to
+func (c *Client) AuthenticatedLogin() (string, error) {
+	user, _, err := c.client.Users.Get(c.ctx, "")
+	if err != nil {
+		return "", fmt.Errorf("failed to get the authenticated user: %w", err)
+	}
+	return user.GetLogin(), nil
+}
@@ -0,0 +1,64 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cosmos/dependency-guardian/pkg/backoff"
+	"github.com/google/go-github/v60/github"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForbidden(t *testing.T) {
+	resp := &github.Response{Response: &http.Response{StatusCode: http.StatusForbidden}}
+
+	err := forbidden(resp, errors.New("resource not accessible by integration"))
+	require.ErrorIs(t, err, ErrForbidden)
+
+	// Rate limits are answered with a 403 too
+	rateErr := &github.RateLimitError{Response: resp.Response, Message: "API rate limit exceeded"}
+	err = forbidden(resp, rateErr)
+	require.NotErrorIs(t, err, ErrForbidden)
+	require.ErrorAs(t, err, &rateErr)
+
+	abuseErr := &github.AbuseRateLimitError{Response: resp.Response, Message: "secondary rate limit"}
+	err = forbidden(resp, abuseErr)
+	require.NotErrorIs(t, err, ErrForbidden)
+	require.ErrorAs(t, err, &abuseErr)
+
+	require.NoError(t, forbidden(nil, nil))
+}
+
+func TestClientRateLimitWait(t *testing.T) {
+	c := &Client{
+		ctx:        context.Background(),
+		backoff:    backoff.Backoff{Base: time.Second, Max: 4 * time.Second},
+		maxRetries: 2,
+	}
+	rateErr := &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(10 * time.Second)}}}
+
+	// The wait lasts until the reset, plus a jittered delay within the cap
+	for retry := 1; retry <= 3; retry++ {
+		wait, ok := c.rateLimitWait(rateErr, retry)
+		require.True(t, ok)
+		require.Greater(t, wait, 9*time.Second)
+		require.LessOrEqual(t, wait, 10*time.Second+c.backoff.Limit(retry))
+	}
+
+	_, ok := c.rateLimitWait(&github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: time.Now().Add(time.Hour)}}}, 1)
+	require.False(t, ok, "limits resetting much later are not waited for")
+	_, ok = c.rateLimitWait(errors.New("boom"), 1)
+	require.False(t, ok)
+
+	// Retries stop past the maximum, or once the context is done
+	require.False(t, c.waitForRateLimit(rateErr, 3))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c.ctx = ctx
+	start := time.Now()
+	require.False(t, c.waitForRateLimit(rateErr, 1))
+	require.Less(t, time.Since(start), time.Second)
+}
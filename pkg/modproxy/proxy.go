@@ -0,0 +1,166 @@
+package modproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultProxyURL is used when GOPROXY does not name a proxy
+const DefaultProxyURL = "https://proxy.golang.org"
+
+// Client downloads module source trees from a Go module proxy
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewClient creates a client for the first proxy listed in goproxy, a value in
+// the format of the GOPROXY environment variable. The "direct" and "off"
+// entries are skipped since they do not name a proxy.
+func NewClient(goproxy string) *Client {
+	baseURL := DefaultProxyURL
+	for _, entry := range strings.FieldsFunc(goproxy, func(r rune) bool { return r == ',' || r == '|' }) {
+		entry = strings.TrimSpace(entry)
+		if entry != "" && entry != "direct" && entry != "off" {
+			baseURL = entry
+			break
+		}
+	}
+
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// versionInfo is the response of the proxy's .info endpoint
+type versionInfo struct {
+	Version string `json:"Version"`
+}
+
+// Download resolves query, such as a commit hash or tag, to a version of
+// modulePath and extracts the module zip of that version into dir. It returns
+// the resolved version.
+func (c *Client) Download(modulePath, query, dir string) (string, error) {
+	escaped, err := escapePath(modulePath)
+	if err != nil {
+		return "", err
+	}
+
+	infoData, err := c.get(fmt.Sprintf("%s/%s/@v/%s.info", c.BaseURL, escaped, query))
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s@%s: %w", modulePath, query, err)
+	}
+	var info versionInfo
+	if err := json.Unmarshal(infoData, &info); err != nil {
+		return "", fmt.Errorf("failed to decode version info of %s@%s: %w", modulePath, query, err)
+	}
+	if info.Version == "" {
+		return "", fmt.Errorf("proxy returned no version for %s@%s", modulePath, query)
+	}
+
+	escapedVersion, err := escapePath(info.Version)
+	if err != nil {
+		return "", err
+	}
+	zipData, err := c.get(fmt.Sprintf("%s/%s/@v/%s.zip", c.BaseURL, escaped, escapedVersion))
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s@%s: %w", modulePath, info.Version, err)
+	}
+
+	if err := extract(zipData, modulePath+"@"+info.Version+"/", dir); err != nil {
+		return "", fmt.Errorf("failed to extract %s@%s: %w", modulePath, info.Version, err)
+	}
+	return info.Version, nil
+}
+
+// get fetches url and returns the response body
+func (c *Client) get(url string) ([]byte, error) {
+	resp, err := c.HTTPClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("proxy returned status %d for %s", resp.StatusCode, url)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// extract writes the files of a module zip into dir. Every file of a module
+// zip is stored under the "module@version/" prefix, which is stripped.
+func extract(data []byte, prefix, dir string) error {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return err
+	}
+
+	for _, f := range r.File {
+		name, ok := strings.CutPrefix(f.Name, prefix)
+		if !ok {
+			return fmt.Errorf("unexpected file %s outside of %s", f.Name, prefix)
+		}
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if !strings.HasPrefix(target, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("file %s escapes the target directory", f.Name)
+		}
+		if err := extractFile(f, target); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractFile writes a single zip entry to target
+func extractFile(f *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	src, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
+
+// escapePath applies the case encoding of module paths and versions used by
+// proxies: every uppercase letter is replaced by "!" and its lowercase form.
+func escapePath(s string) (string, error) {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '!':
+			return "", fmt.Errorf("invalid character '!' in %q", s)
+		case r >= 'A' && r <= 'Z':
+			b.WriteByte('!')
+			b.WriteRune(r + ('a' - 'A'))
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String(), nil
+}
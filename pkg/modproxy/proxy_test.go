@@ -0,0 +1,92 @@
+package modproxy
+
+import (
+	"archive/zip"
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// moduleZip builds a module zip holding files under the module@version prefix
+func moduleZip(t *testing.T, prefix string, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range files {
+		f, err := w.Create(prefix + name)
+		require.NoError(t, err)
+		_, err = f.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func TestClient_Download(t *testing.T) {
+	const version = "v0.0.0-20240102030405-abcdef123456"
+	data := moduleZip(t, "github.com/Org/repo@"+version+"/", map[string]string{
+		"go.mod":   "module github.com/Org/repo",
+		"d/d.go":   "package d",
+		"c/c.go":   "package c",
+		"LICENSE":  "MIT",
+		"d/x/x.go": "package x",
+	})
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.URL.Path)
+		switch r.URL.Path {
+		case "/github.com/!org/repo/@v/abcdef123456.info":
+			_, _ = w.Write([]byte(`{"Version":"` + version + `","Time":"2024-01-02T03:04:05Z"}`))
+		case "/github.com/!org/repo/@v/" + version + ".zip":
+			_, _ = w.Write(data)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("off," + server.URL + "/,direct")
+	require.Equal(t, server.URL, client.BaseURL)
+
+	dir := t.TempDir()
+	resolved, err := client.Download("github.com/Org/repo", "abcdef123456", dir)
+	require.NoError(t, err)
+	require.Equal(t, version, resolved)
+	require.Len(t, requests, 2)
+
+	content, err := os.ReadFile(filepath.Join(dir, "d", "x", "x.go"))
+	require.NoError(t, err)
+	require.Equal(t, "package x", string(content))
+	require.FileExists(t, filepath.Join(dir, "go.mod"))
+
+	// Unknown revisions are reported
+	_, err = client.Download("github.com/Org/repo", "unknown", t.TempDir())
+	require.ErrorContains(t, err, "proxy returned status 404")
+}
+
+func TestClient_DownloadRejectsEscapingFiles(t *testing.T) {
+	data := moduleZip(t, "example.com/m@v1.0.0/", map[string]string{"../evil.go": "package evil"})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/example.com/m/@v/v1.0.0.info":
+			_, _ = w.Write([]byte(`{"Version":"v1.0.0"}`))
+		default:
+			_, _ = w.Write(data)
+		}
+	}))
+	defer server.Close()
+
+	_, err := NewClient(server.URL).Download("example.com/m", "v1.0.0", t.TempDir())
+	require.ErrorContains(t, err, "escapes the target directory")
+}
+
+func TestNewClient_Default(t *testing.T) {
+	require.Equal(t, DefaultProxyURL, NewClient("").BaseURL)
+	require.Equal(t, DefaultProxyURL, NewClient("direct").BaseURL)
+}
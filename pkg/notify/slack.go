@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+)
+
+// SlackNotifier posts a condensed analysis report to a Slack incoming webhook
+type SlackNotifier struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+// NewSlackNotifier creates a notifier posting to the given webhook URL
+func NewSlackNotifier(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{
+		WebhookURL: webhookURL,
+		Client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// slackMessage is the payload of an incoming webhook. Text is the fallback
+// shown in notifications; blocks carry the formatted message.
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type string     `json:"type"`
+	Text *slackText `json:"text,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Notify posts the critical packages and impact counts of result, linking to
+// the pull request at prURL.
+func (s *SlackNotifier) Notify(result *analysis.AnalysisResult, prURL string) error {
+	payload, err := json.Marshal(slackPayload(result, prURL))
+	if err != nil {
+		return fmt.Errorf("failed to encode Slack message: %w", err)
+	}
+
+	resp, err := s.Client.Post(s.WebhookURL, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to post Slack message: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("slack webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// slackPayload renders the condensed report as Slack blocks
+func slackPayload(result *analysis.AnalysisResult, prURL string) *slackMessage {
+	title := fmt.Sprintf("Dependency impact for <%s|%s>", prURL, prURL)
+	critical := result.CriticalPackageNames()
+
+	var summary strings.Builder
+	summary.WriteString(fmt.Sprintf("*Changed packages:* %d\n", len(result.Impacts)))
+	summary.WriteString(fmt.Sprintf("*Affected packages:* %d\n", len(result.AffectedPackageNames())))
+	summary.WriteString(fmt.Sprintf("*Critical packages affected:* %d", len(critical)))
+
+	msg := &slackMessage{
+		Text: fmt.Sprintf("Dependency impact for %s: %d critical packages affected", prURL, len(critical)),
+		Blocks: []slackBlock{
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: title}},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: summary.String()}},
+		},
+	}
+
+	if len(critical) > 0 {
+		var list strings.Builder
+		list.WriteString(":rotating_light: *Critical packages*\n")
+		for _, name := range critical {
+			list.WriteString(fmt.Sprintf("• `%s`\n", name))
+		}
+		msg.Blocks = append(msg.Blocks, slackBlock{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: strings.TrimSuffix(list.String(), "\n")},
+		})
+	}
+
+	return msg
+}
@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlackNotifier_Notify(t *testing.T) {
+	var received slackMessage
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodPost, r.Method)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	result := &analysis.AnalysisResult{
+		Impacts: []*analysis.PackageImpact{
+			{
+				ChangedPackage: "example.com/m/d",
+				AffectedPackages: []*analysis.AffectedPackage{
+					{Name: "example.com/m/billing", IsCritical: true},
+					{Name: "example.com/m/docs"},
+				},
+			},
+			{
+				ChangedPackage: "example.com/m/e",
+				AffectedPackages: []*analysis.AffectedPackage{
+					{Name: "example.com/m/billing", IsCritical: true},
+				},
+			},
+		},
+	}
+
+	err := NewSlackNotifier(server.URL).Notify(result, "https://github.com/o/r/pull/1")
+	require.NoError(t, err)
+
+	require.Contains(t, received.Text, "https://github.com/o/r/pull/1")
+	require.Len(t, received.Blocks, 3)
+	require.Equal(t, "Dependency impact for <https://github.com/o/r/pull/1|https://github.com/o/r/pull/1>", received.Blocks[0].Text.Text)
+	require.Equal(t, "*Changed packages:* 2\n*Affected packages:* 2\n*Critical packages affected:* 1", received.Blocks[1].Text.Text)
+	require.Equal(t, ":rotating_light: *Critical packages*\n• `example.com/m/billing`", received.Blocks[2].Text.Text)
+}
+
+func TestSlackNotifier_NotifyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	err := NewSlackNotifier(server.URL).Notify(&analysis.AnalysisResult{}, "https://github.com/o/r/pull/1")
+	require.EqualError(t, err, "slack webhook returned status 403")
+}
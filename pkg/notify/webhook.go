@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/cosmos/dependency-guardian/pkg/backoff"
+	"go.uber.org/zap"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the request body, keyed with
+// the webhook secret and prefixed with "sha256="
+const SignatureHeader = "X-Dependency-Guardian-Signature"
+
+// webhookAttempts is the number of times a delivery is attempted before giving
+// up on transient failures
+const webhookAttempts = 3
+
+// WebhookNotifier posts the raw analysis result as JSON to a generic webhook
+type WebhookNotifier struct {
+	URL     string
+	Secret  string // Signs the body when set
+	Client  *http.Client
+	Backoff backoff.Backoff // Bounds the jittered delay between attempts
+}
+
+// NewWebhookNotifier creates a notifier posting to url, signing the payloads
+// with secret when it is not empty
+func NewWebhookNotifier(url, secret string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:     url,
+		Secret:  secret,
+		Client:  &http.Client{Timeout: 10 * time.Second},
+		Backoff: backoff.Backoff{Base: time.Second, Max: 10 * time.Second},
+	}
+}
+
+// PullRequestInfo identifies the pull request an analysis was run for
+type PullRequestInfo struct {
+	Owner   string `json:"owner"`
+	Repo    string `json:"repo"`
+	Number  int    `json:"number"`
+	URL     string `json:"url"`
+	BaseSHA string `json:"base_sha"`
+	HeadSHA string `json:"head_sha"`
+}
+
+// webhookPayload is the body posted to the webhook
+type webhookPayload struct {
+	PullRequest PullRequestInfo          `json:"pull_request"`
+	Result      *analysis.AnalysisResult `json:"result"`
+}
+
+// Notify posts result and the pull request metadata. Server errors, rate
+// limiting and network failures are retried; other non-2xx responses fail
+// immediately.
+func (w *WebhookNotifier) Notify(result *analysis.AnalysisResult, pr PullRequestInfo) error {
+	body, err := json.Marshal(&webhookPayload{PullRequest: pr, Result: result})
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	for attempt := 1; ; attempt++ {
+		retryable, err := w.post(body)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt == webhookAttempts {
+			return err
+		}
+
+		delay := w.Backoff.Delay(attempt)
+		zap.S().Warnw("webhook delivery failed, retrying", "attempt", attempt, "delay", delay, "error", err)
+		time.Sleep(delay)
+	}
+}
+
+// post performs a single delivery and reports whether a failure is worth
+// retrying
+func (w *WebhookNotifier) post(body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.Secret, body))
+	}
+
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to post to webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		retryable := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retryable, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// Sign returns the signature header value of body for secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/cosmos/dependency-guardian/pkg/backoff"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookNotifier_Notify(t *testing.T) {
+	var signature string
+	var received struct {
+		PullRequest PullRequestInfo          `json:"pull_request"`
+		Result      *analysis.AnalysisResult `json:"result"`
+	}
+	var body []byte
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			// A transient failure is retried
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		signature = r.Header.Get(SignatureHeader)
+		var err error
+		body, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(body, &received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	result := &analysis.AnalysisResult{CriticalCount: 2}
+	pr := PullRequestInfo{Owner: "o", Repo: "r", Number: 1, HeadSHA: "head"}
+
+	notifier := NewWebhookNotifier(server.URL, "s3cret")
+	notifier.Backoff = backoff.Backoff{}
+	require.NoError(t, notifier.Notify(result, pr))
+	require.Equal(t, 2, calls)
+
+	require.Equal(t, pr, received.PullRequest)
+	require.Equal(t, 2, received.Result.CriticalCount)
+	require.Equal(t, Sign("s3cret", body), signature)
+	require.Regexp(t, "^sha256=[0-9a-f]{64}$", signature)
+	require.NotEqual(t, Sign("other", body), signature)
+}
+
+func TestWebhookNotifier_NotifyError(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		require.Empty(t, r.Header.Get(SignatureHeader))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL, "")
+	notifier.Backoff = backoff.Backoff{}
+	err := notifier.Notify(&analysis.AnalysisResult{}, PullRequestInfo{})
+	require.ErrorContains(t, err, "webhook returned status 401")
+
+	// Client errors are not retried
+	require.Equal(t, 1, calls)
+}
@@ -0,0 +1,73 @@
+// Package report renders analysis results in formats consumed by other tools
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+)
+
+// JUnitSuite is a JUnit XML test suite holding a test case per changed
+// package, for CI dashboards aggregating test reports
+type JUnitSuite struct {
+	XMLName  xml.Name     `xml:"testsuite"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Cases    []*JUnitCase `xml:"testcase"`
+}
+
+// JUnitCase is the test case of a changed package. It fails when the package
+// affects a critical package.
+type JUnitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *JUnitFailure `xml:"failure,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"` // Affected packages of a passing case
+}
+
+// JUnitFailure describes the critical impact of a failing test case
+type JUnitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"` // Affected packages, critical ones marked
+}
+
+// JUnit returns an analysis result as a JUnit test suite
+func JUnit(r *analysis.AnalysisResult) *JUnitSuite {
+	suite := &JUnitSuite{Name: "dependency-guardian"}
+	for _, impact := range r.Impacts {
+		tc := &JUnitCase{Name: r.DisplayName(impact.ChangedPackage), ClassName: "dependency-impact"}
+
+		var critical []string
+		var affected strings.Builder
+		for _, pkg := range impact.AffectedPackages {
+			affected.WriteString(r.DisplayName(pkg.Name))
+			if pkg.IsCritical {
+				critical = append(critical, r.DisplayName(pkg.Name))
+				affected.WriteString(" (critical)")
+			}
+			affected.WriteString("\n")
+		}
+
+		if len(critical) > 0 {
+			noun := "packages"
+			if len(critical) == 1 {
+				noun = "package"
+			}
+			tc.Failure = &JUnitFailure{
+				Message: fmt.Sprintf("affects %d critical %s: %s", len(critical), noun, strings.Join(critical, ", ")),
+				Type:    "CriticalImpact",
+				Text:    affected.String(),
+			}
+			suite.Failures++
+		} else {
+			tc.SystemOut = affected.String()
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Tests = len(suite.Cases)
+	return suite
+}
@@ -0,0 +1,47 @@
+package report
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/cosmos/dependency-guardian/pkg/analysis"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJUnit(t *testing.T) {
+	result := &analysis.AnalysisResult{
+		ModulePath:    "example.com/m",
+		RelativePaths: true,
+		Impacts: []*analysis.PackageImpact{
+			{
+				ChangedPackage: "example.com/m/d",
+				AffectedPackages: []*analysis.AffectedPackage{
+					{Name: "example.com/m/c"},
+					{Name: "example.com/m/auth", IsCritical: true},
+				},
+			},
+			{
+				ChangedPackage:   "example.com/m/util",
+				AffectedPackages: []*analysis.AffectedPackage{{Name: "example.com/m/c"}},
+			},
+		},
+	}
+
+	data, err := xml.MarshalIndent(JUnit(result), "", "  ")
+	require.NoError(t, err)
+	require.Equal(t, `<testsuite name="dependency-guardian" tests="2" failures="1">
+  <testcase name="d" classname="dependency-impact">
+    <failure message="affects 1 critical package: auth" type="CriticalImpact">c&#xA;auth (critical)&#xA;</failure>
+  </testcase>
+  <testcase name="util" classname="dependency-impact">
+    <system-out>c&#xA;</system-out>
+  </testcase>
+</testsuite>`, string(data))
+
+	// Without a critical impact, every case passes
+	result.Impacts = result.Impacts[1:]
+	suite := JUnit(result)
+	require.Equal(t, 1, suite.Tests)
+	require.Zero(t, suite.Failures)
+	require.Nil(t, suite.Cases[0].Failure)
+}